@@ -2,7 +2,7 @@ package pgxadapter
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -10,7 +10,6 @@ import (
 	"github.com/casbin/casbin/v3/model"
 	"github.com/casbin/casbin/v3/persist"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 )
 
 // LoadPolicy loads all policy rules from the storage
@@ -38,80 +37,217 @@ func (a *PgxAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex i
 	return a.RemoveFilteredPolicyCtx(context.Background(), sec, ptype, fieldIndex, fieldValues...)
 }
 
-// LoadPolicy loads all policy rules from the storage
+// LoadPolicy loads all policy rules from the storage.
+// Loads are serialized per adapter because a Casbin model is not safe for
+// concurrent writes; callers sharing a model across adapters must synchronize themselves.
 func (a *PgxAdapter) LoadPolicyCtx(ctx context.Context, model model.Model) error {
+	ctx, cancel := a.readContext(ctx)
+	defer cancel()
 
-	q, args, err := a.psql.
-		Select(selectColumns...).
-		From(a.tableName).
-		OrderBy("id").
-		ToSql()
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	a.loadMu.Lock()
+	defer a.loadMu.Unlock()
+
+	if a.policyVersion {
+		version, err := a.readVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if err := a.loadPolicy(ctx, model); err != nil {
+			return err
+		}
+		a.loadedVersion.Store(version)
+		return nil
+	}
+
+	return a.loadPolicy(ctx, model)
+}
+
+// loadPolicy loads all policy rules, the caller must hold loadMu
+func (a *PgxAdapter) loadPolicy(ctx context.Context, model model.Model) error {
+	if !a.inMemoryFallback {
+		return a.loadPolicyFromDB(ctx, model, nil)
+	}
+
+	var snapshot [][]string
+	err := a.loadPolicyFromDB(ctx, model, &snapshot)
+	return a.applyFallback(model, snapshot, err)
+}
+
+// loadPolicyFromDB loads all policy rules from the table.
+// Loaded lines are also appended to snapshot when it is not nil.
+func (a *PgxAdapter) loadPolicyFromDB(ctx context.Context, model model.Model, snapshot *[][]string) error {
+	if err := a.learnEft(model); err != nil {
+		return err
+	}
 
+	q, args, err := a.loadSQL(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to build query: %w", err)
 	}
 
-	rows, err := a.db.Query(ctx, q, args...)
+	if a.loadFetchSize > 0 {
+		return a.loadWithCursor(ctx, q, args, func(rows pgx.Rows, loaded int) (int, error) {
+			return a.loadPolicyRows(rows, model, snapshot, loaded)
+		})
+	}
+
+	rows, err := a.dbFrom(ctx).Query(ctx, q, args...)
 
 	if err != nil {
 		return fmt.Errorf("failed to query policies: %w", err)
 	}
 	defer rows.Close()
 
-	for rows.Next() {
-		var ptype string
-		var v0, v1, v2, v3, v4, v5 sql.NullString
+	if _, err := a.loadPolicyRows(rows, model, snapshot, 0); err != nil {
+		return err
+	}
 
-		if err := rows.Scan(&ptype, &v0, &v1, &v2, &v3, &v4, &v5); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
-		}
+	return nil
+}
+
+// loadWithCursor runs the load query q through a server side cursor, fetching
+// loadFetchSize rows per round trip and passing each batch to load with the
+// number of rows loaded so far
+func (a *PgxAdapter) loadWithCursor(ctx context.Context, q string, args []any, load func(rows pgx.Rows, loaded int) (int, error)) error {
+	// Cursors only live inside a transaction
+	tx, err := a.dbFrom(ctx).Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
-		policyLine := []string{ptype}
+	if _, err := tx.Exec(ctx, "DECLARE casbin_load_cursor NO SCROLL CURSOR FOR "+q, args...); err != nil {
+		return fmt.Errorf("failed to declare cursor: %w", err)
+	}
 
-		if v0.Valid {
-			policyLine = append(policyLine, v0.String)
-		}
-		if v1.Valid {
-			policyLine = append(policyLine, v1.String)
+	fetchSQL := fmt.Sprintf("FETCH FORWARD %d FROM casbin_load_cursor", a.loadFetchSize)
+	loaded := 0
+	for {
+		rows, err := tx.Query(ctx, fetchSQL)
+		if err != nil {
+			return fmt.Errorf("failed to fetch policies: %w", err)
 		}
-		if v2.Valid {
-			policyLine = append(policyLine, v2.String)
+
+		n, err := load(rows, loaded)
+		rows.Close()
+		if err != nil {
+			return err
 		}
-		if v3.Valid {
-			policyLine = append(policyLine, v3.String)
+		loaded += n
+
+		if n < a.loadFetchSize {
+			break
 		}
-		if v4.Valid {
-			policyLine = append(policyLine, v4.String)
+	}
+
+	// Closed explicitly, as the cursor outlives a savepoint of the caller's transaction
+	if _, err := tx.Exec(ctx, "CLOSE casbin_load_cursor"); err != nil {
+		return fmt.Errorf("failed to close cursor: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// loadPolicyRows loads every row into the model and returns the number of rows read.
+// Loaded lines are also appended to snapshot when it is not nil. loaded is the
+// number of rows already loaded by earlier batches of the same load.
+func (a *PgxAdapter) loadPolicyRows(rows pgx.Rows, model model.Model, snapshot *[][]string, loaded int) (int, error) {
+	scanner := a.newRuleScanner()
+	count := 0
+	for rows.Next() {
+		if err := a.checkLoadRows(loaded + count + 1); err != nil {
+			return count, err
 		}
-		if v5.Valid {
-			policyLine = append(policyLine, v5.String)
+
+		// The model keeps the line, so each row gets a line of its own
+		line, err := scanner.scanLine(rows, nil)
+		if err != nil {
+			return count, err
 		}
 
-		persist.LoadPolicyLine(strings.Join(policyLine, ", "), model)
+		if err := persist.LoadPolicyArray(line, model); err != nil {
+			return count, err
+		}
+		if snapshot != nil {
+			*snapshot = append(*snapshot, line)
+		}
+		count++
 	}
 
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating rows: %w", err)
+		return count, fmt.Errorf("error iterating rows: %w", err)
 	}
 
+	return count, nil
+}
+
+// limitLoad caps a load query one row past maxLoadRows, which is enough to
+// detect an oversized load without streaming the whole result
+func (a *PgxAdapter) limitLoad(query sq.SelectBuilder) sq.SelectBuilder {
+	if a.maxLoadRows <= 0 {
+		return query
+	}
+	return query.Limit(uint64(a.maxLoadRows) + 1)
+}
+
+// checkLoadRows fails once a load reaches more than maxLoadRows rows
+func (a *PgxAdapter) checkLoadRows(rows int) error {
+	if a.maxLoadRows > 0 && rows > a.maxLoadRows {
+		return fmt.Errorf("%w: more than %d rows", ErrMaxLoadRows, a.maxLoadRows)
+	}
 	return nil
 }
 
-// SavePolicy saves all policy rules to the storage
+// SavePolicy saves all policy rules to the storage, replacing the stored ones.
+// With WithTenantColumn only the rules of the tenant are replaced.
 func (a *PgxAdapter) SavePolicyCtx(ctx context.Context, model model.Model) error {
+	return a.withRetry(ctx, func() error {
+		return a.savePolicy(ctx, model)
+	})
+}
+
+// savePolicy runs a single attempt of SavePolicyCtx
+func (a *PgxAdapter) savePolicy(ctx context.Context, model model.Model) error {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Validate the model against the table before writing anything
+	if err := a.learnEft(model); err != nil {
+		return err
+	}
+	if err := a.validateModel(model); err != nil {
+		return err
+	}
 
 	// Start a transaction
-	tx, err := a.db.Begin(ctx)
+	tx, err := a.beginWrite(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	// Clear existing policies
-	quotedTableName := pgx.Identifier{a.tableName}.Sanitize()
-	truncateSQL := "TRUNCATE TABLE " + quotedTableName
-	if _, err := tx.Exec(ctx, truncateSQL); err != nil {
-		return fmt.Errorf("failed to clear policies: %w", err)
+	if err := a.lockTx(ctx, tx); err != nil {
+		return err
 	}
 
 	// Prepare batch insert
@@ -133,33 +269,50 @@ func (a *PgxAdapter) SavePolicyCtx(ctx context.Context, model model.Model) error
 		}
 	}
 
-	// Batch insert all policies
-	if len(lines) > 0 {
-		insertBuilder := a.psql.Insert(a.tableName).
-			Columns(insertColumns...)
-
-		for i, line := range lines {
-			vals := make([]any, 7)
-			vals[0] = ptypes[i]
-
-			for j := range 6 {
-				if j < len(line) && line[j] != "" {
-					vals[j+1] = line[j]
-				} else {
-					vals[j+1] = nil
-				}
-			}
+	collected := len(lines)
+	if a.dedupeRules {
+		ptypes, lines = dedupe(ptypes, lines)
+	}
 
-			insertBuilder = insertBuilder.Values(vals...)
-		}
+	changes := make([]auditChange, len(lines))
+	for i, line := range lines {
+		changes[i] = auditChange{ptype: ptypes[i], newRule: line}
+	}
+	if err := a.writeAudit(ctx, tx, AuditOpSave, changes); err != nil {
+		return err
+	}
 
-		sql, args, err := insertBuilder.ToSql()
+	if a.saveMode == SaveModeUpsertDiff {
+		written, err := a.savePolicyDiff(ctx, tx, ptypes, lines)
 		if err != nil {
-			return fmt.Errorf("failed to build insert query: %w", err)
+			return err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
 		}
+		a.dedupedRules.Add(int64(collected - len(lines)))
+		a.maybeAnalyze(ctx, written)
+		return nil
+	}
+
+	// DELETE rather than TRUNCATE: TRUNCATE locks out concurrent readers until
+	// commit, while DELETE lets them keep reading the old rules meanwhile
+	clearSQL, clearArgs, err := a.deleteRules(ctx).ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete query: %w", err)
+	}
+	cleared, err := tx.Exec(ctx, clearSQL, clearArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to clear policies: %w", err)
+	}
 
-		if _, err := tx.Exec(ctx, sql, args...); err != nil {
-			return fmt.Errorf("failed to insert policies: %w", err)
+	// COPY has no bind parameter limit and is much faster than INSERT for large saves
+	if len(lines) > 0 {
+		source := pgx.CopyFromSlice(len(lines), func(i int) ([]any, error) {
+			return a.copyValues(ctx, ptypes[i], lines[i]), nil
+		})
+		if _, err := tx.CopyFrom(ctx, a.qualifiedIdentifier(a.tableName), a.copyColumns(), source); err != nil {
+			return fmt.Errorf("failed to insert policies: %w", mapWriteError(err))
 		}
 	}
 
@@ -168,102 +321,159 @@ func (a *PgxAdapter) SavePolicyCtx(ctx context.Context, model model.Model) error
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	a.dedupedRules.Add(int64(collected - len(lines)))
+	a.maybeAnalyze(ctx, cleared.RowsAffected()+int64(len(lines)))
+
 	return nil
 }
 
 // AddPolicy adds a policy rule to the storage
 func (a *PgxAdapter) AddPolicyCtx(ctx context.Context, sec string, ptype string, rule []string) error {
+	_, err := a.AddPolicyReturningID(ctx, sec, ptype, rule)
+	return err
+}
 
-	vals := make([]any, 7)
-	vals[0] = ptype
+// AddPolicyReturningID adds a policy rule to the storage and returns the id of the inserted row.
+// With WithUUIDPrimaryKey the rule is still added, but its id is only returned by AddPolicyReturningUUID.
+func (a *PgxAdapter) AddPolicyReturningID(ctx context.Context, sec string, ptype string, rule []string) (int64, error) {
+	if a.uuidPrimaryKey {
+		return 0, a.addPolicyReturning(ctx, ptype, rule, nil)
+	}
 
-	for i := range 6 {
-		if i < len(rule) && rule[i] != "" {
-			vals[i+1] = rule[i]
-		} else {
-			vals[i+1] = nil
-		}
+	var id int64
+	if err := a.addPolicyReturning(ctx, ptype, rule, &id); err != nil {
+		return 0, err
 	}
 
-	sql, args, err := a.psql.
-		Insert(a.tableName).
-		Columns(insertColumns...).
-		Values(vals...).
-		ToSql()
+	return id, nil
+}
+
+// addPolicyReturning inserts a policy rule and scans the generated id into dest, if not nil
+func (a *PgxAdapter) addPolicyReturning(ctx context.Context, ptype string, rule []string, dest any) error {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return err
+	}
 
+	if dest == nil {
+		var discard any
+		dest = &discard
+	}
+
+	suffix := "RETURNING id"
+	if a.idempotentWrites {
+		suffix = a.onConflictDoNothing() + " " + suffix
+	}
+
+	sql, args, err := a.insertRuleSQL(ctx, ptype, rule, suffix)
 	if err != nil {
 		return fmt.Errorf("failed to build insert query: %w", err)
 	}
 
-	var result pgconn.CommandTag
-	result, err = a.db.Exec(ctx, sql, args...)
+	err = a.auditTx(ctx, func(db DB) error {
+		err := db.QueryRow(ctx, sql, args...).Scan(dest)
+		if a.idempotentWrites && errors.Is(err, pgx.ErrNoRows) {
+			// The rule already exists, return its id without auditing
+			return a.existingRuleID(ctx, db, ptype, rule, dest)
+		}
+		if err != nil {
+			return err
+		}
+		return a.writeAudit(ctx, db, AuditOpAdd, []auditChange{{ptype: ptype, newRule: rule}})
+	})
 
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("no rows affected")
+		}
 		// Check if it's a unique constraint violation
 		if strings.Contains(err.Error(), "duplicate key") {
 			return fmt.Errorf("policy already exists")
 		}
-		return fmt.Errorf("failed to add policy: %w", err)
-	}
-
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("no rows affected")
+		return fmt.Errorf("failed to add policy: %w", mapWriteError(err))
 	}
 
 	return nil
 }
 
+// existingRuleID scans the id of an existing rule into dest
+func (a *PgxAdapter) existingRuleID(ctx context.Context, db DB, ptype string, rule []string, dest any) error {
+	sql, args, err := a.selectRules(ctx, "id").
+		Where(sq.Eq{a.column("ptype"): ptype}).
+		Where(a.ruleEq(ptype, rule)).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build select query: %w", err)
+	}
+	return db.QueryRow(ctx, sql, args...).Scan(dest)
+}
+
 // RemovePolicy removes a policy rule from the storage
 func (a *PgxAdapter) RemovePolicyCtx(ctx context.Context, sec string, ptype string, rule []string) error {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
 
-	deleteBuilder := a.psql.Delete(a.tableName).Where(sq.Eq{"ptype": ptype})
-
-	// Add conditions for each rule value
-	for i := range 6 {
-		col := colParams[i]
-		if i < len(rule) && rule[i] != "" {
-			deleteBuilder = deleteBuilder.Where(sq.Eq{col: rule[i]})
-		} else {
-			deleteBuilder = deleteBuilder.Where(sq.Eq{col: nil})
-		}
+	if err := a.ensureInit(ctx); err != nil {
+		return err
 	}
-
-	sql, args, err := deleteBuilder.ToSql()
+	ctx, err := a.resolveTenant(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to build delete query: %w", err)
+		return err
 	}
 
-	var result pgconn.CommandTag
-	result, err = a.db.Exec(ctx, sql, args...)
-
+	sql, args, err := a.removeRuleSQL(ctx, ptype, rule)
 	if err != nil {
-		return fmt.Errorf("failed to remove policy: %w", err)
+		return fmt.Errorf("failed to build delete query: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("policy not found")
-	}
+	return a.auditTx(ctx, func(db DB) error {
+		result, err := db.Exec(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed to remove policy: %w", err)
+		}
 
-	return nil
+		if result.RowsAffected() == 0 {
+			return fmt.Errorf("policy not found")
+		}
+
+		return a.writeAudit(ctx, db, AuditOpRemove, []auditChange{{ptype: ptype, oldRule: rule}})
+	})
 }
 
 // RemoveFilteredPolicy removes policy rules that match the filter from the storage
 func (a *PgxAdapter) RemoveFilteredPolicyCtx(ctx context.Context, sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return err
+	}
 
-	if fieldIndex < 0 || fieldIndex > 5 {
+	if fieldIndex < 0 || fieldIndex >= a.valueColumnTotal() {
 		return fmt.Errorf("invalid field index: %d", fieldIndex)
 	}
 
-	deleteBuilder := a.psql.Delete(a.tableName).Where(sq.Eq{"ptype": ptype})
+	deleteBuilder := a.deleteRules(ctx).Where(sq.Eq{a.column("ptype"): ptype})
 
 	// Add conditions for filtered values
 	for i := range fieldValues {
-		if i+fieldIndex > 5 {
+		if i+fieldIndex >= a.valueColumnTotal() {
 			break
 		}
-		col := colParams[i+fieldIndex]
+		col := a.fieldColumn(ptype, i+fieldIndex)
 		if fieldValues[i] != "" {
-			deleteBuilder = deleteBuilder.Where(sq.Eq{col: fieldValues[i]})
+			deleteBuilder = deleteBuilder.Where(a.columnEq(col, fieldValues[i]))
 		}
 	}
 
@@ -272,15 +482,40 @@ func (a *PgxAdapter) RemoveFilteredPolicyCtx(ctx context.Context, sec string, pt
 		return fmt.Errorf("failed to build delete query: %w", err)
 	}
 
-	var result pgconn.CommandTag
-	result, err = a.db.Exec(ctx, sql, args...)
+	return a.auditTx(ctx, func(db DB) error {
+		removed, err := a.execAudited(ctx, db, AuditOpRemove, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed to remove filtered policies: %w", err)
+		}
 
-	if err != nil {
-		return fmt.Errorf("failed to remove filtered policies: %w", err)
-	}
+		if removed == 0 {
+			return fmt.Errorf("no matching policies found")
+		}
+
+		return nil
+	})
+}
+
+// validateModel checks that every policy rule in the model fits in the table's value columns
+func (a *PgxAdapter) validateModel(model model.Model) error {
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range model[sec] {
+			// The effect token is stored in its own column
+			columns := a.valueColumnCount()
+			maxTokens := columns
+			if _, ok := a.eftIndex(ptype); ok {
+				maxTokens++
+			}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("no matching policies found")
+			if len(ast.Tokens) > maxTokens {
+				return fmt.Errorf("ptype %s has %d tokens but the table only has %d value columns", ptype, len(ast.Tokens), columns)
+			}
+			for _, rule := range ast.Policy {
+				if len(rule) > maxTokens {
+					return fmt.Errorf("ptype %s has a rule with %d values but the table only has %d value columns", ptype, len(rule), columns)
+				}
+			}
+		}
 	}
 
 	return nil
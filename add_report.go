@@ -0,0 +1,98 @@
+package pgxadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// AddReport lists the outcome of AddPoliciesSkipDuplicates per rule
+type AddReport struct {
+	// Inserted holds the rules that were added
+	Inserted [][]string
+	// Duplicates holds the rules that already existed and were skipped
+	Duplicates [][]string
+}
+
+// AddPoliciesSkipDuplicates adds rules like AddPolicies, but inserts each rule
+// in its own savepoint so a rule that already exists is skipped instead of
+// failing the whole batch. Rules are added in one transaction, and any other
+// error rolls all of them back. It costs a few round trips per rule, so prefer
+// AddPolicies when duplicates are not expected.
+func (a *PgxAdapter) AddPoliciesSkipDuplicates(ctx context.Context, ptype string, rules [][]string) (AddReport, error) {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return AddReport{}, err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return AddReport{}, err
+	}
+
+	tx, err := a.beginWrite(ctx)
+	if err != nil {
+		return AddReport{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var report AddReport
+	for _, rule := range rules {
+		sql, args, err := a.psql.Insert(a.quotedTableName()).
+			Columns(a.insertColumns()...).
+			Values(a.insertValues(ctx, ptype, rule)...).
+			ToSql()
+		if err != nil {
+			return AddReport{}, fmt.Errorf("failed to build insert query: %w", err)
+		}
+
+		inserted, err := a.insertInSavepoint(ctx, tx, sql, args)
+		if err != nil {
+			return AddReport{}, fmt.Errorf("failed to add policies: %w", mapWriteError(err))
+		}
+		if inserted {
+			report.Inserted = append(report.Inserted, rule)
+		} else {
+			report.Duplicates = append(report.Duplicates, rule)
+		}
+	}
+
+	changes := make([]auditChange, len(report.Inserted))
+	for i, rule := range report.Inserted {
+		changes[i] = auditChange{ptype: ptype, newRule: rule}
+	}
+	if err := a.writeAudit(ctx, tx, AuditOpAdd, changes); err != nil {
+		return AddReport{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return AddReport{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	a.maybeAnalyze(ctx, int64(len(report.Inserted)))
+
+	return report, nil
+}
+
+// insertInSavepoint runs an insert in a savepoint of db and reports false,
+// rolling the savepoint back, if it violates the unique index
+func (a *PgxAdapter) insertInSavepoint(ctx context.Context, db DB, sql string, args []any) (bool, error) {
+	sp, err := db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer sp.Rollback(ctx)
+
+	if _, err := sp.Exec(ctx, sql, args...); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return false, sp.Rollback(ctx)
+		}
+		return false, err
+	}
+
+	return true, sp.Commit(ctx)
+}
@@ -0,0 +1,51 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestAddPoliciesSkipDuplicates(t *testing.T) {
+	tableName := "casbin_test_add_skip_duplicates"
+	conn := setupTestDB(t, tableName)
+	ctx := context.Background()
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	existing := [][]string{{"bob", "data2", "write"}}
+	if err := adapter.AddPolicies("p", "p", existing); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+
+	rules := [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"carol", "data3", "read"},
+	}
+	report, err := adapter.AddPoliciesSkipDuplicates(ctx, "p", rules)
+	if err != nil {
+		t.Fatalf("AddPoliciesSkipDuplicates() unexpected error: %v", err)
+	}
+
+	wantInserted := [][]string{rules[0], rules[2]}
+	if !slices.EqualFunc(report.Inserted, wantInserted, slices.Equal) {
+		t.Errorf("Inserted = %v, want %v", report.Inserted, wantInserted)
+	}
+	if !slices.EqualFunc(report.Duplicates, existing, slices.Equal) {
+		t.Errorf("Duplicates = %v, want %v", report.Duplicates, existing)
+	}
+
+	var count int
+	if err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM "+tableName).Scan(&count); err != nil {
+		t.Fatalf("Failed to count rules: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 rules, got %d", count)
+	}
+}
@@ -0,0 +1,40 @@
+package pgxadapter
+
+import (
+	"context"
+	"log/slog"
+)
+
+// defaultAutoAnalyzeThreshold is the number of inserted rows that triggers ANALYZE
+const defaultAutoAnalyzeThreshold = 1000
+
+// WithAutoAnalyze runs ANALYZE on the table after SavePolicy, AddPolicies or
+// MigrateFromTable write more rows than the threshold (1000 by default, see
+// WithAutoAnalyzeThreshold), so planner statistics do not wait for autovacuum
+// and filtered loads right after a bulk load do not fall back to sequential
+// scans. SavePolicy counts the rows it removes as well as those it inserts.
+func WithAutoAnalyze() Option {
+	return func(a *PgxAdapter) {
+		a.autoAnalyze = true
+	}
+}
+
+// WithAutoAnalyzeThreshold sets the number of written rows above which WithAutoAnalyze runs ANALYZE
+func WithAutoAnalyzeThreshold(rows int64) Option {
+	return func(a *PgxAdapter) {
+		a.autoAnalyzeThreshold = rows
+	}
+}
+
+// maybeAnalyze analyzes the table if auto analyze is enabled and written exceeds the threshold.
+// It runs after the write committed, so a failure is logged rather than returned.
+func (a *PgxAdapter) maybeAnalyze(ctx context.Context, written int64) {
+	if !a.autoAnalyze || written <= a.autoAnalyzeThreshold {
+		return
+	}
+
+	if _, err := a.dbFrom(ctx).Exec(ctx, "ANALYZE "+a.quotedTableName()); err != nil {
+		slog.Warn("casbin pgx adapter: failed to analyze table",
+			"table", a.tableName, "rows", written, "error", err)
+	}
+}
@@ -0,0 +1,115 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/casbin/casbin/v3/model"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithAutoAnalyze(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tableName := "casbin_test_auto_analyze"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithAutoAnalyze(),
+		pgxadapter.WithAutoAnalyzeThreshold(10),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	// reltuples is only refreshed by ANALYZE, VACUUM and index builds, not by inserts
+	reltuples := func() float64 {
+		var n float64
+		err := conn.QueryRow(ctx, "SELECT reltuples FROM pg_class WHERE oid = $1::regclass", tableName).Scan(&n)
+		if err != nil {
+			t.Fatalf("Failed to query reltuples: %v", err)
+		}
+		return n
+	}
+
+	rules := func(prefix string, n int) [][]string {
+		var rules [][]string
+		for i := range n {
+			rules = append(rules, []string{fmt.Sprintf("%s%d", prefix, i), "data", "read"})
+		}
+		return rules
+	}
+
+	before := reltuples()
+
+	// A batch below the threshold does not analyze
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", rules("small", 5)); err != nil {
+		t.Fatalf("AddPoliciesCtx() unexpected error: %v", err)
+	}
+	if got := reltuples(); got != before {
+		t.Errorf("reltuples after small batch = %v, want unchanged %v", got, before)
+	}
+
+	// A batch above the threshold analyzes the table
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", rules("large", 20)); err != nil {
+		t.Fatalf("AddPoliciesCtx() unexpected error: %v", err)
+	}
+	if got := reltuples(); got != 25 {
+		t.Errorf("reltuples after large batch = %v, want 25", got)
+	}
+}
+
+func TestWithAutoAnalyzeSavePolicy(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tableName := "casbin_test_auto_analyze_save"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithSaveMode(pgxadapter.SaveModeUpsertDiff),
+		pgxadapter.WithAutoAnalyze(),
+		pgxadapter.WithAutoAnalyzeThreshold(10),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	save := func(n int) {
+		m, err := model.NewModelFromString(TestModelText)
+		if err != nil {
+			t.Fatalf("Failed to create model: %v", err)
+		}
+		for i := range n {
+			m.AddPolicy("p", "p", []string{fmt.Sprintf("user%d", i), "data", "read"})
+		}
+		if err := adapter.SavePolicyCtx(ctx, m); err != nil {
+			t.Fatalf("SavePolicyCtx() unexpected error: %v", err)
+		}
+	}
+
+	var reltuples float64
+	query := "SELECT reltuples FROM pg_class WHERE oid = $1::regclass"
+
+	save(20)
+	if err := conn.QueryRow(ctx, query, tableName).Scan(&reltuples); err != nil {
+		t.Fatalf("Failed to query reltuples: %v", err)
+	}
+	if reltuples != 20 {
+		t.Errorf("reltuples after saving 20 rules = %v, want 20", reltuples)
+	}
+
+	// Removing rules counts towards the threshold, even without inserts
+	save(5)
+	if err := conn.QueryRow(ctx, query, tableName).Scan(&reltuples); err != nil {
+		t.Fatalf("Failed to query reltuples: %v", err)
+	}
+	if reltuples != 5 {
+		t.Errorf("reltuples after saving 5 of the rules = %v, want 5", reltuples)
+	}
+}
@@ -0,0 +1,269 @@
+package pgxadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// Audit operations recorded by WithAuditTable
+const (
+	AuditOpAdd    = "add"
+	AuditOpRemove = "remove"
+	AuditOpUpdate = "update"
+	AuditOpSave   = "save"
+)
+
+// AuditEntry is a row of the audit table
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	Op        string    `json:"op"`
+	Ptype     string    `json:"ptype"`
+	OldRule   []string  `json:"old_rule"`
+	NewRule   []string  `json:"new_rule"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditFilter selects audit entries for QueryAudit. Empty fields match every entry.
+type AuditFilter struct {
+	Op    []string
+	Ptype []string
+	Since time.Time
+	Until time.Time
+	Limit uint64
+}
+
+// WithAuditTable records every policy change in the append-only table name,
+// which is created together with the policy table. Each entry holds the
+// operation, the ptype, the rule before and after the change as JSON arrays,
+// and the time of the change. Entries are written in the same transaction as
+// the change, so a rolled back change leaves no entry.
+//
+// Adds and removes record the affected rules, updates record old and new rule
+// pairs, and SavePolicy records every saved rule with no old rule.
+// UpdateFilteredPolicies records the rules it removed and added. Bulk
+// maintenance with MigrateFromTable, DeduplicatePolicies and DeduplicateTable
+// is not audited. With WithTenantColumn each entry also records the tenant of
+// the change, and QueryAudit only returns the entries of the tenant of the call.
+func WithAuditTable(name string) Option {
+	return func(a *PgxAdapter) {
+		a.auditTable = name
+	}
+}
+
+// auditChange is a single rule change to record in the audit table
+type auditChange struct {
+	ptype   string
+	oldRule []string
+	newRule []string
+}
+
+// auditTableDDL returns the statements creating the audit table. Existing
+// audit tables get the tenant column with the empty tenant for their entries.
+func (a *PgxAdapter) auditTableDDL() string {
+	ddl := `CREATE TABLE IF NOT EXISTS ` + a.qualifiedName(a.auditTable) + ` (
+		id BIGSERIAL PRIMARY KEY,
+		op VARCHAR(16) NOT NULL,
+		ptype VARCHAR(100) NOT NULL,
+		old_rule JSONB NULL,
+		new_rule JSONB NULL,
+		created_at TIMESTAMPTZ NOT NULL` + a.tenantColumnDDL() + `
+	)`
+	if a.useTenantColumn {
+		ddl += `;
+		ALTER TABLE ` + a.qualifiedName(a.auditTable) + ` ADD COLUMN IF NOT EXISTS ` + tenantColumn + ` ` + tenantColumnType
+	}
+	return ddl
+}
+
+// auditTx runs fn in a transaction when the audit table is enabled, so audit
+// entries written by fn commit or roll back together with the change.
+// Otherwise fn runs directly against the database.
+func (a *PgxAdapter) auditTx(ctx context.Context, fn func(db DB) error) error {
+	if a.auditTable == "" {
+		return fn(a.dbFrom(ctx))
+	}
+
+	tx, err := a.beginWrite(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// writeAudit records changes under op in the audit table, if enabled.
+// All entries are inserted by one statement through unnest, so the number of
+// bind parameters does not grow with the number of changes.
+func (a *PgxAdapter) writeAudit(ctx context.Context, db DB, op string, changes []auditChange) error {
+	if a.auditTable == "" || len(changes) == 0 {
+		return nil
+	}
+
+	ptypes := make([]string, len(changes))
+	oldRules := make([]*string, len(changes))
+	newRules := make([]*string, len(changes))
+	for i, change := range changes {
+		ptypes[i] = change.ptype
+
+		var err error
+		if oldRules[i], err = auditRule(change.oldRule); err != nil {
+			return err
+		}
+		if newRules[i], err = auditRule(change.newRule); err != nil {
+			return err
+		}
+	}
+
+	columns, values := "", ""
+	args := []any{op, a.now(), ptypes, oldRules, newRules}
+	if a.useTenantColumn {
+		columns, values = ", "+tenantColumn, ", $6"
+		args = append(args, a.tenantOf(ctx))
+	}
+
+	sql := `INSERT INTO ` + a.qualifiedName(a.auditTable) + ` (op, ptype, old_rule, new_rule, created_at` + columns + `)
+		SELECT $1, t.ptype, t.old_rule::jsonb, t.new_rule::jsonb, $2` + values + `
+		FROM unnest($3::text[], $4::text[], $5::text[]) AS t(ptype, old_rule, new_rule)`
+
+	if _, err := db.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to write audit entries: %w", err)
+	}
+
+	return nil
+}
+
+// execAudited runs an INSERT or DELETE of policy rows and returns the number of
+// rows affected. With the audit table enabled, the statement returns the
+// affected rules so that exactly those are recorded under op.
+func (a *PgxAdapter) execAudited(ctx context.Context, db DB, op string, sql string, args ...any) (int64, error) {
+	if a.auditTable == "" {
+		result, err := db.Exec(ctx, sql, args...)
+		if err != nil {
+			return 0, err
+		}
+		return result.RowsAffected(), nil
+	}
+
+	rows, err := db.Query(ctx, sql+" RETURNING "+strings.Join(a.selectColumns(), ", "), args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var changes []auditChange
+	for rows.Next() {
+		ptype, rule, err := a.scanRule(rows)
+		if err != nil {
+			return 0, err
+		}
+		if op == AuditOpRemove {
+			changes = append(changes, auditChange{ptype: ptype, oldRule: rule})
+		} else {
+			changes = append(changes, auditChange{ptype: ptype, newRule: rule})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := a.writeAudit(ctx, db, op, changes); err != nil {
+		return 0, err
+	}
+
+	return int64(len(changes)), nil
+}
+
+// auditRule returns a rule encoded as a JSON array, or nil for no rule
+func auditRule(rule []string) (*string, error) {
+	if rule == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit rule: %w", err)
+	}
+
+	s := string(data)
+	return &s, nil
+}
+
+// QueryAudit returns the audit entries matching filter, oldest first. With
+// WithTenantColumn only the entries of the tenant of the call are returned.
+// Requires WithAuditTable.
+func (a *PgxAdapter) QueryAudit(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	ctx, cancel := a.readContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return nil, err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.auditTable == "" {
+		return nil, fmt.Errorf("audit table is not enabled")
+	}
+
+	query := a.psql.
+		Select("id", "op", "ptype", "old_rule", "new_rule", "created_at").
+		From(a.qualifiedName(a.auditTable)).
+		OrderBy("id")
+
+	if a.useTenantColumn {
+		query = query.Where(a.tenantEq(ctx))
+	}
+	if len(filter.Op) > 0 {
+		query = query.Where(sq.Eq{"op": filter.Op})
+	}
+	if len(filter.Ptype) > 0 {
+		query = query.Where(sq.Eq{"ptype": filter.Ptype})
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where(sq.GtOrEq{"created_at": filter.Since})
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where(sq.Lt{"created_at": filter.Until})
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := a.dbFrom(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit entries: %w", err)
+	}
+
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (AuditEntry, error) {
+		var e AuditEntry
+		err := row.Scan(&e.ID, &e.Op, &e.Ptype, &e.OldRule, &e.NewRule, &e.CreatedAt)
+		return e, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan audit entries: %w", err)
+	}
+
+	return entries, nil
+}
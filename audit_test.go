@@ -0,0 +1,186 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithAuditTable(t *testing.T) {
+	ctx := context.Background()
+	tableName := "casbin_test_audit"
+	auditTable := "casbin_test_audit_log"
+	conn := setupTestDB(t, tableName)
+
+	_, _ = conn.Exec(ctx, "DROP TABLE IF EXISTS "+auditTable)
+	t.Cleanup(func() {
+		_, _ = conn.Exec(ctx, "DROP TABLE IF EXISTS "+auditTable)
+	})
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	adapter, err := pgxadapter.NewAdapterWithConn(conn,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithAuditTable(auditTable),
+		pgxadapter.WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if err := adapter.AddPolicy("p", "p", []string{"bob", "data2", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if err := adapter.UpdatePolicy("p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data1", "write"}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+	if err := adapter.RemovePolicy("p", "p", []string{"bob", "data2", "read"}); err != nil {
+		t.Fatalf("Failed to remove policy: %v", err)
+	}
+
+	// Rolled back changes leave no audit entries
+	if err := adapter.AddPolicies("p", "p", [][]string{{"carol", "data3", "read"}, {"alice", "data1", "write"}}); err == nil {
+		t.Fatal("Expected duplicate AddPolicies to fail")
+	}
+	if err := adapter.UpdatePolicies("p", "p",
+		[][]string{{"alice", "data1", "write"}, {"nobody", "data9", "read"}},
+		[][]string{{"alice", "data1", "read"}, {"nobody", "data9", "write"}}); err == nil {
+		t.Fatal("Expected UpdatePolicies with a missing rule to fail")
+	}
+
+	entries, err := adapter.QueryAudit(ctx, pgxadapter.AuditFilter{})
+	if err != nil {
+		t.Fatalf("QueryAudit() unexpected error: %v", err)
+	}
+
+	expected := []pgxadapter.AuditEntry{
+		{Op: pgxadapter.AuditOpAdd, Ptype: "p", NewRule: []string{"alice", "data1", "read"}},
+		{Op: pgxadapter.AuditOpAdd, Ptype: "p", NewRule: []string{"bob", "data2", "read"}},
+		{Op: pgxadapter.AuditOpUpdate, Ptype: "p", OldRule: []string{"alice", "data1", "read"}, NewRule: []string{"alice", "data1", "write"}},
+		{Op: pgxadapter.AuditOpRemove, Ptype: "p", OldRule: []string{"bob", "data2", "read"}},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("QueryAudit() returned %d entries, want %d: %+v", len(entries), len(expected), entries)
+	}
+	for i, want := range expected {
+		got := entries[i]
+		if got.Op != want.Op || got.Ptype != want.Ptype ||
+			!slices.Equal(got.OldRule, want.OldRule) || !slices.Equal(got.NewRule, want.NewRule) {
+			t.Errorf("entry %d = %+v, want %+v", i, got, want)
+		}
+		if !got.CreatedAt.Equal(now) {
+			t.Errorf("entry %d created at %v, want %v", i, got.CreatedAt, now)
+		}
+	}
+
+	removes, err := adapter.QueryAudit(ctx, pgxadapter.AuditFilter{Op: []string{pgxadapter.AuditOpRemove}})
+	if err != nil {
+		t.Fatalf("QueryAudit() unexpected error: %v", err)
+	}
+	if len(removes) != 1 {
+		t.Errorf("QueryAudit() returned %d remove entries, want 1", len(removes))
+	}
+}
+
+func TestWithAuditTableTenant(t *testing.T) {
+	ctx := context.Background()
+	tableName := "casbin_test_audit_tenant"
+	auditTable := "casbin_test_audit_tenant_log"
+	conn := setupTestDB(t, tableName)
+
+	_, _ = conn.Exec(ctx, "DROP TABLE IF EXISTS "+auditTable)
+	t.Cleanup(func() {
+		_, _ = conn.Exec(ctx, "DROP TABLE IF EXISTS "+auditTable)
+	})
+
+	newAdapter := func(tenant string) *pgxadapter.PgxAdapter {
+		adapter, err := pgxadapter.NewAdapterWithConn(conn,
+			pgxadapter.WithTableName(tableName),
+			pgxadapter.WithAuditTable(auditTable),
+			pgxadapter.WithTenant(tenant),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		return adapter
+	}
+	acme := newAdapter("acme")
+	globex := newAdapter("globex")
+
+	if err := acme.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if err := globex.AddPolicy("p", "p", []string{"bob", "data2", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		adapter *pgxadapter.PgxAdapter
+		want    []string
+	}{
+		{name: "acme", adapter: acme, want: []string{"alice", "data1", "read"}},
+		{name: "globex", adapter: globex, want: []string{"bob", "data2", "read"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := tt.adapter.QueryAudit(ctx, pgxadapter.AuditFilter{})
+			if err != nil {
+				t.Fatalf("QueryAudit() unexpected error: %v", err)
+			}
+			if len(entries) != 1 || !slices.Equal(entries[0].NewRule, tt.want) {
+				t.Errorf("Expected only the entry of %s, got %+v", tt.name, entries)
+			}
+		})
+	}
+}
+
+func TestCopyTenantPoliciesAudit(t *testing.T) {
+	ctx := context.Background()
+	tableName := "casbin_test_audit_tenant_copy"
+	auditTable := "casbin_test_audit_tenant_copy_log"
+	conn := setupTestDB(t, tableName)
+
+	_, _ = conn.Exec(ctx, "DROP TABLE IF EXISTS "+auditTable)
+	t.Cleanup(func() {
+		_, _ = conn.Exec(ctx, "DROP TABLE IF EXISTS "+auditTable)
+	})
+
+	newAdapter := func(tenant string) *pgxadapter.PgxAdapter {
+		adapter, err := pgxadapter.NewAdapterWithConn(conn,
+			pgxadapter.WithTableName(tableName),
+			pgxadapter.WithAuditTable(auditTable),
+			pgxadapter.WithTenant(tenant),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		return adapter
+	}
+	template := newAdapter("template")
+	workspace := newAdapter("workspace")
+
+	if err := template.AddPolicy("p", "p", []string{"admin", "data1", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if _, err := template.CopyTenantPolicies(ctx, "template", "workspace"); err != nil {
+		t.Fatalf("Failed to copy policies: %v", err)
+	}
+
+	// Each tenant's log holds one add: the template's own and the workspace's copy
+	for _, adapter := range []*pgxadapter.PgxAdapter{template, workspace} {
+		entries, err := adapter.QueryAudit(ctx, pgxadapter.AuditFilter{})
+		if err != nil {
+			t.Fatalf("QueryAudit() unexpected error: %v", err)
+		}
+		if len(entries) != 1 || entries[0].Op != pgxadapter.AuditOpAdd ||
+			!slices.Equal(entries[0].NewRule, []string{"admin", "data1", "read"}) {
+			t.Errorf("Expected one add of the rule, got %+v", entries)
+		}
+	}
+}
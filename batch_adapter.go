@@ -3,6 +3,7 @@ package pgxadapter
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
 	sq "github.com/Masterminds/squirrel"
@@ -21,26 +22,45 @@ func (a *PgxAdapter) RemovePolicies(sec string, ptype string, rules [][]string)
 
 // AddPolicies adds policy rules to the storage
 func (a *PgxAdapter) AddPoliciesCtx(ctx context.Context, sec string, ptype string, rules [][]string) error {
+	if a.dedupeRules {
+		incoming := len(rules)
+		_, rules = dedupe(slices.Repeat([]string{ptype}, len(rules)), rules)
+		a.dedupedRules.Add(int64(incoming - len(rules)))
+	}
+
+	return a.withRetry(ctx, func() error {
+		return a.addPolicies(ctx, sec, ptype, rules)
+	})
+}
+
+// addPolicies runs a single attempt of AddPoliciesCtx
+func (a *PgxAdapter) addPolicies(ctx context.Context, sec string, ptype string, rules [][]string) error {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return err
+	}
+
 	if len(rules) == 0 {
 		return nil
 	}
+	if a.copyThreshold > 0 && len(rules) >= a.copyThreshold {
+		return a.copyPolicies(ctx, ptype, rules)
+	}
 
-	insertBuilder := a.psql.Insert(a.tableName).
-		Columns(insertColumns...)
+	insertBuilder := a.psql.Insert(a.quotedTableName()).
+		Columns(a.insertColumns()...)
 
 	for _, rule := range rules {
-		vals := make([]any, 7)
-		vals[0] = ptype
-
-		for i := range 6 {
-			if i < len(rule) && rule[i] != "" {
-				vals[i+1] = rule[i]
-			} else {
-				vals[i+1] = nil
-			}
-		}
-
-		insertBuilder = insertBuilder.Values(vals...)
+		insertBuilder = insertBuilder.Values(a.insertValues(ctx, ptype, rule)...)
+	}
+	if a.idempotentWrites {
+		insertBuilder = insertBuilder.Suffix(a.onConflictDoNothing())
 	}
 
 	sql, args, err := insertBuilder.ToSql()
@@ -48,75 +68,202 @@ func (a *PgxAdapter) AddPoliciesCtx(ctx context.Context, sec string, ptype strin
 		return fmt.Errorf("failed to build insert query: %w", err)
 	}
 
+	if a.idempotentWrites {
+		// Only the rules actually inserted are audited
+		var inserted int64
+		err = a.auditTx(ctx, func(db DB) error {
+			inserted, err = a.execAudited(ctx, db, AuditOpAdd, sql, args...)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add policies: %w", mapWriteError(err))
+		}
+		a.maybeAnalyze(ctx, inserted)
+		return nil
+	}
+
 	var result pgconn.CommandTag
-	result, err = a.db.Exec(ctx, sql, args...)
+	err = a.auditTx(ctx, func(db DB) error {
+		if result, err = db.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+
+		changes := make([]auditChange, len(rules))
+		for i, rule := range rules {
+			changes[i] = auditChange{ptype: ptype, newRule: rule}
+		}
+		return a.writeAudit(ctx, db, AuditOpAdd, changes)
+	})
 
 	if err != nil {
 		// Check if it's a unique constraint violation
 		if strings.Contains(err.Error(), "duplicate key") {
 			return fmt.Errorf("one or more policies already exist")
 		}
-		return fmt.Errorf("failed to add policies: %w", err)
+		return fmt.Errorf("failed to add policies: %w", mapWriteError(err))
 	}
 
 	if result.RowsAffected() == 0 {
 		return fmt.Errorf("no rows affected")
 	}
 
+	a.maybeAnalyze(ctx, result.RowsAffected())
+
 	return nil
 }
 
 // RemovePolicies removes policy rules from the storage
 func (a *PgxAdapter) RemovePoliciesCtx(ctx context.Context, sec string, ptype string, rules [][]string) error {
+	return a.withRetry(ctx, func() error {
+		return a.removePolicies(ctx, sec, ptype, rules)
+	})
+}
+
+// removePolicies runs a single attempt of RemovePoliciesCtx
+func (a *PgxAdapter) removePolicies(ctx context.Context, sec string, ptype string, rules [][]string) error {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return err
+	}
+
 	if len(rules) == 0 {
 		return nil
 	}
 
-	// Start a transaction
-	tx, err := a.db.Begin(ctx)
+	sql, args, err := a.deleteRules(ctx).
+		Where(sq.Eq{a.column("ptype"): ptype}).
+		Where(a.rulesIn(ctx, ptype, rules)).
+		ToSql()
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to build delete query: %w", err)
 	}
-	defer tx.Rollback(ctx)
 
-	var totalRowsAffected int64
+	// Only the rules actually removed are audited
+	return a.auditTx(ctx, func(db DB) error {
+		removed, err := a.execAudited(ctx, db, AuditOpRemove, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed to remove policies: %w", err)
+		}
+		if removed == 0 {
+			return fmt.Errorf("no policies found")
+		}
+		return nil
+	})
+}
 
-	for _, rule := range rules {
-		deleteBuilder := a.psql.Delete(a.tableName).Where(sq.Eq{"ptype": ptype})
+// rulesIn returns the condition matching any of rules of ptype, which are
+// passed as one array per column and unnested, so the number of bind
+// parameters does not grow with the number of rules. Rules are compared like
+// the unique index compares them, treating NULL and empty values alike.
+func (a *PgxAdapter) rulesIn(ctx context.Context, ptype string, rules [][]string) sq.Sqlizer {
+	columns := a.valueColumnTotal()
+	if a.useEftColumn {
+		columns++
+	}
 
-		// Add conditions for each rule value
-		for i := range 6 {
-			col := colParams[i]
+	values := make([][]*string, columns)
+	for i := range values {
+		values[i] = make([]*string, len(rules))
+	}
+	for j, rule := range rules {
+		rule, eft := a.splitEft(ptype, rule)
+		for i := range a.valueColumnTotal() {
 			if i < len(rule) && rule[i] != "" {
-				deleteBuilder = deleteBuilder.Where(sq.Eq{col: rule[i]})
-			} else {
-				deleteBuilder = deleteBuilder.Where(sq.Eq{col: nil})
+				values[i][j] = &rule[i]
 			}
 		}
-
-		sql, args, err := deleteBuilder.ToSql()
-		if err != nil {
-			return fmt.Errorf("failed to build delete query: %w", err)
+		if eft, ok := eft.(string); ok {
+			values[columns-1][j] = &eft
 		}
+	}
 
-		var result pgconn.CommandTag
-		result, err = tx.Exec(ctx, sql, args...)
+	// The select list names every column of the unique index expressions, so
+	// they resolve to the unnested rules rather than to the policy table
+	selectList := []string{"?::text AS " + a.column("ptype")}
+	args := []any{ptype}
+	unnest := make([]string, columns)
+	aliases := make([]string, columns)
+	for i := range columns {
+		unnest[i] = "?::text[]"
+		aliases[i] = fmt.Sprintf("c%d", i)
 
-		if err != nil {
-			return fmt.Errorf("failed to remove policy: %w", err)
+		switch col := valueColumn(i); {
+		case i == a.valueColumnTotal():
+			selectList = append(selectList, aliases[i]+" AS "+eftColumn)
+		case a.isArrayColumn(col):
+			selectList = append(selectList, "string_to_array("+aliases[i]+", "+quoteLiteral(a.arrayDelimiter)+") AS "+a.column(col))
+		default:
+			selectList = append(selectList, aliases[i]+" AS "+a.column(col))
 		}
+	}
+	if a.useTenantColumn {
+		selectList = append(selectList, "?::text AS "+tenantColumn)
+		args = append(args, a.tenantOf(ctx))
+	}
+	for _, column := range values {
+		args = append(args, column)
+	}
+
+	exprs := a.stagedRuleExprs()
+	return sq.Expr("("+exprs+") IN (SELECT "+exprs+" FROM (SELECT "+strings.Join(selectList, ", ")+
+		" FROM unnest("+strings.Join(unnest, ", ")+") AS r("+strings.Join(aliases, ", ")+")) AS rules)", args...)
+}
+
+// RemovePoliciesBulk removes the given rules and returns the number of rows
+// deleted. Rules that do not exist are skipped. Instead of one DELETE per rule
+// it copies the rules into a temporary table and deletes all of them with a
+// single join, all in one transaction, which is much faster for large batches.
+func (a *PgxAdapter) RemovePoliciesBulk(ctx context.Context, ptype string, rules [][]string) (int64, error) {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
 
-		totalRowsAffected += result.RowsAffected()
+	if err := a.ensureInit(ctx); err != nil {
+		return 0, err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(rules) == 0 {
+		return 0, nil
+	}
+
+	tx, err := a.beginWrite(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ptypes := make([]string, len(rules))
+	for i := range ptypes {
+		ptypes[i] = ptype
+	}
+	if err := a.stageRules(ctx, tx, removeStagingTable, ptypes, rules); err != nil {
+		return 0, err
 	}
 
-	if totalRowsAffected == 0 {
-		return fmt.Errorf("no policies found")
+	exprs := a.stagedRuleExprs()
+	deleteSQL := "DELETE FROM " + a.quotedTableName() + " WHERE (" + exprs + ") IN (SELECT " +
+		exprs + " FROM " + removeStagingTable + ")"
+
+	deleted, err := a.execAudited(ctx, tx, AuditOpRemove, deleteSQL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove policies: %w", err)
+	}
+	if err := a.dropStaging(ctx, tx, removeStagingTable); err != nil {
+		return 0, err
 	}
 
-	// Commit transaction
 	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return nil
+	return deleted, nil
 }
@@ -0,0 +1,12 @@
+// Package bench holds reproducible benchmarks of the adapter against a real
+// Postgres database: loading a million rules, adding a hundred thousand and
+// loading a filtered subset. They run against TEST_DATABASE_URL, by default
+// the database of docker-compose.yml, and are skipped when it is unreachable:
+//
+//	make test-db-up
+//	make bench
+//
+// Rule counts can be lowered for quick runs with -load-rules, -add-rules and
+// -filter-rules. Comparing the output of two runs with benchstat shows
+// regressions between revisions.
+package bench
@@ -0,0 +1,431 @@
+package pgxadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ChangeOp is the kind of row change reported by StreamChanges
+type ChangeOp string
+
+// Row changes reported by StreamChanges
+const (
+	ChangeInsert ChangeOp = "insert"
+	ChangeUpdate ChangeOp = "update"
+	ChangeDelete ChangeOp = "delete"
+)
+
+// PolicyChange is a change of the policy table read from the replication slot.
+// Rule is the rule after the change and OldRule the rule before it, so inserts
+// only carry Rule and deletes only OldRule.
+type PolicyChange struct {
+	Op       ChangeOp
+	Ptype    string
+	Rule     []string
+	OldRule  []string
+	OldPtype string
+}
+
+// StreamChanges consumes the logical replication slot named slot and sends
+// every insert, update and delete of the policy table on the returned channel,
+// including edits made outside the adapter such as psql sessions and
+// migrations. The slot is created with the test_decoding plugin if it does
+// not exist, which requires wal_level=logical, and the table is switched to
+// REPLICA IDENTITY FULL so deletes and updates carry the old rule.
+//
+// Changes are read every interval, and the slot is only advanced past a
+// batch once every change of it was sent, so the changes of a batch cut short
+// by a failure or the cancellation of ctx are sent again by the next stream.
+// A failure, including the cancellation of ctx, is sent on the error channel,
+// which is closed after the change channel. The slot outlives the stream and
+// retains WAL until it is consumed again or dropped with DropChangeSlot.
+func (a *PgxAdapter) StreamChanges(ctx context.Context, slot string, interval time.Duration) (<-chan PolicyChange, <-chan error) {
+	out := make(chan PolicyChange)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(out)
+
+		if err := a.streamChanges(ctx, slot, interval, out); err != nil {
+			errc <- err
+		}
+	}()
+
+	return out, errc
+}
+
+// DropChangeSlot drops the replication slot used by StreamChanges.
+// It is a no-op if the slot does not exist.
+func (a *PgxAdapter) DropChangeSlot(ctx context.Context, slot string) error {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+
+	_, err := a.db.Exec(ctx,
+		"SELECT pg_drop_replication_slot(slot_name) FROM pg_replication_slots WHERE slot_name = $1", slot)
+	if err != nil {
+		return fmt.Errorf("failed to drop replication slot: %w", err)
+	}
+	return nil
+}
+
+// streamChanges prepares the slot and polls it until ctx is cancelled
+func (a *PgxAdapter) streamChanges(ctx context.Context, slot string, interval time.Duration, out chan<- PolicyChange) error {
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return err
+	}
+	table, err := a.prepareChangeSlot(ctx, slot)
+	if err != nil {
+		return err
+	}
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		changes, lsn, err := a.readChanges(ctx, slot, table)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			select {
+			case out <- change:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if lsn != "" {
+			if err := a.advanceChangeSlot(ctx, slot, lsn); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// prepareChangeSlot creates the slot if needed and makes deletes log the whole
+// row. It returns the schema-qualified name of the policy table as
+// test_decoding prints it, resolving the schema from the search path when
+// WithSchema is not set.
+func (a *PgxAdapter) prepareChangeSlot(ctx context.Context, slot string) (string, error) {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if _, err := a.db.Exec(ctx, "ALTER TABLE "+a.quotedTableName()+" REPLICA IDENTITY FULL"); err != nil {
+		return "", fmt.Errorf("failed to set replica identity: %w", err)
+	}
+
+	var table string
+	if err := a.db.QueryRow(ctx,
+		`SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname)
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.oid = $1::regclass`, a.quotedTableName(),
+	).Scan(&table); err != nil {
+		return "", fmt.Errorf("failed to resolve table name: %w", err)
+	}
+
+	var exists bool
+	if err := a.db.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)", slot,
+	).Scan(&exists); err != nil {
+		return "", fmt.Errorf("failed to check replication slot: %w", err)
+	}
+	if exists {
+		return table, nil
+	}
+
+	if _, err := a.db.Exec(ctx, "SELECT pg_create_logical_replication_slot($1, 'test_decoding')", slot); err != nil {
+		return "", fmt.Errorf("failed to create replication slot: %w", err)
+	}
+	return table, nil
+}
+
+// readChanges peeks at the pending changes of the slot without consuming
+// them and keeps those of the policy table, named table as test_decoding
+// prints it. It also returns the position to advance the slot to once the
+// changes are sent, empty when nothing is pending.
+func (a *PgxAdapter) readChanges(ctx context.Context, slot, table string) ([]PolicyChange, string, error) {
+	ctx, cancel := a.readContext(ctx)
+	defer cancel()
+
+	rows, err := a.db.Query(ctx,
+		"SELECT lsn::text, data FROM pg_logical_slot_peek_changes($1, NULL, NULL, 'include-xids', '0', 'skip-empty-xacts', '1')", slot)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read replication slot: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []PolicyChange
+	var lsn string
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&lsn, &data); err != nil {
+			return nil, "", fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		change, ok, err := a.parseChange(ctx, data, table)
+		if err != nil {
+			return nil, "", err
+		}
+		if ok {
+			changes = append(changes, change)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return changes, lsn, nil
+}
+
+// advanceChangeSlot consumes the changes of the slot up to lsn
+func (a *PgxAdapter) advanceChangeSlot(ctx context.Context, slot, lsn string) error {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if _, err := a.db.Exec(ctx, "SELECT pg_replication_slot_advance($1, $2::pg_lsn)", slot, lsn); err != nil {
+		return fmt.Errorf("failed to advance replication slot: %w", err)
+	}
+	return nil
+}
+
+// parseChange parses a test_decoding line such as
+//
+//	table public.casbin_rule: INSERT: id[integer]:1 ptype[character varying]:'p' ...
+//
+// and reports false for transaction markers, changes of other tables and,
+// with WithTenantColumn, changes of other tenants.
+func (a *PgxAdapter) parseChange(ctx context.Context, data, table string) (PolicyChange, bool, error) {
+	rest, ok := strings.CutPrefix(data, "table ")
+	if !ok {
+		return PolicyChange{}, false, nil
+	}
+	name, rest, ok := strings.Cut(rest, ": ")
+	if !ok || name != table {
+		return PolicyChange{}, false, nil
+	}
+	action, rest, ok := strings.Cut(rest, ": ")
+	if !ok {
+		return PolicyChange{}, false, fmt.Errorf("malformed change %q", data)
+	}
+
+	switch action {
+	case "INSERT":
+		tuple, err := parseTuple(rest)
+		if err != nil {
+			return PolicyChange{}, false, err
+		}
+		ptype, rule, err := a.tupleRule(tuple)
+		if err != nil {
+			return PolicyChange{}, false, err
+		}
+		return PolicyChange{Op: ChangeInsert, Ptype: ptype, Rule: rule}, a.isTenantTuple(ctx, tuple), nil
+
+	case "DELETE":
+		tuple, err := parseTuple(rest)
+		if err != nil {
+			return PolicyChange{}, false, err
+		}
+		ptype, rule, err := a.tupleRule(tuple)
+		if err != nil {
+			return PolicyChange{}, false, err
+		}
+		return PolicyChange{Op: ChangeDelete, OldPtype: ptype, OldRule: rule}, a.isTenantTuple(ctx, tuple), nil
+
+	case "UPDATE":
+		change := PolicyChange{Op: ChangeUpdate}
+		if old, ok := strings.CutPrefix(rest, "old-key: "); ok {
+			var found bool
+			old, rest, found = strings.Cut(old, " new-tuple: ")
+			if !found {
+				return PolicyChange{}, false, fmt.Errorf("malformed change %q", data)
+			}
+			tuple, err := parseTuple(old)
+			if err != nil {
+				return PolicyChange{}, false, err
+			}
+			if change.OldPtype, change.OldRule, err = a.tupleRule(tuple); err != nil {
+				return PolicyChange{}, false, err
+			}
+		}
+		tuple, err := parseTuple(rest)
+		if err != nil {
+			return PolicyChange{}, false, err
+		}
+		if change.Ptype, change.Rule, err = a.tupleRule(tuple); err != nil {
+			return PolicyChange{}, false, err
+		}
+		return change, a.isTenantTuple(ctx, tuple), nil
+	}
+
+	return PolicyChange{}, false, nil
+}
+
+// isTenantTuple reports whether a parsed tuple belongs to the tenant of the call
+func (a *PgxAdapter) isTenantTuple(ctx context.Context, tuple map[string]*string) bool {
+	if !a.useTenantColumn {
+		return true
+	}
+	v := tuple[tenantColumn]
+	return v != nil && *v == a.tenantOf(ctx)
+}
+
+// tupleRule extracts the ptype and rule values of a parsed tuple, as scanRule
+// does, joining array columns with the array delimiter
+func (a *PgxAdapter) tupleRule(tuple map[string]*string) (string, []string, error) {
+	var ptype string
+	if v := tuple[a.columnName("ptype")]; v != nil {
+		ptype = *v
+	}
+
+	var rule []string
+	for i := range a.valueColumnTotal() {
+		col := valueColumn(i)
+		v := tuple[a.columnName(col)]
+		if v == nil {
+			continue
+		}
+		if !a.isArrayColumn(col) {
+			rule = append(rule, *v)
+			continue
+		}
+		elems, err := parseArray(*v)
+		if err != nil {
+			return "", nil, err
+		}
+		rule = append(rule, strings.Join(elems, a.arrayDelimiter))
+	}
+	if a.useEftColumn {
+		if v := tuple["eft"]; v != nil {
+			rule = append(rule, *v)
+		}
+	}
+	return ptype, rule, nil
+}
+
+// parseTuple parses the "name[type]:value" pairs of a test_decoding line.
+// NULL values map to nil.
+func parseTuple(s string) (map[string]*string, error) {
+	tuple := make(map[string]*string)
+	for s != "" {
+		s = strings.TrimLeft(s, " ")
+
+		open := strings.IndexByte(s, '[')
+		if open < 0 {
+			return nil, fmt.Errorf("malformed tuple %q", s)
+		}
+		name := strings.Trim(s[:open], `"`)
+
+		// Types such as character varying[] contain brackets themselves
+		typeEnd := strings.Index(s[open:], "]:")
+		if typeEnd < 0 {
+			return nil, fmt.Errorf("malformed tuple %q", s)
+		}
+		s = s[open+typeEnd+2:]
+
+		value, rest, err := parseTupleValue(s)
+		if err != nil {
+			return nil, err
+		}
+		tuple[name] = value
+		s = rest
+	}
+	return tuple, nil
+}
+
+// parseTupleValue parses one value, quoted with doubled quotes for text types
+func parseTupleValue(s string) (*string, string, error) {
+	if !strings.HasPrefix(s, "'") {
+		raw, rest, _ := strings.Cut(s, " ")
+		if raw == "null" {
+			return nil, rest, nil
+		}
+		return &raw, rest, nil
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != '\'' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '\'' {
+			b.WriteByte('\'')
+			i++
+			continue
+		}
+		value := b.String()
+		return &value, s[i+1:], nil
+	}
+	return nil, "", errors.New("unterminated value in tuple")
+}
+
+// parseArray parses the elements of an array value such as {a,"b c",NULL},
+// as array_to_string reads them: NULL elements are left out
+func parseArray(s string) ([]string, error) {
+	inner, ok := strings.CutPrefix(s, "{")
+	if !ok {
+		return nil, fmt.Errorf("malformed array %q", s)
+	}
+	inner, ok = strings.CutSuffix(inner, "}")
+	if !ok {
+		return nil, fmt.Errorf("malformed array %q", s)
+	}
+	if inner == "" {
+		return nil, nil
+	}
+
+	var elems []string
+	var b strings.Builder
+	quoted, escaped, wasQuoted := false, false, false
+	flush := func() {
+		elem := b.String()
+		if wasQuoted || elem != "NULL" {
+			elems = append(elems, elem)
+		}
+		b.Reset()
+		wasQuoted = false
+	}
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		switch {
+		case escaped:
+			b.WriteByte(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+			wasQuoted = true
+		case c == ',' && !quoted:
+			flush()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if quoted || escaped {
+		return nil, fmt.Errorf("malformed array %q", s)
+	}
+	flush()
+	return elems, nil
+}
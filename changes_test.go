@@ -0,0 +1,301 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestStreamChanges(t *testing.T) {
+	tableName := "casbin_test_changes"
+	pool := setupTestPool(t, tableName)
+
+	var walLevel string
+	if err := pool.QueryRow(context.Background(), "SHOW wal_level").Scan(&walLevel); err != nil {
+		t.Fatalf("Failed to read wal_level: %v", err)
+	}
+	if walLevel != "logical" {
+		t.Skip("StreamChanges requires wal_level=logical")
+	}
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	const slot = "casbin_test_changes_slot"
+	t.Cleanup(func() {
+		if err := adapter.DropChangeSlot(context.Background(), slot); err != nil {
+			t.Errorf("Failed to drop slot: %v", err)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errc := adapter.StreamChanges(ctx, slot, 50*time.Millisecond)
+
+	// Wait for the slot before writing, changes made earlier are not decoded
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var exists bool
+		_ = pool.QueryRow(context.Background(),
+			"SELECT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)", slot).Scan(&exists)
+		if exists {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the replication slot")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Out-of-band edits, as from psql
+	statements := []string{
+		"INSERT INTO " + tableName + " (ptype, v0, v1, v2) VALUES ('p', 'alice', 'data1', 'read')",
+		"UPDATE " + tableName + " SET v2 = 'it''s' WHERE v0 = 'alice'",
+		"DELETE FROM " + tableName + " WHERE v0 = 'alice'",
+	}
+	for _, stmt := range statements {
+		if _, err := pool.Exec(context.Background(), stmt); err != nil {
+			t.Fatalf("Failed to execute %q: %v", stmt, err)
+		}
+	}
+
+	expected := []pgxadapter.PolicyChange{
+		{Op: pgxadapter.ChangeInsert, Ptype: "p", Rule: []string{"alice", "data1", "read"}},
+		{Op: pgxadapter.ChangeUpdate, Ptype: "p", Rule: []string{"alice", "data1", "it's"}, OldPtype: "p", OldRule: []string{"alice", "data1", "read"}},
+		{Op: pgxadapter.ChangeDelete, OldPtype: "p", OldRule: []string{"alice", "data1", "it's"}},
+	}
+	for i, want := range expected {
+		select {
+		case got := <-changes:
+			if got.Op != want.Op || got.Ptype != want.Ptype || got.OldPtype != want.OldPtype ||
+				!slices.Equal(got.Rule, want.Rule) || !slices.Equal(got.OldRule, want.OldRule) {
+				t.Errorf("change %d = %+v, want %+v", i, got, want)
+			}
+		case err := <-errc:
+			t.Fatalf("StreamChanges failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for change %d", i)
+		}
+	}
+}
+
+func TestStreamChangesSchema(t *testing.T) {
+	tableName := "casbin_test_changes_schema"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	var walLevel string
+	if err := pool.QueryRow(ctx, "SHOW wal_level").Scan(&walLevel); err != nil {
+		t.Fatalf("Failed to read wal_level: %v", err)
+	}
+	if walLevel != "logical" {
+		t.Skip("StreamChanges requires wal_level=logical")
+	}
+
+	// Two tenants with a table of the same name in schemas of their own
+	newAdapter := func(schema string) *pgxadapter.PgxAdapter {
+		_, _ = pool.Exec(ctx, "DROP SCHEMA IF EXISTS "+schema+" CASCADE")
+		t.Cleanup(func() {
+			_, _ = pool.Exec(context.Background(), "DROP SCHEMA IF EXISTS "+schema+" CASCADE")
+		})
+		adapter, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName), pgxadapter.WithSchema(schema))
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		return adapter
+	}
+	acme := newAdapter("casbin_test_changes_acme")
+	beta := newAdapter("casbin_test_changes_beta")
+
+	const slot = "casbin_test_changes_schema_slot"
+	t.Cleanup(func() {
+		if err := acme.DropChangeSlot(context.Background(), slot); err != nil {
+			t.Errorf("Failed to drop slot: %v", err)
+		}
+	})
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changes, errc := acme.StreamChanges(streamCtx, slot, 50*time.Millisecond)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var exists bool
+		_ = pool.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)", slot).Scan(&exists)
+		if exists {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the replication slot")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if err := beta.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if err := acme.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	// The change of the other schema is skipped, so the first change is alice's
+	select {
+	case got := <-changes:
+		if got.Op != pgxadapter.ChangeInsert || !slices.Equal(got.Rule, []string{"alice", "data1", "read"}) {
+			t.Errorf("first change = %+v, want the insert of alice's rule", got)
+		}
+	case err := <-errc:
+		t.Fatalf("StreamChanges failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a change")
+	}
+}
+
+// startChangeStream starts StreamChanges on slot and waits until the slot
+// exists, as changes made before it are not decoded
+func startChangeStream(t *testing.T, ctx context.Context, pool *pgxpool.Pool, adapter *pgxadapter.PgxAdapter, slot string) (<-chan pgxadapter.PolicyChange, <-chan error) {
+	t.Helper()
+
+	changes, errc := adapter.StreamChanges(ctx, slot, 50*time.Millisecond)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var exists bool
+		_ = pool.QueryRow(context.Background(),
+			"SELECT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)", slot).Scan(&exists)
+		if exists {
+			return changes, errc
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the replication slot")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// skipWithoutLogicalWAL skips tests needing wal_level=logical
+func skipWithoutLogicalWAL(t *testing.T, pool *pgxpool.Pool) {
+	t.Helper()
+
+	var walLevel string
+	if err := pool.QueryRow(context.Background(), "SHOW wal_level").Scan(&walLevel); err != nil {
+		t.Fatalf("Failed to read wal_level: %v", err)
+	}
+	if walLevel != "logical" {
+		t.Skip("StreamChanges requires wal_level=logical")
+	}
+}
+
+func TestStreamChangesRedelivery(t *testing.T) {
+	tableName := "casbin_test_changes_redelivery"
+	pool := setupTestPool(t, tableName)
+	skipWithoutLogicalWAL(t, pool)
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	const slot = "casbin_test_changes_redelivery_slot"
+	t.Cleanup(func() {
+		if err := adapter.DropChangeSlot(context.Background(), slot); err != nil {
+			t.Errorf("Failed to drop slot: %v", err)
+		}
+	})
+
+	// A first stream creates the slot and stops before any change is made
+	ctx, cancel := context.WithCancel(context.Background())
+	_, errc := startChangeStream(t, ctx, pool, adapter, slot)
+	cancel()
+	for range errc {
+	}
+
+	if err := adapter.AddPoliciesCtx(context.Background(), "p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+	}); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+
+	receive := func(changes <-chan pgxadapter.PolicyChange, errc <-chan error, want []string) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if got.Op != pgxadapter.ChangeInsert || !slices.Equal(got.Rule, want) {
+				t.Errorf("change = %+v, want the insert of %v", got, want)
+			}
+		case err := <-errc:
+			t.Fatalf("StreamChanges failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for the insert of %v", want)
+		}
+	}
+
+	// The second stream is cancelled after the first change of the batch
+	ctx, cancel = context.WithCancel(context.Background())
+	changes, errc := adapter.StreamChanges(ctx, slot, 50*time.Millisecond)
+	receive(changes, errc, []string{"alice", "data1", "read"})
+	cancel()
+	for range errc {
+	}
+
+	// The whole batch is sent again
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	changes, errc = adapter.StreamChanges(ctx, slot, 50*time.Millisecond)
+	receive(changes, errc, []string{"alice", "data1", "read"})
+	receive(changes, errc, []string{"bob", "data2", "write"})
+}
+
+func TestStreamChangesArrayColumn(t *testing.T) {
+	tableName := "casbin_test_changes_array"
+	pool := setupTestPool(t, tableName)
+	skipWithoutLogicalWAL(t, pool)
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithArrayColumn("v1"),
+		pgxadapter.WithArrayDelimiter("|"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	const slot = "casbin_test_changes_array_slot"
+	t.Cleanup(func() {
+		if err := adapter.DropChangeSlot(context.Background(), slot); err != nil {
+			t.Errorf("Failed to drop slot: %v", err)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, errc := startChangeStream(t, ctx, pool, adapter, slot)
+
+	rule := []string{"alice", "data1|data 2|a,b", "read"}
+	if err := adapter.AddPolicyCtx(context.Background(), "p", "p", rule); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	// Streamed rules read the array column back like loaded ones
+	select {
+	case got := <-changes:
+		if got.Op != pgxadapter.ChangeInsert || !slices.Equal(got.Rule, rule) {
+			t.Errorf("change = %+v, want the insert of %v", got, rule)
+		}
+	case err := <-errc:
+		t.Fatalf("StreamChanges failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a change")
+	}
+}
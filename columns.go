@@ -0,0 +1,574 @@
+package pgxadapter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// isArrayColumn reports whether the value column is stored as TEXT[]
+func (a *PgxAdapter) isArrayColumn(col string) bool {
+	return a.arrayColumns[col]
+}
+
+// defaultColumnLength is the VARCHAR length of value columns without WithColumnLength
+const defaultColumnLength = 100
+
+// WithColumnLength sets the VARCHAR length of the value columns (v0..v5),
+// which defaults to 100. Existing tables with shorter columns are widened when
+// the adapter creates its table; columns are never narrowed.
+func WithColumnLength(n int) Option {
+	return func(a *PgxAdapter) {
+		a.columnLength = n
+	}
+}
+
+// WithTextColumns stores the value columns (v0..v5) as unbounded TEXT instead
+// of VARCHAR, for values such as long URLs or JWT subjects. Existing VARCHAR
+// columns are widened to TEXT when the adapter creates its table. The unique
+// index still limits a rule to about 2700 bytes in total.
+func WithTextColumns() Option {
+	return func(a *PgxAdapter) {
+		a.textColumns = true
+	}
+}
+
+// columnType returns the SQL type of a value column
+func (a *PgxAdapter) columnType(col string) string {
+	if a.isArrayColumn(col) {
+		return "TEXT[]"
+	}
+	if a.textColumns {
+		return "TEXT"
+	}
+	return fmt.Sprintf("VARCHAR(%d)", a.valueColumnLength())
+}
+
+// valueColumnLength returns the VARCHAR length of the value columns
+func (a *PgxAdapter) valueColumnLength() int {
+	if a.columnLength > 0 {
+		return a.columnLength
+	}
+	return defaultColumnLength
+}
+
+// valueColumnsDDL returns the column definitions of the value columns
+func (a *PgxAdapter) valueColumnsDDL() string {
+	var ddl string
+	for i := range a.valueColumnTotal() {
+		col := valueColumn(i)
+		ddl += ",\n\t\t" + a.column(col) + " " + a.columnType(col)
+	}
+	return ddl
+}
+
+// alterColumns adds the value columns missing from an existing table, such as
+// those of WithExtraColumns, and widens the ones narrower than the configured
+// type. The unique index is recreated in the same statement, so it covers
+// every value column and its expressions keep matching the ON CONFLICT
+// clauses built from uniqueIndexColumns. The tenant column of WithTenantColumn
+// and the hash column of WithHashUniqueIndex are added here too, replacing the
+// unique index of older tables.
+func (a *PgxAdapter) alterColumns(ctx context.Context) error {
+	rows, err := a.db.Query(ctx,
+		`SELECT attname, format_type(atttypid, NULL), CASE WHEN atttypid = 'varchar'::regtype THEN atttypmod - 4 ELSE -1 END
+		FROM pg_attribute
+		WHERE attrelid = to_regclass($1) AND attnum > 0 AND NOT attisdropped`,
+		a.quotedTableName())
+	if err != nil {
+		return fmt.Errorf("failed to query column types: %w", err)
+	}
+	defer rows.Close()
+
+	// Value columns by table column name
+	valueColumns := make(map[string]string)
+	for i := range a.valueColumnTotal() {
+		valueColumns[a.columnName(valueColumn(i))] = valueColumn(i)
+	}
+
+	var alters []string
+	var hasRuleHash, hasTenant bool
+	for rows.Next() {
+		var name, typ string
+		var length int
+		if err := rows.Scan(&name, &typ, &length); err != nil {
+			return fmt.Errorf("failed to scan column type: %w", err)
+		}
+		switch name {
+		case ruleHashColumn:
+			hasRuleHash = true
+		case tenantColumn:
+			hasTenant = true
+		}
+		col, ok := valueColumns[name]
+		if !ok {
+			continue
+		}
+		delete(valueColumns, name)
+		if a.isArrayColumn(col) || typ != "character varying" {
+			continue
+		}
+		if a.textColumns || (length > 0 && length < a.valueColumnLength()) {
+			alters = append(alters, "ALTER COLUMN "+a.column(col)+" TYPE "+a.columnType(col))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	for i := range a.valueColumnTotal() {
+		col := valueColumn(i)
+		if _, missing := valueColumns[a.columnName(col)]; missing {
+			alters = append(alters, "ADD COLUMN "+a.column(col)+" "+a.columnType(col))
+		}
+	}
+	if a.useTenantColumn && !hasTenant {
+		alters = append(alters, "ADD COLUMN "+tenantColumn+" "+tenantColumnType)
+	}
+	if a.hashUniqueIndex && (len(alters) > 0 || !hasRuleHash) {
+		// The hash column depends on the value columns, so it is added again
+		// after them and covers the added ones
+		alters = append([]string{"DROP COLUMN IF EXISTS " + ruleHashColumn}, alters...)
+		alters = append(alters, "ADD COLUMN "+a.ruleHashColumnDDL())
+	}
+	if len(alters) == 0 {
+		return nil
+	}
+
+	// Sent as one query string, so the statements run in a single implicit transaction
+	ddl := "ALTER TABLE " + a.quotedTableName() + " " + strings.Join(alters, ", ")
+	if a.uniqueConstraint == "" {
+		ddl += ";\n\t\tDROP INDEX IF EXISTS " + a.qualifiedName(a.uniqueIndexName()) + ";\n\t\t" + a.uniqueIndexDDL()
+	}
+	if _, err := a.db.Exec(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to alter columns: %w", err)
+	}
+	return nil
+}
+
+// WithColumnNames maps the rule columns (ptype, v0..v5) to other column names,
+// so the adapter can run against an existing table without renaming its
+// columns, e.g. WithColumnNames(map[string]string{"v0": "subject"}). Columns
+// missing from the map keep their default name.
+func WithColumnNames(names map[string]string) Option {
+	return func(a *PgxAdapter) {
+		a.columnNames = names
+	}
+}
+
+// validateColumnNames checks that WithColumnNames only maps rule columns
+func (a *PgxAdapter) validateColumnNames() error {
+	for col := range a.columnNames {
+		if valid := a.tableRuleColumns(); !slices.Contains(valid, col) {
+			return fmt.Errorf("invalid column mapping %q: valid columns are %s", col, strings.Join(valid, ", "))
+		}
+	}
+	return nil
+}
+
+// columnName returns the name of the table column storing the rule column col
+func (a *PgxAdapter) columnName(col string) string {
+	if name, ok := a.columnNames[col]; ok {
+		return name
+	}
+	return col
+}
+
+// column returns the identifier of the table column storing the rule column col,
+// quoted when it is mapped with WithColumnNames
+func (a *PgxAdapter) column(col string) string {
+	if name, ok := a.columnNames[col]; ok {
+		return pgx.Identifier{name}.Sanitize()
+	}
+	return col
+}
+
+// columns returns the identifiers of the table columns storing the rule columns cols
+func (a *PgxAdapter) columns(cols []string) []string {
+	if len(a.columnNames) == 0 {
+		return cols
+	}
+	mapped := make([]string, len(cols))
+	for i, col := range cols {
+		mapped[i] = a.column(col)
+	}
+	return mapped
+}
+
+// columnExpr returns the expression reading a value column as a single string.
+// Array columns are joined back with the array delimiter.
+func (a *PgxAdapter) columnExpr(col string) string {
+	if a.isArrayColumn(col) {
+		return "array_to_string(" + a.column(col) + ", " + quoteLiteral(a.arrayDelimiter) + ")"
+	}
+	return a.column(col)
+}
+
+// columnEq returns a condition comparing a value column with a value or a slice of values
+func (a *PgxAdapter) columnEq(col string, value any) sq.Sqlizer {
+	return sq.Eq{a.columnExpr(col): value}
+}
+
+// emptyValue returns the value written for an empty value of col. Tables
+// attached with WithExistingUniqueConstraint typically declare their columns
+// NOT NULL with an empty string default and compare empty values with =, so
+// they get the empty string; the adapter's own tables get NULL.
+func (a *PgxAdapter) emptyValue(col string) any {
+	if a.uniqueConstraint == "" || a.isArrayColumn(col) {
+		return nil
+	}
+	return ""
+}
+
+// columnValue returns the value to write into a value column.
+// Tokens for array columns are split on the array delimiter.
+func (a *PgxAdapter) columnValue(col string, value string) any {
+	if a.isArrayColumn(col) {
+		return sq.Expr("string_to_array(?, ?)", value, a.arrayDelimiter)
+	}
+	return value
+}
+
+// selectColumns returns the select list for reading policy rules
+func (a *PgxAdapter) selectColumns() []string {
+	columns := []string{a.column("ptype")}
+	for i := range a.valueColumnTotal() {
+		col := valueColumn(i)
+		switch {
+		case a.isArrayColumn(col):
+			columns = append(columns, a.columnExpr(col)+" AS "+col)
+		case a.emptyValue(col) != nil:
+			// Read back as NULL, so rules load without their empty values
+			columns = append(columns, "NULLIF("+a.column(col)+", '') AS "+col)
+		default:
+			columns = append(columns, a.column(col))
+		}
+	}
+	if a.useEftColumn {
+		columns = append(columns, eftColumn)
+	}
+	return columns
+}
+
+// WithExtraColumns adds n value columns after v5 (v6, v7, ...) for models
+// whose rules have more than six values, such as ABAC models with many
+// attributes. Existing tables get the missing columns when the adapter creates
+// its table.
+func WithExtraColumns(n int) Option {
+	return func(a *PgxAdapter) {
+		a.extraColumns = n
+	}
+}
+
+// valueColumnTotal returns the number of value columns of the table
+func (a *PgxAdapter) valueColumnTotal() int {
+	return defaultValueColumns + max(a.extraColumns, 0)
+}
+
+// tableRuleColumns returns ptype followed by every value column of the table
+func (a *PgxAdapter) tableRuleColumns() []string {
+	columns := make([]string, 0, a.valueColumnTotal()+1)
+	columns = append(columns, "ptype")
+	for i := range a.valueColumnTotal() {
+		columns = append(columns, valueColumn(i))
+	}
+	return columns
+}
+
+// storedColumns returns every rule column of the table
+func (a *PgxAdapter) storedColumns() []string {
+	columns := a.tableRuleColumns()
+	if a.useEftColumn {
+		columns = append(columns, eftColumn)
+	}
+	if a.useTenantColumn {
+		columns = append(columns, tenantColumn)
+	}
+	return columns
+}
+
+// valueColumnCount returns the number of value columns written by inserts
+func (a *PgxAdapter) valueColumnCount() int {
+	if len(a.insertValueColumns) > 0 {
+		return len(a.insertValueColumns)
+	}
+	return a.valueColumnTotal()
+}
+
+// insertColumns returns the column list for writing policy rules.
+// Value columns beyond valueColumnCount are left to default to NULL.
+func (a *PgxAdapter) insertColumns() []string {
+	return a.columns(a.ruleInsertColumns())
+}
+
+// copyColumns returns the unquoted column names of insertColumns, as COPY takes them
+func (a *PgxAdapter) copyColumns() []string {
+	columns := a.ruleInsertColumns()
+	for i, col := range columns {
+		columns[i] = a.columnName(col)
+	}
+	return columns
+}
+
+// ruleInsertColumns returns the rule columns written by inserts
+func (a *PgxAdapter) ruleInsertColumns() []string {
+	n := a.valueColumnCount()
+	columns := make([]string, 0, n+3)
+	columns = append(columns, a.tableRuleColumns()[:n+1]...)
+	if a.useEftColumn {
+		columns = append(columns, eftColumn)
+	}
+	if a.useTenantColumn {
+		columns = append(columns, tenantColumn)
+	}
+	return append(columns, a.timestampColumns()...)
+}
+
+// insertValues returns the values of a rule in insertColumns order
+func (a *PgxAdapter) insertValues(ctx context.Context, ptype string, rule []string) []any {
+	rule, eft := a.splitEft(ptype, rule)
+
+	n := a.valueColumnCount()
+	vals := make([]any, n+1, n+3)
+	vals[0] = ptype
+
+	for i := range n {
+		if i < len(rule) && rule[i] != "" {
+			vals[i+1] = a.columnValue(valueColumn(i), rule[i])
+		} else {
+			vals[i+1] = a.emptyValue(valueColumn(i))
+		}
+	}
+
+	if a.useEftColumn {
+		vals = append(vals, eft)
+	}
+	if a.useTenantColumn {
+		vals = append(vals, a.tenantOf(ctx))
+	}
+
+	return append(vals, a.timestampValues()...)
+}
+
+// copyValues returns the values of a rule in insertColumns order for COPY,
+// which takes array columns as slices instead of SQL expressions
+func (a *PgxAdapter) copyValues(ctx context.Context, ptype string, rule []string) []any {
+	vals := a.insertValues(ctx, ptype, rule)
+	for i := range a.valueColumnCount() {
+		if vals[i+1] != nil && a.isArrayColumn(valueColumn(i)) {
+			vals[i+1] = strings.Split(rule[i], a.arrayDelimiter)
+		}
+	}
+	return vals
+}
+
+// ruleEq returns the conditions matching every value column of a rule exactly
+func (a *PgxAdapter) ruleEq(ptype string, rule []string) sq.And {
+	rule, eft := a.splitEft(ptype, rule)
+
+	conds := sq.And{}
+	for i := range a.valueColumnTotal() {
+		col := valueColumn(i)
+		switch {
+		case i < len(rule) && rule[i] != "":
+			conds = append(conds, a.columnEq(col, rule[i]))
+		case a.emptyValue(col) != nil:
+			conds = append(conds, sq.Or{sq.Eq{a.column(col): nil}, sq.Eq{a.column(col): ""}})
+		default:
+			conds = append(conds, sq.Eq{a.column(col): nil})
+		}
+	}
+
+	if a.useEftColumn {
+		conds = append(conds, sq.Eq{eftColumn: eft})
+	}
+
+	return conds
+}
+
+// ruleSetMap returns the assignments writing every value column of a rule,
+// and updated_at with WithTimestamps
+func (a *PgxAdapter) ruleSetMap(ptype string, rule []string) map[string]any {
+	rule, eft := a.splitEft(ptype, rule)
+
+	setMap := make(map[string]any)
+	for i := range a.valueColumnTotal() {
+		col := valueColumn(i)
+		if i < len(rule) && rule[i] != "" {
+			setMap[a.column(col)] = a.columnValue(col, rule[i])
+		} else {
+			setMap[a.column(col)] = a.emptyValue(col)
+		}
+	}
+
+	if a.useEftColumn {
+		setMap[eftColumn] = eft
+	}
+	if a.timestamps {
+		setMap[updatedAtColumn] = a.now()
+	}
+
+	return setMap
+}
+
+// fieldColumn returns the column holding the rule value at index for ptype
+func (a *PgxAdapter) fieldColumn(ptype string, index int) string {
+	if idx, ok := a.eftIndex(ptype); ok && idx == index {
+		return eftColumn
+	}
+	return valueColumn(index)
+}
+
+// scanRule scans a row read with selectColumns into its ptype and rule values
+func (a *PgxAdapter) scanRule(rows pgx.Rows) (string, []string, error) {
+	return a.scanRuleWith(rows)
+}
+
+// scanRuleWith scans a row read with selectColumns followed by columns scanned into extra
+func (a *PgxAdapter) scanRuleWith(rows pgx.Rows, extra ...any) (string, []string, error) {
+	line, err := a.newRuleScanner(extra...).scanLine(rows, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return line[0], line[1:], nil
+}
+
+// ruleScanner scans rows read with selectColumns. Its scan targets are
+// allocated once and reused for every row, so loading many rules only
+// allocates the lines themselves.
+type ruleScanner struct {
+	ptype  string
+	values []sql.NullString
+	dest   []any
+}
+
+// newRuleScanner returns a scanner of rows read with selectColumns followed
+// by columns scanned into extra
+func (a *PgxAdapter) newRuleScanner(extra ...any) *ruleScanner {
+	columns := a.valueColumnTotal()
+	if a.useEftColumn {
+		columns++
+	}
+
+	s := &ruleScanner{values: make([]sql.NullString, columns)}
+	s.dest = make([]any, 0, 1+columns+len(extra))
+	s.dest = append(s.dest, &s.ptype)
+	for i := range s.values {
+		s.dest = append(s.dest, &s.values[i])
+	}
+	s.dest = append(s.dest, extra...)
+	return s
+}
+
+// scanLine scans the current row into a line of its ptype followed by its rule
+// values, appended to buf[:0]. A nil buf allocates a line of the exact size,
+// pass the previous line to reuse it when it is not retained.
+func (s *ruleScanner) scanLine(rows pgx.Rows, buf []string) ([]string, error) {
+	if err := rows.Scan(s.dest...); err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	if buf == nil {
+		size := 1
+		for _, v := range s.values {
+			if v.Valid {
+				size++
+			}
+		}
+		buf = make([]string, 0, size)
+	}
+
+	// The effect is always the last token, so appending it restores its position
+	line := append(buf[:0], s.ptype)
+	for _, v := range s.values {
+		if v.Valid {
+			line = append(line, v.String)
+		}
+	}
+	return line, nil
+}
+
+// orderBy returns the ORDER BY list for reading policy rules
+func (a *PgxAdapter) orderBy() []string {
+	if !a.canonicalOrder {
+		return []string{"id"}
+	}
+
+	orderBy := []string{a.column("ptype") + ` COLLATE "C"`}
+	for i := range a.valueColumnTotal() {
+		orderBy = append(orderBy, a.columnExpr(valueColumn(i))+` COLLATE "C" NULLS FIRST`)
+	}
+	if a.useEftColumn {
+		orderBy = append(orderBy, eftColumn+` COLLATE "C" NULLS FIRST`)
+	}
+
+	// Ties are only possible between rows the unique index treats as equal
+	return append(orderBy, "id")
+}
+
+// uniqueIndexColumns returns the parenthesized expression list of the unique index over the rule columns
+func (a *PgxAdapter) uniqueIndexColumns() string {
+	if a.hashUniqueIndex {
+		// Unique indexes of partitioned tables must include the partition key
+		if a.partitionByPtype {
+			return "(" + a.column("ptype") + ", " + ruleHashColumn + ")"
+		}
+		return "(" + ruleHashColumn + ")"
+	}
+	return "(" + strings.Join(a.uniqueIndexExprs(), ", ") + ")"
+}
+
+// uniqueIndexExprs returns the expressions of the unique index over the rule columns
+func (a *PgxAdapter) uniqueIndexExprs() []string {
+	exprs := []string{a.column("ptype")}
+	for i := range a.valueColumnTotal() {
+		col := valueColumn(i)
+		if a.isArrayColumn(col) {
+			exprs = append(exprs, "COALESCE("+a.column(col)+",'{}')")
+		} else {
+			exprs = append(exprs, "COALESCE("+a.column(col)+",'')")
+		}
+	}
+	if a.useEftColumn {
+		exprs = append(exprs, "COALESCE("+eftColumn+",'')")
+	}
+	if a.useTenantColumn {
+		exprs = append(exprs, tenantColumn)
+	}
+	return exprs
+}
+
+// checkConstraintsDDL returns the table constraints added with WithCheckConstraint
+func (a *PgxAdapter) checkConstraintsDDL() string {
+	var ddl string
+	for i := range a.valueColumnTotal() {
+		col := valueColumn(i)
+		allowed := a.checkConstraints[col]
+		if len(allowed) == 0 {
+			continue
+		}
+
+		literals := make([]string, len(allowed))
+		for j, value := range allowed {
+			literals[j] = quoteLiteral(value)
+		}
+
+		name := pgx.Identifier{"chk_" + a.tableName + "_" + col}.Sanitize()
+		if a.isArrayColumn(col) {
+			ddl += ",\n\t\tCONSTRAINT " + name + " CHECK (" + a.column(col) + " <@ ARRAY[" + strings.Join(literals, ", ") + "]::text[])"
+		} else {
+			ddl += ",\n\t\tCONSTRAINT " + name + " CHECK (" + a.column(col) + " IN (" + strings.Join(literals, ", ") + "))"
+		}
+	}
+	return ddl
+}
+
+// quoteLiteral quotes s as a SQL string literal
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,244 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestColumnTypes(t *testing.T) {
+	tableName := "casbin_test_column_types"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	// Created with the default VARCHAR(100) columns
+	if _, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName)); err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     []pgxadapter.Option
+		wantType string
+		value    string
+	}{
+		{
+			name:     "widened_to_longer_varchar",
+			opts:     []pgxadapter.Option{pgxadapter.WithColumnLength(255)},
+			wantType: "character varying(255)",
+			value:    strings.Repeat("a", 200),
+		},
+		{
+			name:     "not_narrowed",
+			opts:     []pgxadapter.Option{pgxadapter.WithColumnLength(50)},
+			wantType: "character varying(255)",
+			value:    strings.Repeat("b", 200),
+		},
+		{
+			name:     "widened_to_text",
+			opts:     []pgxadapter.Option{pgxadapter.WithTextColumns()},
+			wantType: "text",
+			value:    "https://example.com/" + strings.Repeat("c", 1000),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]pgxadapter.Option{pgxadapter.WithTableName(tableName)}, tt.opts...)
+			adapter, err := pgxadapter.NewAdapterWithPool(pool, opts...)
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			var typ string
+			if err := pool.QueryRow(ctx,
+				"SELECT format_type(atttypid, atttypmod) FROM pg_attribute WHERE attrelid = $1::regclass AND attname = 'v0'",
+				tableName).Scan(&typ); err != nil {
+				t.Fatalf("Failed to query column type: %v", err)
+			}
+			if typ != tt.wantType {
+				t.Errorf("Expected column type %q, got %q", tt.wantType, typ)
+			}
+
+			if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{tt.value, "data1", "read"}); err != nil {
+				t.Fatalf("Failed to add policy: %v", err)
+			}
+			// Duplicates must still be detected through the recreated unique index
+			if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{tt.value, "data1", "read"}); err == nil {
+				t.Error("Expected adding a duplicate rule to fail")
+			}
+		})
+	}
+}
+
+func TestWithColumnNames(t *testing.T) {
+	tableName := "casbin_test_column_names"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	// A legacy table using its own column names, including a mixed case one
+	if _, err := pool.Exec(ctx, `CREATE TABLE `+tableName+` (
+		id SERIAL PRIMARY KEY,
+		kind VARCHAR(100) NOT NULL,
+		"Subject" VARCHAR(100),
+		object VARCHAR(100),
+		action VARCHAR(100),
+		v3 VARCHAR(100),
+		v4 VARCHAR(100),
+		v5 VARCHAR(100)
+	)`); err != nil {
+		t.Fatalf("Failed to create legacy table: %v", err)
+	}
+	if _, err := pool.Exec(ctx,
+		`INSERT INTO `+tableName+` (kind, "Subject", object, action) VALUES ('p', 'alice', 'data1', 'read')`,
+	); err != nil {
+		t.Fatalf("Failed to insert legacy rule: %v", err)
+	}
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithColumnNames(map[string]string{
+			"ptype": "kind",
+			"v0":    "Subject",
+			"v1":    "object",
+			"v2":    "action",
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	m, err := model.NewModelFromString(TestModelText)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("Failed to create enforcer: %v", err)
+	}
+	if ok, _ := e.Enforce("alice", "data1", "read"); !ok {
+		t.Error("Expected the legacy rule to be loaded")
+	}
+
+	if _, err := e.AddPolicies([][]string{{"bob", "data2", "write"}, {"carol", "data3", "read"}}); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+	if _, err := e.UpdatePolicy([]string{"bob", "data2", "write"}, []string{"bob", "data2", "read"}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+	if _, err := e.RemoveFilteredPolicy(0, "carol"); err != nil {
+		t.Fatalf("Failed to remove policy: %v", err)
+	}
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+
+	if err := e.LoadFilteredPolicy(pgxadapter.Filter{V0: []string{"bob"}}); err != nil {
+		t.Fatalf("Failed to load filtered policy: %v", err)
+	}
+	policies, _ := e.GetPolicy()
+	if len(policies) != 1 || policies[0][0] != "bob" || policies[0][2] != "read" {
+		t.Errorf("Expected only the updated rule of bob, got %v", policies)
+	}
+
+	var subjects []string
+	rows, err := pool.Query(ctx, `SELECT "Subject" FROM `+tableName+` WHERE kind = 'p' ORDER BY "Subject"`)
+	if err != nil {
+		t.Fatalf("Failed to query legacy table: %v", err)
+	}
+	for rows.Next() {
+		var subject string
+		if err := rows.Scan(&subject); err != nil {
+			t.Fatalf("Failed to scan subject: %v", err)
+		}
+		subjects = append(subjects, subject)
+	}
+	if strings.Join(subjects, ",") != "alice,bob" {
+		t.Errorf("Expected the rules to be stored in the mapped columns, got %v", subjects)
+	}
+}
+
+func TestWithColumnNamesInvalid(t *testing.T) {
+	tableName := "casbin_test_column_names_invalid"
+	pool := setupTestPool(t, tableName)
+
+	_, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithColumnNames(map[string]string{"v6": "extra"}),
+	)
+	if err == nil {
+		t.Fatal("Expected an error for a mapping of an unknown column")
+	}
+}
+
+func TestWithExtraColumns(t *testing.T) {
+	tableName := "casbin_test_extra_columns"
+	pool := setupTestPool(t, tableName)
+
+	// An existing table with the default six value columns gets the extra ones
+	if _, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName)); err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithExtraColumns(2),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	m, err := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act, dept, region, level, tier, env
+
+[policy_definition]
+p = sub, obj, act, dept, region, level, tier, env
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act && r.env == p.env
+`)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("Failed to create enforcer: %v", err)
+	}
+
+	rules := [][]string{
+		{"alice", "data1", "read", "eng", "eu", "3", "gold", "prod"},
+		{"bob", "data2", "write", "ops", "us", "1", "silver", "staging"},
+	}
+	if _, err := e.AddPolicies(rules); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if ok, _ := e.Enforce("alice", "data1", "read", "", "", "", "", "prod"); !ok {
+		t.Error("Expected the eighth value to round-trip")
+	}
+
+	if _, err := e.RemoveFilteredPolicy(7, "staging"); err != nil {
+		t.Fatalf("Failed to remove policy: %v", err)
+	}
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	policies, _ := e.GetPolicy()
+	if len(policies) != 1 || strings.Join(policies[0], ",") != strings.Join(rules[0], ",") {
+		t.Errorf("Expected only %v, got %v", rules[0], policies)
+	}
+}
@@ -0,0 +1,33 @@
+package pgxadapter
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// txKey is the context key of a transaction set with WithTx
+type txKey struct{}
+
+// WithTx returns a copy of ctx carrying tx. Adapter calls made with the
+// returned context run their statements on tx instead of the adapter's
+// connection or pool, so they commit or roll back with the caller's
+// transaction. Operations that need a transaction of their own use a
+// savepoint in tx.
+func WithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction set on ctx with WithTx, if any
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok && tx != nil
+}
+
+// dbFrom returns the transaction carried by ctx, or the adapter's database
+func (a *PgxAdapter) dbFrom(ctx context.Context) DB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return a.db
+}
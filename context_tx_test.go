@@ -0,0 +1,84 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v3/model"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithTx(t *testing.T) {
+	tableName := "casbin_test_context_tx"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	count := func(ctx context.Context) int {
+		t.Helper()
+		m, _ := model.NewModelFromString(TestModelText)
+		if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+			t.Fatalf("Failed to load policy: %v", err)
+		}
+		policies, _ := m.GetPolicy("p", "p")
+		return len(policies)
+	}
+
+	tests := []struct {
+		name      string
+		commit    bool
+		wantRules int
+	}{
+		{name: "commit", commit: true, wantRules: 1},
+		{name: "rollback", commit: false, wantRules: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := pool.Exec(ctx, "DELETE FROM "+tableName); err != nil {
+				t.Fatalf("Failed to clear table: %v", err)
+			}
+
+			tx, err := pool.Begin(ctx)
+			if err != nil {
+				t.Fatalf("Failed to begin transaction: %v", err)
+			}
+			defer tx.Rollback(ctx)
+
+			if got, ok := pgxadapter.TxFromContext(ctx); ok || got != nil {
+				t.Error("TxFromContext() expected no transaction on a plain context")
+			}
+			txCtx := pgxadapter.WithTx(ctx, tx)
+			if got, ok := pgxadapter.TxFromContext(txCtx); !ok || got != tx {
+				t.Error("TxFromContext() expected the transaction set with WithTx")
+			}
+
+			if err := adapter.AddPolicyCtx(txCtx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+				t.Fatalf("Failed to add policy: %v", err)
+			}
+			if got := count(txCtx); got != 1 {
+				t.Errorf("Expected 1 rule inside the transaction, got %d", got)
+			}
+			if got := count(ctx); got != 0 {
+				t.Errorf("Expected 0 rules outside the transaction, got %d", got)
+			}
+
+			if tt.commit {
+				if err := tx.Commit(ctx); err != nil {
+					t.Fatalf("Failed to commit: %v", err)
+				}
+			} else if err := tx.Rollback(ctx); err != nil {
+				t.Fatalf("Failed to roll back: %v", err)
+			}
+
+			if got := count(ctx); got != tt.wantRules {
+				t.Errorf("Expected %d rules after the transaction, got %d", tt.wantRules, got)
+			}
+		})
+	}
+}
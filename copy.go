@@ -0,0 +1,85 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// defaultCopyThreshold is the batch size from which AddPolicies copies the rules
+const defaultCopyThreshold = 1000
+
+// WithCopyThreshold sets the number of rules from which AddPolicies sends them
+// with the COPY protocol instead of a multi-row INSERT, which is much faster
+// for large batches and has no bind parameter limit. The rules are copied into
+// a temporary table and inserted from there, so duplicates are handled like
+// with INSERT. Defaults to 1000; zero or less always uses INSERT.
+func WithCopyThreshold(rules int) Option {
+	return func(a *PgxAdapter) {
+		a.copyThreshold = rules
+	}
+}
+
+// copyPolicies adds rules like addPolicies, sending them with COPY through a staging table
+func (a *PgxAdapter) copyPolicies(ctx context.Context, ptype string, rules [][]string) error {
+	tx, err := a.beginWrite(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ptypes := make([]string, len(rules))
+	for i := range ptypes {
+		ptypes[i] = ptype
+	}
+	if err := a.stageRules(ctx, tx, addStagingTable, ptypes, rules); err != nil {
+		return err
+	}
+
+	columns := strings.Join(a.insertColumns(), ", ")
+	insertSQL := "INSERT INTO " + a.quotedTableName() + " (" + columns + ") SELECT " + columns +
+		" FROM " + addStagingTable
+
+	var inserted int64
+	if a.idempotentWrites {
+		// Only the rules actually inserted are audited
+		inserted, err = a.execAudited(ctx, tx, AuditOpAdd, insertSQL+" "+a.onConflictDoNothing())
+	} else {
+		inserted, err = a.copyInsert(ctx, tx, ptype, rules, insertSQL)
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return fmt.Errorf("one or more policies already exist")
+		}
+		return fmt.Errorf("failed to add policies: %w", mapWriteError(err))
+	}
+	if err := a.dropStaging(ctx, tx, addStagingTable); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	a.maybeAnalyze(ctx, inserted)
+
+	return nil
+}
+
+// copyInsert runs insertSQL and audits every rule, as all of them must have been inserted
+func (a *PgxAdapter) copyInsert(ctx context.Context, db DB, ptype string, rules [][]string, insertSQL string) (int64, error) {
+	result, err := db.Exec(ctx, insertSQL)
+	if err != nil {
+		return 0, err
+	}
+
+	changes := make([]auditChange, len(rules))
+	for i, rule := range rules {
+		changes[i] = auditChange{ptype: ptype, newRule: rule}
+	}
+	if err := a.writeAudit(ctx, db, AuditOpAdd, changes); err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,90 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithCopyThreshold(t *testing.T) {
+	rules := [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data3", "read"}}
+
+	tests := []struct {
+		name      string
+		opts      []pgxadapter.Option
+		duplicate bool
+	}{
+		{name: "insert below threshold", opts: []pgxadapter.Option{pgxadapter.WithCopyThreshold(10)}, duplicate: true},
+		{name: "copy at threshold", opts: []pgxadapter.Option{pgxadapter.WithCopyThreshold(3)}, duplicate: true},
+		{name: "copy skipping duplicates", opts: []pgxadapter.Option{pgxadapter.WithCopyThreshold(1), pgxadapter.WithIdempotentWrites()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tableName := "casbin_test_copy_threshold"
+			pool := setupTestPool(t, tableName)
+			ctx := context.Background()
+
+			opts := append([]pgxadapter.Option{pgxadapter.WithTableName(tableName)}, tt.opts...)
+			adapter, err := pgxadapter.NewAdapterWithPool(pool, opts...)
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			if err := adapter.AddPoliciesCtx(ctx, "p", "p", rules); err != nil {
+				t.Fatalf("Failed to add policies: %v", err)
+			}
+
+			got, err := adapter.GetRawPolicies(ctx)
+			if err != nil {
+				t.Fatalf("Failed to get policies: %v", err)
+			}
+			want := make([][]string, len(rules))
+			for i, rule := range rules {
+				want[i] = append([]string{"p"}, rule...)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %v in order, got %v", want, got)
+			}
+
+			err = adapter.AddPoliciesCtx(ctx, "p", "p", rules)
+			if tt.duplicate && err == nil {
+				t.Error("Expected an error adding existing rules")
+			}
+			if !tt.duplicate && err != nil {
+				t.Errorf("Expected existing rules to be skipped, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAddPoliciesCopyLargeBatch(t *testing.T) {
+	tableName := "casbin_test_copy_large"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	// More bind parameters than a single INSERT could take
+	rules := make([][]string, 20000)
+	for i := range rules {
+		rules[i] = []string{fmt.Sprintf("user%d", i), "data", "read"}
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", rules); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+
+	var count int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+tableName).Scan(&count); err != nil {
+		t.Fatalf("Failed to count rules: %v", err)
+	}
+	if count != len(rules) {
+		t.Errorf("Expected %d rules, got %d", len(rules), count)
+	}
+}
@@ -0,0 +1,68 @@
+package pgxadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// maintenanceDatabase is the database connected to for creating the adapter's
+// own database when the connection string names no other one
+const maintenanceDatabase = "postgres"
+
+// applyDatabase points config at the database set with WithDatabaseName or,
+// when neither the option nor config names one, at the default database. It
+// returns the function creating that database if it does not exist, which
+// does nothing when the database of config is used as is.
+func (a *PgxAdapter) applyDatabase(config *pgx.ConnConfig) func(ctx context.Context) error {
+	name := a.database
+	if name == "" {
+		if config.Database != "" {
+			return func(context.Context) error { return nil }
+		}
+		name = defaultDatabase
+	}
+
+	admin := config.Copy()
+	if admin.Database == "" || admin.Database == name {
+		admin.Database = maintenanceDatabase
+	}
+	config.Database = name
+
+	return func(ctx context.Context) error {
+		return createDatabase(ctx, admin, name)
+	}
+}
+
+// createDatabase connects with config and creates the named database if it does not exist
+func createDatabase(ctx context.Context, config *pgx.ConnConfig, name string) error {
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to connect to create database: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	var exists bool
+	if err := conn.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", name,
+	).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check database: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	// CREATE DATABASE cannot run in a transaction block, so it is sent on its own
+	if _, err := conn.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{name}.Sanitize()); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "42P04" {
+			// Created concurrently by another instance
+			return nil
+		}
+		return fmt.Errorf("failed to create database: %w", err)
+	}
+	return nil
+}
@@ -1,15 +1,17 @@
 package pgxadapter
 
-var (
-	insertColumns = []string{"ptype", "v0", "v1", "v2", "v3", "v4", "v5"}
-	selectColumns = []string{"ptype", "v0", "v1", "v2", "v3", "v4", "v5"}
-
-	colParams = map[int]string{
-		0: "v0",
-		1: "v1",
-		2: "v2",
-		3: "v3",
-		4: "v4",
-		5: "v5",
-	}
-)
+import "strconv"
+
+// eftColumn is the name of the dedicated effect column enabled by WithEftColumn
+const eftColumn = "eft"
+
+// defaultValueColumns is the number of value columns (v0..v5) without WithExtraColumns
+const defaultValueColumns = 6
+
+// ruleColumns are the rule columns of a table without extra columns
+var ruleColumns = []string{"ptype", "v0", "v1", "v2", "v3", "v4", "v5"}
+
+// valueColumn returns the name of the value column at index i
+func valueColumn(i int) string {
+	return "v" + strconv.Itoa(i)
+}
@@ -0,0 +1,78 @@
+package pgxadapter
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// WithCreateTableDDL replaces the statement creating the policy table with a
+// text/template, so the table can get its own storage parameters, tablespace
+// or extra constraints. The template is executed with two fields, both safe
+// to embed as they are: .Table is the quoted, schema-qualified table name and
+// .Columns the comma separated definitions of every column the adapter needs,
+// including the check constraints of WithCheckConstraint. For example:
+//
+//	CREATE TABLE IF NOT EXISTS {{.Table}} ({{.Columns}}) WITH (fillfactor = 70) TABLESPACE fast
+//
+// The statement should not fail if the table exists. WithFillFactor has no
+// effect with a custom statement; the unique index and other indexes are
+// still created by the adapter.
+func WithCreateTableDDL(sqlTemplate string) Option {
+	return func(a *PgxAdapter) {
+		a.createTableTemplate = sqlTemplate
+	}
+}
+
+// WithUnloggedTable creates the policy table as UNLOGGED, which skips the
+// write-ahead log and makes bulk loads faster, for CI and other throwaway
+// environments. Unlogged tables are emptied after a crash and are not
+// replicated. Only affects newly created tables; with WithPartitionByPtype
+// the partitions are unlogged.
+func WithUnloggedTable() Option {
+	return func(a *PgxAdapter) {
+		a.unlogged = true
+	}
+}
+
+// unloggedDDL returns the UNLOGGED keyword of a created table, if enabled
+func (a *PgxAdapter) unloggedDDL() string {
+	if !a.unlogged {
+		return ""
+	}
+	return "UNLOGGED "
+}
+
+// createTableData is the data the WithCreateTableDDL template is executed with
+type createTableData struct {
+	Table   string
+	Columns string
+}
+
+// createTableDDL returns the statement creating the policy table
+func (a *PgxAdapter) createTableDDL() (string, error) {
+	columns := `
+		` + a.idColumnDDL() + `,
+		` + a.column("ptype") + ` VARCHAR(100) NOT NULL` + a.valueColumnsDDL() + a.eftColumnDDL() + a.metadataColumnDDL() + a.tenantColumnDDL() + a.timestampsDDL() + a.ruleHashDDL() + a.checkConstraintsDDL() + a.partitionKeyDDL() + `
+	`
+
+	if a.createTableTemplate == "" {
+		// Use pgx identifier quoting for secure table name handling
+		// Partitioned tables hold no data of their own and cannot be unlogged
+		unlogged := a.unloggedDDL()
+		if a.partitionByPtype {
+			unlogged = ""
+		}
+		return `CREATE ` + unlogged + `TABLE IF NOT EXISTS ` + a.quotedTableName() + ` (` + columns + `)` + a.partitionDDL(), nil
+	}
+
+	tmpl, err := template.New("create_table").Parse(a.createTableTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid create table template: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, createTableData{Table: a.quotedTableName(), Columns: columns}); err != nil {
+		return "", fmt.Errorf("invalid create table template: %w", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,115 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithCreateTableDDL(t *testing.T) {
+	tableName := "casbin_test_create_ddl"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		ddl     string
+		wantErr bool
+	}{
+		{name: "unknown_field", ddl: "CREATE TABLE {{.Name}} ({{.Columns}})", wantErr: true},
+		{name: "malformed", ddl: "CREATE TABLE {{.Table ({{.Columns}})", wantErr: true},
+		{
+			name: "storage_parameters",
+			ddl:  "CREATE TABLE IF NOT EXISTS {{.Table}} ({{.Columns}}, CONSTRAINT chk_ptype CHECK (ptype <> '')) WITH (fillfactor = 70) TABLESPACE pg_default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, err := pgxadapter.NewAdapterWithPool(pool,
+				pgxadapter.WithTableName(tableName),
+				pgxadapter.WithCreateTableDDL(tt.ddl),
+			)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error for an invalid template")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			var options []string
+			if err := pool.QueryRow(ctx,
+				"SELECT coalesce(reloptions, '{}') FROM pg_class WHERE oid = $1::regclass", tableName,
+			).Scan(&options); err != nil {
+				t.Fatalf("Failed to query table options: %v", err)
+			}
+			if strings.Join(options, ",") != "fillfactor=70" {
+				t.Errorf("Expected fillfactor=70, got %v", options)
+			}
+
+			if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+				t.Fatalf("Failed to add policy: %v", err)
+			}
+			if err := adapter.AddPolicyCtx(ctx, "p", "", []string{"alice", "data1", "read"}); err == nil {
+				t.Error("Expected the custom constraint to reject an empty ptype")
+			}
+		})
+	}
+}
+
+func TestWithUnloggedTable(t *testing.T) {
+	tableName := "casbin_test_unlogged"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		opts       []pgxadapter.Option
+		relation   string
+		wantLogged string
+	}{
+		{
+			name:       "table",
+			opts:       []pgxadapter.Option{pgxadapter.WithUnloggedTable()},
+			relation:   tableName,
+			wantLogged: "u",
+		},
+		{
+			name:       "partitions",
+			opts:       []pgxadapter.Option{pgxadapter.WithUnloggedTable(), pgxadapter.WithPartitionByPtype()},
+			relation:   tableName + "_p",
+			wantLogged: "u",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _ = pool.Exec(ctx, "DROP TABLE IF EXISTS "+tableName+" CASCADE")
+
+			opts := append([]pgxadapter.Option{pgxadapter.WithTableName(tableName)}, tt.opts...)
+			adapter, err := pgxadapter.NewAdapterWithPool(pool, opts...)
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			var persistence string
+			if err := pool.QueryRow(ctx,
+				"SELECT relpersistence::text FROM pg_class WHERE oid = $1::regclass", tt.relation,
+			).Scan(&persistence); err != nil {
+				t.Fatalf("Failed to query table: %v", err)
+			}
+			if persistence != tt.wantLogged {
+				t.Errorf("Expected relpersistence %q, got %q", tt.wantLogged, persistence)
+			}
+
+			if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+				t.Fatalf("Failed to add policy: %v", err)
+			}
+		})
+	}
+}
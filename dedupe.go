@@ -0,0 +1,119 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// WithRuleDeduplication normalizes the rules passed to AddPolicies and
+// SavePolicy before they are written, trimming surrounding whitespace from
+// every value and dropping trailing empty values, and drops rules repeating
+// an earlier one, so noisy sync jobs do not run into the unique index.
+// DeduplicatedRules counts the rules dropped.
+func WithRuleDeduplication() Option {
+	return func(a *PgxAdapter) {
+		a.dedupeRules = true
+	}
+}
+
+// DeduplicatedRules returns the number of rules dropped by WithRuleDeduplication
+// since the adapter was created
+func (a *PgxAdapter) DeduplicatedRules() int64 {
+	return a.dedupedRules.Load()
+}
+
+// dedupe normalizes rules and drops those repeating an earlier rule of the
+// same ptype, see WithRuleDeduplication. It returns the ptypes and rules kept.
+func dedupe(ptypes []string, rules [][]string) ([]string, [][]string) {
+	seen := make(map[string]struct{}, len(rules))
+	keptPtypes := make([]string, 0, len(rules))
+	kept := make([][]string, 0, len(rules))
+	for i, rule := range rules {
+		rule = normalizeRule(rule)
+		key := ptypes[i] + "\x00" + strings.Join(rule, "\x00")
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keptPtypes = append(keptPtypes, ptypes[i])
+		kept = append(kept, rule)
+	}
+	return keptPtypes, kept
+}
+
+// normalizeRule returns rule with its values trimmed and trailing empty values dropped
+func normalizeRule(rule []string) []string {
+	normalized := make([]string, len(rule))
+	for i, value := range rule {
+		normalized[i] = strings.TrimSpace(value)
+	}
+	for len(normalized) > 0 && normalized[len(normalized)-1] == "" {
+		normalized = normalized[:len(normalized)-1]
+	}
+	return normalized
+}
+
+// duplicateRulesError describes why the unique index could not be created,
+// including how many groups of duplicate rules the table holds
+func (a *PgxAdapter) duplicateRulesError(ctx context.Context, cause error) error {
+	countSQL := `SELECT COUNT(*) FROM (SELECT 1 FROM ` + a.quotedTableName() +
+		` GROUP BY ` + strings.Join(a.uniqueIndexExprs(), ", ") + ` HAVING COUNT(*) > 1) AS duplicates`
+
+	var groups int64
+	if err := a.dbFrom(ctx).QueryRow(ctx, countSQL).Scan(&groups); err != nil {
+		return fmt.Errorf("failed to create index: %w", cause)
+	}
+
+	return fmt.Errorf("%w: table %s has %d groups of duplicate rules, deduplicate them (see DeduplicateTable) before creating the unique index: %w",
+		ErrDuplicateRules, a.tableName, groups, cause)
+}
+
+// DeduplicatePolicies removes duplicate rules, keeping the row with the lowest id
+// of each group, and returns the number of rows deleted. An adapter cannot be
+// created on a table that already holds duplicates, see DeduplicateTable.
+func (a *PgxAdapter) DeduplicatePolicies(ctx context.Context) (int64, error) {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return 0, err
+	}
+
+	return a.deduplicate(ctx)
+}
+
+// DeduplicateTable removes duplicate rules from the table an adapter created
+// with opts would use, like DeduplicatePolicies, and returns the number of
+// rows deleted. The table is neither created nor checked, so it cleans up a
+// table created outside the adapter, on which creating an adapter fails with
+// ErrDuplicateRules because its unique index cannot be built.
+func DeduplicateTable(ctx context.Context, db DB, opts ...Option) (int64, error) {
+	a := newAdapter(opts...)
+	a.db = db
+
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	return a.deduplicate(ctx)
+}
+
+// deduplicate deletes every row but the one with the lowest id of each group of duplicate rules
+func (a *PgxAdapter) deduplicate(ctx context.Context) (int64, error) {
+	// UUIDs have no MIN aggregate
+	keep := "MIN(id)"
+	if a.uuidPrimaryKey {
+		keep = "(array_agg(id ORDER BY id))[1]"
+	}
+
+	quotedTableName := a.quotedTableName()
+	deleteSQL := `DELETE FROM ` + quotedTableName + ` WHERE id NOT IN (SELECT ` + keep + ` FROM ` +
+		quotedTableName + ` GROUP BY ` + strings.Join(a.uniqueIndexExprs(), ", ") + `)`
+
+	result, err := a.dbFrom(ctx).Exec(ctx, deleteSQL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove duplicate policies: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
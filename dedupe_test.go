@@ -0,0 +1,179 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/jackc/pgx/v5"
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestDuplicateRules(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tableName := "casbin_test_duplicate_rules"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	// Simulate a table that was filled before the unique index existed
+	quotedTableName := pgx.Identifier{tableName}.Sanitize()
+	if _, err := conn.Exec(ctx, "DROP INDEX "+pgx.Identifier{"idx_" + tableName}.Sanitize()); err != nil {
+		t.Fatalf("Failed to drop unique index: %v", err)
+	}
+
+	rows := [][]any{
+		{"p", "alice", "data1", "read"},
+		{"p", "alice", "data1", "read"},
+		{"p", "alice", "data1", "read"},
+		{"g", "bob", "admin", nil},
+		{"g", "bob", "admin", nil},
+		{"p", "carol", "data2", "write"},
+	}
+	for _, row := range rows {
+		_, err := conn.Exec(ctx, "INSERT INTO "+quotedTableName+" (ptype, v0, v1, v2) VALUES ($1, $2, $3, $4)", row...)
+		if err != nil {
+			t.Fatalf("Failed to insert duplicate row: %v", err)
+		}
+	}
+
+	_, err = pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+	if !errors.Is(err, pgxadapter.ErrDuplicateRules) {
+		t.Fatalf("NewAdapterWithConn() error = %v, want ErrDuplicateRules", err)
+	}
+	if !strings.Contains(err.Error(), "2 groups") {
+		t.Errorf("NewAdapterWithConn() error = %q, want it to report 2 duplicate groups", err.Error())
+	}
+
+	deleted, err := adapter.DeduplicatePolicies(ctx)
+	if err != nil {
+		t.Fatalf("DeduplicatePolicies() unexpected error: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("DeduplicatePolicies() deleted %d rows, want 3", deleted)
+	}
+
+	var minID int
+	err = conn.QueryRow(ctx, "SELECT MIN(id) FROM "+quotedTableName+" WHERE v0 = 'alice'").Scan(&minID)
+	if err != nil {
+		t.Fatalf("Failed to query remaining row: %v", err)
+	}
+	if minID != 1 {
+		t.Errorf("DeduplicatePolicies() kept id %d, want the lowest id 1", minID)
+	}
+
+	// The unique index can now be created
+	if _, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName)); err != nil {
+		t.Errorf("NewAdapterWithConn() after deduplication unexpected error: %v", err)
+	}
+}
+
+func TestDeduplicateTable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tableName := "casbin_test_deduplicate_table"
+	conn := setupTestDB(t, tableName)
+
+	// A table created and filled outside the adapter, without its unique index
+	quotedTableName := pgx.Identifier{tableName}.Sanitize()
+	_, err := conn.Exec(ctx, `CREATE TABLE `+quotedTableName+` (
+		id SERIAL PRIMARY KEY,
+		ptype VARCHAR(100) NOT NULL,
+		v0 VARCHAR(100), v1 VARCHAR(100), v2 VARCHAR(100),
+		v3 VARCHAR(100), v4 VARCHAR(100), v5 VARCHAR(100))`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	_, err = conn.Exec(ctx, `INSERT INTO `+quotedTableName+` (ptype, v0, v1, v2) VALUES
+		('p', 'alice', 'data1', 'read'), ('p', 'alice', 'data1', 'read'),
+		('g', 'bob', 'admin', NULL), ('g', 'bob', 'admin', NULL),
+		('p', 'carol', 'data2', 'write')`)
+	if err != nil {
+		t.Fatalf("Failed to insert duplicate rows: %v", err)
+	}
+
+	_, err = pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+	if !errors.Is(err, pgxadapter.ErrDuplicateRules) {
+		t.Fatalf("NewAdapterWithConn() error = %v, want ErrDuplicateRules", err)
+	}
+	if !strings.Contains(err.Error(), "2 groups") {
+		t.Errorf("NewAdapterWithConn() error = %q, want it to report 2 duplicate groups", err.Error())
+	}
+
+	deleted, err := pgxadapter.DeduplicateTable(ctx, conn, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("DeduplicateTable() unexpected error: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("DeduplicateTable() deleted %d rows, want 2", deleted)
+	}
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("NewAdapterWithConn() after deduplication unexpected error: %v", err)
+	}
+	policies, err := adapter.GetRawPolicies(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read policies: %v", err)
+	}
+	if len(policies) != 3 {
+		t.Errorf("Table has %d policies after deduplication, want 3", len(policies))
+	}
+}
+
+func TestWithRuleDeduplication(t *testing.T) {
+	ctx := context.Background()
+	tableName := "casbin_test_rule_deduplication"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithRuleDeduplication(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	rules := [][]string{
+		{"alice", "data1", "read"},
+		{" alice", "data1 ", "read"},
+		{"alice", "data1", "read", ""},
+		{"bob", "data2", "write"},
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", rules); err != nil {
+		t.Fatalf("AddPoliciesCtx() unexpected error: %v", err)
+	}
+	if got := adapter.DeduplicatedRules(); got != 2 {
+		t.Errorf("DeduplicatedRules() after AddPoliciesCtx = %d, want 2", got)
+	}
+
+	m, err := model.NewModelFromString(TestModelText)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	for _, rule := range rules {
+		m.AddPolicy("p", "p", rule)
+	}
+	if err := adapter.SavePolicyCtx(ctx, m); err != nil {
+		t.Fatalf("SavePolicyCtx() unexpected error: %v", err)
+	}
+	if got := adapter.DeduplicatedRules(); got != 4 {
+		t.Errorf("DeduplicatedRules() after SavePolicyCtx = %d, want 4", got)
+	}
+
+	var count int
+	if err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM "+pgx.Identifier{tableName}.Sanitize()).Scan(&count); err != nil {
+		t.Fatalf("Failed to count policies: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Table has %d policies, want 2", count)
+	}
+}
@@ -0,0 +1,121 @@
+package pgxadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Diagnostics describes the adapter's view of its table, for attaching to bug reports
+type Diagnostics struct {
+	ServerVersion     string   `json:"server_version"`
+	Database          string   `json:"database"`
+	Schema            string   `json:"schema"`
+	TableName         string   `json:"table_name"`
+	TableExists       bool     `json:"table_exists"`
+	RowCount          int64    `json:"row_count"`
+	MissingColumns    []string `json:"missing_columns"`
+	UniqueIndexName   string   `json:"unique_index_name"`
+	UniqueIndexExists bool     `json:"unique_index_exists"`
+	IsFiltered        bool     `json:"is_filtered"`
+}
+
+// Diagnostics gathers the server version, the state of the adapter's table and
+// its unique index, and the adapter settings that decide which table is used.
+// It only reads the catalogs and counts the table's rows.
+func (a *PgxAdapter) Diagnostics(ctx context.Context) (Diagnostics, error) {
+	ctx, cancel := a.readContext(ctx)
+	defer cancel()
+
+	d := Diagnostics{
+		Database:        a.database,
+		TableName:       a.tableName,
+		UniqueIndexName: a.uniqueIndexName(),
+		IsFiltered:      a.IsFiltered(),
+	}
+
+	if err := a.ensureInit(ctx); err != nil {
+		return d, err
+	}
+
+	if err := a.dbFrom(ctx).QueryRow(ctx, "SHOW server_version").Scan(&d.ServerVersion); err != nil {
+		return d, fmt.Errorf("failed to query server version: %w", err)
+	}
+
+	if err := a.inspectTable(ctx, &d); err != nil || !d.TableExists {
+		return d, err
+	}
+
+	if err := a.dbFrom(ctx).QueryRow(ctx, "SELECT COUNT(*) FROM "+a.quotedTableName()).Scan(&d.RowCount); err != nil {
+		return d, fmt.Errorf("failed to count policies: %w", err)
+	}
+
+	return d, nil
+}
+
+// expectedColumns returns the columns the adapter reads or writes
+func (a *PgxAdapter) expectedColumns() []string {
+	columns := []string{"id"}
+	for _, col := range a.storedColumns() {
+		columns = append(columns, a.columnName(col))
+	}
+	if a.useMetadataColumn {
+		columns = append(columns, metadataColumn)
+	}
+	if a.timestamps {
+		columns = append(columns, createdAtColumn, updatedAtColumn)
+	}
+	if a.hashUniqueIndex {
+		columns = append(columns, ruleHashColumn)
+	}
+	return columns
+}
+
+// inspectTable fills in the schema of the adapter's table and, if it exists,
+// its missing columns and whether its unique index exists
+func (a *PgxAdapter) inspectTable(ctx context.Context, d *Diagnostics) error {
+	table := a.quotedTableName()
+	err := a.dbFrom(ctx).QueryRow(ctx,
+		`SELECT n.nspname FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.oid = to_regclass($1)`, table).Scan(&d.Schema)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if err := a.dbFrom(ctx).QueryRow(ctx, "SELECT current_schema()").Scan(&d.Schema); err != nil {
+			return fmt.Errorf("failed to query current schema: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query table: %w", err)
+	}
+	d.TableExists = true
+
+	rows, err := a.dbFrom(ctx).Query(ctx,
+		`SELECT attname FROM pg_attribute
+		WHERE attrelid = to_regclass($1) AND attnum > 0 AND NOT attisdropped`, table)
+	if err != nil {
+		return fmt.Errorf("failed to query columns: %w", err)
+	}
+	columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to query columns: %w", err)
+	}
+	for _, col := range a.expectedColumns() {
+		if !slices.Contains(columns, col) {
+			d.MissingColumns = append(d.MissingColumns, col)
+		}
+	}
+
+	if err := a.dbFrom(ctx).QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM pg_index i
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		WHERE i.indrelid = to_regclass($1) AND ic.relname = $2 AND i.indisunique)`,
+		table, d.UniqueIndexName).Scan(&d.UniqueIndexExists); err != nil {
+		return fmt.Errorf("failed to query unique index: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"testing"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestDiagnostics(t *testing.T) {
+	tableName := "casbin_test_diagnostics"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	policies := [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"carol", "data3", "read"},
+	}
+	if err := adapter.AddPolicies("p", "p", policies); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+
+	d, err := adapter.Diagnostics(context.Background())
+	if err != nil {
+		t.Fatalf("Diagnostics() unexpected error: %v", err)
+	}
+
+	if d.ServerVersion == "" {
+		t.Error("Expected server version to be set")
+	}
+	if !d.TableExists {
+		t.Error("Expected table to exist")
+	}
+	if d.TableName != tableName {
+		t.Errorf("TableName = %v, want %v", d.TableName, tableName)
+	}
+	if d.Schema != "public" {
+		t.Errorf("Schema = %v, want public", d.Schema)
+	}
+	if d.RowCount != int64(len(policies)) {
+		t.Errorf("RowCount = %d, want %d", d.RowCount, len(policies))
+	}
+	if len(d.MissingColumns) != 0 {
+		t.Errorf("MissingColumns = %v, want none", d.MissingColumns)
+	}
+	if !d.UniqueIndexExists {
+		t.Errorf("Expected unique index %s to exist", d.UniqueIndexName)
+	}
+	if d.IsFiltered {
+		t.Error("Expected IsFiltered to be false")
+	}
+}
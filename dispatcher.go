@@ -0,0 +1,484 @@
+package pgxadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/persist"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ persist.Dispatcher = (*Dispatcher)(nil)
+
+// Dispatcher operations recorded in the queue table
+const (
+	dispatchAddPolicies            = "add_policies"
+	dispatchRemovePolicies         = "remove_policies"
+	dispatchRemoveFilteredPolicy   = "remove_filtered_policy"
+	dispatchClearPolicy            = "clear_policy"
+	dispatchUpdatePolicy           = "update_policy"
+	dispatchUpdatePolicies         = "update_policies"
+	dispatchUpdateFilteredPolicies = "update_filtered_policies"
+)
+
+// dispatchEntry is the payload of a queue row
+type dispatchEntry struct {
+	Rules       [][]string `json:"rules,omitempty"`
+	NewRules    [][]string `json:"new_rules,omitempty"`
+	FieldIndex  int        `json:"field_index,omitempty"`
+	FieldValues []string   `json:"field_values,omitempty"`
+}
+
+// Dispatcher is a persist.Dispatcher that routes policy changes of
+// distributed enforcers through a queue table named after the policy table
+// with a "_dispatch" suffix. Each change is written to the policy table and
+// appended to the queue under the adapter's advisory lock, so the queue order
+// is the order in which changes were persisted. Every instance, including the
+// one making the change, applies queued changes to its enforcer in that order
+// without persisting them again.
+//
+// Changes are applied asynchronously: a call returns once the change is
+// persisted and queued, and the local enforcer sees it shortly after. Queue
+// rows older than the retention are pruned as new changes are queued.
+type Dispatcher struct {
+	adapter  *PgxAdapter
+	enforcer casbin.IDistributedEnforcer
+
+	// connMu guards conn, which holds the advisory lock while enqueueing
+	connMu sync.Mutex
+	conn   *pgx.Conn
+
+	// applyMu serializes applying queued entries
+	applyMu sync.Mutex
+	lastID  int64
+
+	retention time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// defaultQueueRetention is how long queued changes are kept by default
+const defaultQueueRetention = 24 * time.Hour
+
+// DispatcherOption configures a Dispatcher
+type DispatcherOption func(*Dispatcher)
+
+// WithQueueRetention sets how long queued changes are kept before being
+// pruned. An instance disconnected for longer than that reloads the whole
+// policy when it reconnects, as the changes it missed may be gone. Zero or
+// less keeps every change. Defaults to 24h.
+func WithQueueRetention(retention time.Duration) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.retention = retention
+	}
+}
+
+// NewDispatcher creates the queue table if needed, sets the dispatcher on e
+// and starts applying changes queued from now on. e must already hold the
+// current policy, typically loaded from adapter.
+func NewDispatcher(ctx context.Context, adapter *PgxAdapter, e casbin.IDistributedEnforcer, opts ...DispatcherOption) (*Dispatcher, error) {
+	if err := adapter.ensureInit(ctx); err != nil {
+		return nil, err
+	}
+
+	d := &Dispatcher{
+		adapter:   adapter,
+		enforcer:  e,
+		retention: defaultQueueRetention,
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	if err := d.createQueue(ctx); err != nil {
+		return nil, err
+	}
+	if err := d.readLastID(ctx); err != nil {
+		return nil, err
+	}
+
+	conn, err := adapter.listenConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.conn = conn
+
+	listenCtx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	ready := make(chan struct{})
+	errc := make(chan error, 1)
+	go d.run(listenCtx, ready, errc)
+
+	select {
+	case <-ready:
+	case err := <-errc:
+		cancel()
+		conn.Close(context.Background())
+		return nil, err
+	}
+
+	e.SetDispatcher(d)
+	return d, nil
+}
+
+// run listens until ctx is done, reconnecting with backoff when the
+// connection drops. Entries queued meanwhile stay in the queue and are applied
+// once listening again, unless the connection was down for longer than the
+// retention, in which case the whole policy is reloaded. The outcome of the
+// first attempt is reported through ready or errc.
+func (d *Dispatcher) run(ctx context.Context, ready chan<- struct{}, errc chan<- error) {
+	defer close(d.done)
+
+	connected := false
+	attempt := 0
+	var lostAt time.Time
+	for {
+		err := d.adapter.listen(ctx, d.channel(), func() {
+			attempt = 0
+			if !connected {
+				connected = true
+				close(ready)
+				return
+			}
+			if d.retention > 0 && time.Since(lostAt) >= d.retention {
+				d.resync(ctx)
+				return
+			}
+			d.applyPending(ctx)
+		}, func(string) { d.applyPending(ctx) })
+		if ctx.Err() != nil {
+			return
+		}
+		if !connected {
+			errc <- err
+			return
+		}
+
+		if attempt == 0 {
+			lostAt = time.Now()
+		}
+		slog.Warn("casbin pgx adapter: dispatcher lost its connection, reconnecting",
+			"table", d.adapter.tableName, "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoffDelay(attempt, defaultMinBackoff, defaultMaxBackoff)):
+		}
+		attempt++
+	}
+}
+
+// Close stops applying queued changes and releases the dispatcher's connections
+func (d *Dispatcher) Close() {
+	d.cancel()
+	<-d.done
+
+	d.connMu.Lock()
+	defer d.connMu.Unlock()
+	d.conn.Close(context.Background())
+}
+
+// AddPolicies adds rules on all instances
+func (d *Dispatcher) AddPolicies(sec string, ptype string, rules [][]string) error {
+	return d.dispatch(dispatchAddPolicies, sec, ptype, dispatchEntry{Rules: rules}, func(ctx context.Context) error {
+		return d.adapter.AddPoliciesCtx(ctx, sec, ptype, rules)
+	})
+}
+
+// RemovePolicies removes rules on all instances
+func (d *Dispatcher) RemovePolicies(sec string, ptype string, rules [][]string) error {
+	return d.dispatch(dispatchRemovePolicies, sec, ptype, dispatchEntry{Rules: rules}, func(ctx context.Context) error {
+		return d.adapter.RemovePoliciesCtx(ctx, sec, ptype, rules)
+	})
+}
+
+// RemoveFilteredPolicy removes the rules matching the filter on all instances
+func (d *Dispatcher) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	entry := dispatchEntry{FieldIndex: fieldIndex, FieldValues: fieldValues}
+	return d.dispatch(dispatchRemoveFilteredPolicy, sec, ptype, entry, func(ctx context.Context) error {
+		return d.adapter.RemoveFilteredPolicyCtx(ctx, sec, ptype, fieldIndex, fieldValues...)
+	})
+}
+
+// ClearPolicy removes every rule on all instances
+func (d *Dispatcher) ClearPolicy() error {
+	return d.dispatch(dispatchClearPolicy, "", "", dispatchEntry{}, func(ctx context.Context) error {
+		sql, args, err := d.adapter.deleteRules(ctx).ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build delete query: %w", err)
+		}
+		if _, err := d.adapter.dbFrom(ctx).Exec(ctx, sql, args...); err != nil {
+			return fmt.Errorf("failed to clear policies: %w", err)
+		}
+		return nil
+	})
+}
+
+// UpdatePolicy replaces oldRule with newRule on all instances
+func (d *Dispatcher) UpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	entry := dispatchEntry{Rules: [][]string{oldRule}, NewRules: [][]string{newRule}}
+	return d.dispatch(dispatchUpdatePolicy, sec, ptype, entry, func(ctx context.Context) error {
+		return d.adapter.UpdatePolicyCtx(ctx, sec, ptype, oldRule, newRule)
+	})
+}
+
+// UpdatePolicies replaces oldRules with newRules on all instances
+func (d *Dispatcher) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	entry := dispatchEntry{Rules: oldRules, NewRules: newRules}
+	return d.dispatch(dispatchUpdatePolicies, sec, ptype, entry, func(ctx context.Context) error {
+		return d.adapter.UpdatePoliciesCtx(ctx, sec, ptype, oldRules, newRules)
+	})
+}
+
+// UpdateFilteredPolicies removes oldRules and adds newRules on all instances
+func (d *Dispatcher) UpdateFilteredPolicies(sec string, ptype string, oldRules [][]string, newRules [][]string) error {
+	entry := dispatchEntry{Rules: oldRules, NewRules: newRules}
+	return d.dispatch(dispatchUpdateFilteredPolicies, sec, ptype, entry, func(ctx context.Context) error {
+		if err := d.adapter.RemovePoliciesCtx(ctx, sec, ptype, oldRules); err != nil {
+			return err
+		}
+		return d.adapter.AddPoliciesCtx(ctx, sec, ptype, newRules)
+	})
+}
+
+// dispatch queues a change and persists it with persist while holding the
+// advisory lock. persist runs in the transaction of the queue row, so the
+// change and its queue row are committed together or not at all.
+func (d *Dispatcher) dispatch(op, sec, ptype string, entry dispatchEntry, persist func(ctx context.Context) error) error {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode dispatch entry: %w", err)
+	}
+
+	ctx, cancel := d.adapter.writeContext(context.Background())
+	defer cancel()
+
+	d.connMu.Lock()
+	defer d.connMu.Unlock()
+
+	if d.conn.IsClosed() {
+		conn, err := d.adapter.listenConn(ctx)
+		if err != nil {
+			return err
+		}
+		d.conn = conn
+	}
+
+	tx, err := d.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", d.adapter.GetAdvisoryLockKey()); err != nil {
+		return fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO "+d.queueTable()+" (op, sec, ptype, payload, created_at) VALUES ($1, $2, $3, $4, $5)",
+		op, sec, ptype, payload, d.adapter.now(),
+	); err != nil {
+		return fmt.Errorf("failed to queue change: %w", err)
+	}
+
+	if err := persist(WithTx(ctx, tx)); err != nil {
+		return err
+	}
+
+	if d.retention > 0 {
+		if _, err := tx.Exec(ctx,
+			"DELETE FROM "+d.queueTable()+" WHERE created_at < $1", d.adapter.now().Add(-d.retention),
+		); err != nil {
+			return fmt.Errorf("failed to prune dispatch queue: %w", err)
+		}
+	}
+
+	// Delivered on commit, so consumers never wake up before the row is visible
+	if _, err := tx.Exec(ctx, "SELECT pg_notify($1, '')", d.channel()); err != nil {
+		return fmt.Errorf("failed to notify: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// applyPending applies the queued entries this instance has not applied yet.
+// If an entry cannot be decoded or applied, it is skipped and the enforcer
+// reloads the whole policy. Errors are logged, as there is no caller to return
+// them to; if the queue cannot be read at all, the entries are retried on the
+// next notification.
+func (d *Dispatcher) applyPending(ctx context.Context) {
+	d.applyMu.Lock()
+	defer d.applyMu.Unlock()
+
+	ctx, cancel := d.adapter.readContext(ctx)
+	defer cancel()
+
+	pending, err := d.readPending(ctx)
+	if err != nil {
+		slog.Warn("casbin pgx adapter: failed to read dispatch queue",
+			"table", d.adapter.tableName, "error", err)
+		return
+	}
+
+	for _, q := range pending {
+		err := q.err
+		if err == nil {
+			err = d.apply(q.op, q.sec, q.ptype, q.entry)
+		}
+		if err != nil {
+			slog.Warn("casbin pgx adapter: failed to apply dispatched change, reloading policy",
+				"table", d.adapter.tableName, "id", q.id, "op", q.op, "error", err)
+			if err := d.enforcer.LoadPolicy(); err != nil {
+				slog.Warn("casbin pgx adapter: failed to reload policy",
+					"table", d.adapter.tableName, "error", err)
+			}
+		}
+		d.lastID = q.id
+	}
+}
+
+// resync reloads the whole policy and positions the dispatcher after the last
+// queued entry, for when queued entries may have been pruned unapplied
+func (d *Dispatcher) resync(ctx context.Context) {
+	d.applyMu.Lock()
+	defer d.applyMu.Unlock()
+
+	slog.Warn("casbin pgx adapter: dispatcher was disconnected longer than the queue retention, reloading policy",
+		"table", d.adapter.tableName, "retention", d.retention)
+
+	// Positioned first, so entries queued during the reload are applied after it
+	if err := d.readLastID(ctx); err != nil {
+		slog.Warn("casbin pgx adapter: failed to read dispatch queue",
+			"table", d.adapter.tableName, "error", err)
+	}
+	if err := d.enforcer.LoadPolicy(); err != nil {
+		slog.Warn("casbin pgx adapter: failed to reload policy",
+			"table", d.adapter.tableName, "error", err)
+	}
+}
+
+// queuedEntry is a queue row read by readPending. err is set when the payload
+// could not be decoded.
+type queuedEntry struct {
+	id             int64
+	op, sec, ptype string
+	entry          dispatchEntry
+	err            error
+}
+
+// readPending reads the queued entries after lastID, in order
+func (d *Dispatcher) readPending(ctx context.Context) ([]queuedEntry, error) {
+	rows, err := d.adapter.db.Query(ctx,
+		"SELECT id, op, sec, ptype, payload FROM "+d.queueTable()+" WHERE id > $1 ORDER BY id", d.lastID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []queuedEntry
+	for rows.Next() {
+		var q queuedEntry
+		var payload []byte
+		if err := rows.Scan(&q.id, &q.op, &q.sec, &q.ptype, &payload); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(payload, &q.entry); err != nil {
+			q.err = fmt.Errorf("failed to decode dispatch entry: %w", err)
+		}
+		pending = append(pending, q)
+	}
+	return pending, rows.Err()
+}
+
+// apply replays one queued change on the enforcer without persisting it
+func (d *Dispatcher) apply(op, sec, ptype string, entry dispatchEntry) error {
+	noPersist := func() bool { return false }
+	e := d.enforcer
+
+	var err error
+	switch op {
+	case dispatchAddPolicies:
+		_, err = e.AddPoliciesSelf(noPersist, sec, ptype, entry.Rules)
+	case dispatchRemovePolicies:
+		_, err = e.RemovePoliciesSelf(noPersist, sec, ptype, entry.Rules)
+	case dispatchRemoveFilteredPolicy:
+		_, err = e.RemoveFilteredPolicySelf(noPersist, sec, ptype, entry.FieldIndex, entry.FieldValues...)
+	case dispatchClearPolicy:
+		err = e.ClearPolicySelf(noPersist)
+	case dispatchUpdatePolicy:
+		if len(entry.Rules) != 1 || len(entry.NewRules) != 1 {
+			return errors.New("malformed update_policy entry")
+		}
+		_, err = e.UpdatePolicySelf(noPersist, sec, ptype, entry.Rules[0], entry.NewRules[0])
+	case dispatchUpdatePolicies:
+		_, err = e.UpdatePoliciesSelf(noPersist, sec, ptype, entry.Rules, entry.NewRules)
+	case dispatchUpdateFilteredPolicies:
+		if _, err = e.RemovePoliciesSelf(noPersist, sec, ptype, entry.Rules); err == nil {
+			_, err = e.AddPoliciesSelf(noPersist, sec, ptype, entry.NewRules)
+		}
+	default:
+		err = fmt.Errorf("unknown dispatch operation %q", op)
+	}
+	return err
+}
+
+// queueTable returns the sanitized name of the queue table
+func (d *Dispatcher) queueTable() string {
+	return d.adapter.qualifiedName(d.adapter.tableName + "_dispatch")
+}
+
+// channel returns the NOTIFY channel that wakes up consumers
+func (d *Dispatcher) channel() string {
+	return d.adapter.baseNotifyChannel() + "_dispatch"
+}
+
+// readLastID positions the dispatcher after the last queued entry
+func (d *Dispatcher) readLastID(ctx context.Context) error {
+	ctx, cancel := d.adapter.readContext(ctx)
+	defer cancel()
+
+	if err := d.adapter.db.QueryRow(ctx, "SELECT COALESCE(MAX(id), 0) FROM "+d.queueTable()).Scan(&d.lastID); err != nil {
+		return fmt.Errorf("failed to read dispatch queue: %w", err)
+	}
+	return nil
+}
+
+// createQueue creates the queue table
+func (d *Dispatcher) createQueue(ctx context.Context) error {
+	ctx, cancel := d.adapter.writeContext(ctx)
+	defer cancel()
+
+	ddl := `CREATE TABLE IF NOT EXISTS ` + d.queueTable() + ` (
+		id BIGSERIAL PRIMARY KEY,
+		op VARCHAR(32) NOT NULL,
+		sec VARCHAR(16) NOT NULL,
+		ptype VARCHAR(100) NOT NULL,
+		payload JSONB NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL
+	)`
+	if _, err := d.adapter.db.Exec(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to create dispatch queue: %w", err)
+	}
+
+	// Pruning deletes by age
+	index := d.adapter.tableName + "_dispatch_created_at_idx"
+	if _, err := d.adapter.db.Exec(ctx,
+		"CREATE INDEX IF NOT EXISTS "+pgx.Identifier{index}.Sanitize()+" ON "+d.queueTable()+" (created_at)",
+	); err != nil {
+		return fmt.Errorf("failed to create dispatch queue index: %w", err)
+	}
+	return nil
+}
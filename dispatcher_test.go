@@ -0,0 +1,245 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestDispatcher(t *testing.T) {
+	tableName := "casbin_test_dispatcher"
+	pool := setupTestPool(t, tableName)
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), "DROP TABLE IF EXISTS "+tableName+"_dispatch")
+	})
+
+	newEnforcer := func() *casbin.DistributedEnforcer {
+		adapter, err := pgxadapter.NewAdapterWithPool(pool,
+			pgxadapter.WithTableName(tableName),
+			pgxadapter.WithNamespace("dispatcher"),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		m, err := model.NewModelFromString(TestModelText)
+		if err != nil {
+			t.Fatalf("Failed to create model: %v", err)
+		}
+		e, err := casbin.NewDistributedEnforcer(m, adapter)
+		if err != nil {
+			t.Fatalf("Failed to create enforcer: %v", err)
+		}
+		d, err := pgxadapter.NewDispatcher(context.Background(), adapter, e)
+		if err != nil {
+			t.Fatalf("Failed to create dispatcher: %v", err)
+		}
+		t.Cleanup(d.Close)
+		return e
+	}
+
+	first := newEnforcer()
+	second := newEnforcer()
+
+	eventually := func(e *casbin.DistributedEnforcer, rule []any, want bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if ok, _ := e.Enforce(rule...); ok == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Enforce(%v) did not become %v", rule, want)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	if _, err := first.AddPolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if _, err := second.AddPolicy("bob", "data2", "write"); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if _, err := first.RemovePolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("Failed to remove policy: %v", err)
+	}
+
+	for _, e := range []*casbin.DistributedEnforcer{first, second} {
+		eventually(e, []any{"bob", "data2", "write"}, true)
+		eventually(e, []any{"alice", "data1", "read"}, false)
+	}
+
+	// The queued changes were persisted exactly once
+	var count int
+	if err := pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM "+tableName).Scan(&count); err != nil {
+		t.Fatalf("Failed to count rules: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 persisted rule, got %d", count)
+	}
+}
+
+func TestDispatcherFailedChangeIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	tableName := "casbin_test_dispatcher_atomic"
+	pool := setupTestPool(t, tableName)
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), "DROP TABLE IF EXISTS "+tableName+"_dispatch")
+	})
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+
+	m, err := model.NewModelFromString(TestModelText)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	e, err := casbin.NewDistributedEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("Failed to create enforcer: %v", err)
+	}
+	d, err := pgxadapter.NewDispatcher(ctx, adapter, e)
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+	t.Cleanup(d.Close)
+
+	// Removing alice succeeds, but adding bob's existing rule fails, which
+	// must roll back the removal along with the queue row
+	err = d.UpdateFilteredPolicies("p", "p", [][]string{{"alice", "data1", "read"}}, [][]string{{"bob", "data2", "write"}})
+	if err == nil {
+		t.Fatal("UpdateFilteredPolicies() expected error for an existing rule")
+	}
+
+	var rules, queued int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+tableName).Scan(&rules); err != nil {
+		t.Fatalf("Failed to count rules: %v", err)
+	}
+	if rules != 2 {
+		t.Errorf("Expected the 2 rules to be kept, got %d", rules)
+	}
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+tableName+"_dispatch").Scan(&queued); err != nil {
+		t.Fatalf("Failed to count queued changes: %v", err)
+	}
+	if queued != 0 {
+		t.Errorf("Expected no queued change, got %d", queued)
+	}
+
+	// ClearPolicy runs in the queue transaction as well
+	if err := d.ClearPolicy(); err != nil {
+		t.Fatalf("ClearPolicy() unexpected error: %v", err)
+	}
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+tableName).Scan(&rules); err != nil {
+		t.Fatalf("Failed to count rules: %v", err)
+	}
+	if rules != 0 {
+		t.Errorf("Expected no rules after ClearPolicy, got %d", rules)
+	}
+}
+
+func TestDispatcherSkipsUndecodableEntry(t *testing.T) {
+	ctx := context.Background()
+	tableName := "casbin_test_dispatcher_bad_entry"
+	pool := setupTestPool(t, tableName)
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), "DROP TABLE IF EXISTS "+tableName+"_dispatch")
+	})
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	m, err := model.NewModelFromString(TestModelText)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	e, err := casbin.NewDistributedEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("Failed to create enforcer: %v", err)
+	}
+	d, err := pgxadapter.NewDispatcher(ctx, adapter, e)
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+	t.Cleanup(d.Close)
+
+	// A payload that is valid JSON but not a dispatch entry
+	if _, err := pool.Exec(ctx,
+		"INSERT INTO "+tableName+"_dispatch (op, sec, ptype, payload, created_at) VALUES ('add_policies', 'p', 'p', '{\"rules\": \"x\"}', now())",
+	); err != nil {
+		t.Fatalf("Failed to queue malformed entry: %v", err)
+	}
+
+	// Changes queued after it are still applied
+	if err := d.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}}); err != nil {
+		t.Fatalf("AddPolicies() unexpected error: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if ok, _ := e.Enforce("alice", "data1", "read"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Change queued after a malformed entry was not applied")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestDispatcherQueueRetention(t *testing.T) {
+	ctx := context.Background()
+	tableName := "casbin_test_dispatcher_retention"
+	pool := setupTestPool(t, tableName)
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), "DROP TABLE IF EXISTS "+tableName+"_dispatch")
+	})
+
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	m, err := model.NewModelFromString(TestModelText)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	e, err := casbin.NewDistributedEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("Failed to create enforcer: %v", err)
+	}
+	d, err := pgxadapter.NewDispatcher(ctx, adapter, e, pgxadapter.WithQueueRetention(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+	t.Cleanup(d.Close)
+
+	if err := d.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}}); err != nil {
+		t.Fatalf("AddPolicies() unexpected error: %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+	if err := d.AddPolicies("p", "p", [][]string{{"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("AddPolicies() unexpected error: %v", err)
+	}
+
+	// Only the change within the retention is kept
+	var queued int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+tableName+"_dispatch").Scan(&queued); err != nil {
+		t.Fatalf("Failed to count queued changes: %v", err)
+	}
+	if queued != 1 {
+		t.Errorf("Expected 1 queued change after pruning, got %d", queued)
+	}
+}
@@ -0,0 +1,74 @@
+package pgxadapter
+
+import (
+	"fmt"
+
+	"github.com/casbin/casbin/v3/model"
+)
+
+// WithEftColumn stores the policy effect token (e.g. p = sub, obj, act, eft)
+// in a dedicated eft column instead of the next positional vN column.
+// The effect must be the last token of the policy definition. Its position is
+// learned from the model on LoadPolicy, LoadFilteredPolicy and SavePolicy, so
+// rules written before the first of those are stored positionally.
+func WithEftColumn() Option {
+	return func(a *PgxAdapter) {
+		a.useEftColumn = true
+	}
+}
+
+// learnEft records the position of the effect token for every policy type of the model
+func (a *PgxAdapter) learnEft(model model.Model) error {
+	if !a.useEftColumn {
+		return nil
+	}
+
+	indexes := make(map[string]int)
+	for ptype, ast := range model["p"] {
+		for i, token := range ast.Tokens {
+			if token != ptype+"_eft" {
+				continue
+			}
+			if i != len(ast.Tokens)-1 {
+				return fmt.Errorf("ptype %s must declare eft as its last token", ptype)
+			}
+			indexes[ptype] = i
+		}
+	}
+
+	a.mu.Lock()
+	a.eftIndexes = indexes
+	a.mu.Unlock()
+
+	return nil
+}
+
+// eftIndex returns the position of the effect token in rules of ptype
+func (a *PgxAdapter) eftIndex(ptype string) (int, bool) {
+	if !a.useEftColumn {
+		return 0, false
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	idx, ok := a.eftIndexes[ptype]
+	return idx, ok
+}
+
+// splitEft separates the effect token from the rest of the rule.
+// The returned effect is nil when ptype has no effect token.
+func (a *PgxAdapter) splitEft(ptype string, rule []string) ([]string, any) {
+	idx, ok := a.eftIndex(ptype)
+	if !ok || idx >= len(rule) || rule[idx] == "" {
+		return rule, nil
+	}
+	return rule[:idx], rule[idx]
+}
+
+// eftColumnDDL returns the column definition of the effect column, if enabled
+func (a *PgxAdapter) eftColumnDDL() string {
+	if !a.useEftColumn {
+		return ""
+	}
+	return ",\n\t\t" + eftColumn + " VARCHAR(100)"
+}
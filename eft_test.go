@@ -0,0 +1,137 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+	"github.com/jackc/pgx/v5"
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+// testEftModelText is a Casbin model with a per-rule effect token
+var testEftModelText = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[policy_effect]
+e = some(where (p.eft == allow)) && !some(where (p.eft == deny))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`
+
+func TestWithEftColumn(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tableName := "casbin_test_eft_column"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithEftColumn(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(testEftModelText)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("Failed to create enforcer: %v", err)
+	}
+
+	rules := [][]string{
+		{"alice", "data1", "read", "allow"},
+		{"alice", "data1", "write", "deny"},
+		{"bob", "data2", "read", "allow"},
+	}
+	for _, rule := range rules {
+		if _, err := e.AddPolicy(rule); err != nil {
+			t.Fatalf("Failed to add policy: %v", err)
+		}
+	}
+
+	// Verify the effect is stored in its own column
+	var eftCount, v3Count int
+	quotedTableName := pgx.Identifier{tableName}.Sanitize()
+	err = conn.QueryRow(ctx,
+		"SELECT COUNT(eft), COUNT(v3) FROM "+quotedTableName,
+	).Scan(&eftCount, &v3Count)
+	if err != nil {
+		t.Fatalf("Failed to count effect values: %v", err)
+	}
+	if eftCount != 3 || v3Count != 0 {
+		t.Errorf("Stored %d eft values and %d v3 values, want 3 and 0", eftCount, v3Count)
+	}
+
+	// Verify filtering to allow rules only
+	filteredModel, _ := model.NewModelFromString(testEftModelText)
+	err = adapter.LoadFilteredPolicyCtx(ctx, filteredModel, pgxadapter.Filter{Eft: []string{"allow"}})
+	if err != nil {
+		t.Fatalf("LoadFilteredPolicyCtx() unexpected error: %v", err)
+	}
+
+	policies, _ := filteredModel.GetPolicy("p", "p")
+	if len(policies) != 2 {
+		t.Fatalf("LoadFilteredPolicyCtx() loaded %d policies, want 2", len(policies))
+	}
+	for _, policy := range policies {
+		if policy[3] != "allow" {
+			t.Errorf("LoadFilteredPolicyCtx() loaded %v, want only allow rules", policy)
+		}
+	}
+
+	// Verify the effect round trips through removal
+	if _, err := e.RemovePolicy("alice", "data1", "write", "deny"); err != nil {
+		t.Errorf("RemovePolicy() unexpected error: %v", err)
+	}
+}
+
+func TestWithEftColumnTransaction(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tableName := "casbin_test_eft_column_tx"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithEftColumn(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	// Loading teaches the adapter where the effect is
+	m, _ := model.NewModelFromString(testEftModelText)
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	err = adapter.Transaction(ctx, func(tx *pgxadapter.PgxAdapter) error {
+		if err := tx.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read", "allow"}); err != nil {
+			return err
+		}
+		return tx.UpdatePolicyCtx(ctx, "p", "p",
+			[]string{"alice", "data1", "read", "allow"}, []string{"alice", "data1", "read", "deny"})
+	})
+	if err != nil {
+		t.Fatalf("Transaction() unexpected error: %v", err)
+	}
+
+	// The view stores the effect in its own column like the adapter
+	var eft, v3 *string
+	quotedTableName := pgx.Identifier{tableName}.Sanitize()
+	if err := conn.QueryRow(ctx, "SELECT eft, v3 FROM "+quotedTableName).Scan(&eft, &v3); err != nil {
+		t.Fatalf("Failed to read the rule: %v", err)
+	}
+	if eft == nil || *eft != "deny" || v3 != nil {
+		t.Errorf("Stored eft %v and v3 %v, want deny and NULL", eft, v3)
+	}
+}
@@ -0,0 +1,32 @@
+package pgxadapter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrCheckViolation is returned when a written value is rejected by a check
+// constraint, such as one added with WithCheckConstraint
+var ErrCheckViolation = errors.New("policy value violates check constraint")
+
+// ErrDuplicateRules is returned when the unique index cannot be created because
+// the table already holds duplicate rules, see DeduplicateTable
+var ErrDuplicateRules = errors.New("table contains duplicate policy rules")
+
+// ErrTableNotReady is returned when WithSkipTableCreate is set and the table,
+// one of its columns or its unique index does not exist
+var ErrTableNotReady = errors.New("policy table is not ready")
+
+// ErrMaxLoadRows is returned when a load reads more rows than allowed by WithMaxLoadRows
+var ErrMaxLoadRows = errors.New("policy load exceeds maximum rows")
+
+// mapWriteError maps database errors raised by writes to the package sentinels
+func mapWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23514" {
+		return fmt.Errorf("%w %s: %w", ErrCheckViolation, pgErr.ConstraintName, err)
+	}
+	return err
+}
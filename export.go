@@ -0,0 +1,93 @@
+package pgxadapter
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// policyJSON is the JSON representation of a policy rule written by ExportJSON
+type policyJSON struct {
+	Ptype string   `json:"ptype"`
+	Rule  []string `json:"rule"`
+}
+
+// GetRawPolicies returns every stored rule as its ptype followed by its values.
+// Use WithCanonicalOrder for output that is stable across databases.
+func (a *PgxAdapter) GetRawPolicies(ctx context.Context) ([][]string, error) {
+	ctx, cancel := a.readContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return nil, err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sql, args, err := a.selectRules(ctx, a.selectColumns()...).
+		OrderBy(a.orderBy()...).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := a.dbFrom(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policies: %w", err)
+	}
+	defer rows.Close()
+
+	var policies [][]string
+	scanner := a.newRuleScanner()
+	for rows.Next() {
+		line, err := scanner.scanLine(rows, nil)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return policies, nil
+}
+
+// ExportCSV writes every stored rule to w in Casbin's CSV policy format
+func (a *PgxAdapter) ExportCSV(ctx context.Context, w io.Writer) error {
+	policies, err := a.GetRawPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(policies); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	return nil
+}
+
+// ExportJSON writes every stored rule to w as a JSON array of {"ptype", "rule"} objects
+func (a *PgxAdapter) ExportJSON(ctx context.Context, w io.Writer) error {
+	policies, err := a.GetRawPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	out := make([]policyJSON, 0, len(policies))
+	for _, policy := range policies {
+		out = append(out, policyJSON{Ptype: policy[0], Rule: policy[1:]})
+	}
+
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		return fmt.Errorf("failed to write json: %w", err)
+	}
+
+	return nil
+}
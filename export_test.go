@@ -0,0 +1,69 @@
+package pgxadapter_test
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestExportCanonicalOrder(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rules := [][]string{
+		{"p", "alice", "data1", "read"},
+		{"p", "Bob", "data2", "write"},
+		{"p", "alice", "data1"},
+		{"g", "alice", "admin"},
+		{"g", "bob", "member"},
+	}
+
+	exportTable := func(tableName string, rules [][]string) ([]byte, []byte) {
+		conn := setupTestDB(t, tableName)
+
+		adapter, err := pgxadapter.NewAdapterWithConn(conn,
+			pgxadapter.WithTableName(tableName),
+			pgxadapter.WithCanonicalOrder(),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+
+		for _, rule := range rules {
+			if err := adapter.AddPolicyCtx(ctx, rule[0], rule[0], rule[1:]); err != nil {
+				t.Fatalf("Failed to add policy: %v", err)
+			}
+		}
+
+		var csvOut, jsonOut bytes.Buffer
+		if err := adapter.ExportCSV(ctx, &csvOut); err != nil {
+			t.Fatalf("ExportCSV() unexpected error: %v", err)
+		}
+		if err := adapter.ExportJSON(ctx, &jsonOut); err != nil {
+			t.Fatalf("ExportJSON() unexpected error: %v", err)
+		}
+
+		return csvOut.Bytes(), jsonOut.Bytes()
+	}
+
+	reversed := slices.Clone(rules)
+	slices.Reverse(reversed)
+
+	csvA, jsonA := exportTable("casbin_test_export_order_a", rules)
+	csvB, jsonB := exportTable("casbin_test_export_order_b", reversed)
+
+	if !bytes.Equal(csvA, csvB) {
+		t.Errorf("ExportCSV() output differs:\n%s\nvs\n%s", csvA, csvB)
+	}
+	if !bytes.Equal(jsonA, jsonB) {
+		t.Errorf("ExportJSON() output differs:\n%s\nvs\n%s", jsonA, jsonB)
+	}
+
+	expectedCSV := "g,alice,admin\ng,bob,member\np,Bob,data2,write\np,alice,data1\np,alice,data1,read\n"
+	if string(csvA) != expectedCSV {
+		t.Errorf("ExportCSV() = %q, want %q", csvA, expectedCSV)
+	}
+}
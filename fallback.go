@@ -0,0 +1,83 @@
+package pgxadapter
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/casbin/casbin/v3/persist"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// WithInMemoryFallback keeps a snapshot of the last successful LoadPolicy.
+// If a later LoadPolicy fails because the database is unreachable, the snapshot
+// is loaded into the model instead and a warning is logged, so enforcement keeps
+// serving the last known good (possibly stale) policy during a failover.
+// Filtered loads and writes never fall back and still return their errors.
+func WithInMemoryFallback() Option {
+	return func(a *PgxAdapter) {
+		a.inMemoryFallback = true
+	}
+}
+
+// applyFallback stores the snapshot of a successful load, or loads the previous
+// snapshot into the model when the load failed with a connection error
+func (a *PgxAdapter) applyFallback(model model.Model, snapshot [][]string, err error) error {
+	if err == nil {
+		a.mu.Lock()
+		a.snapshot = snapshot
+		a.mu.Unlock()
+		return nil
+	}
+
+	if !isConnectionError(err) {
+		return err
+	}
+
+	a.mu.RLock()
+	cached := a.snapshot
+	a.mu.RUnlock()
+
+	if cached == nil {
+		return err
+	}
+
+	slog.Warn("casbin pgx adapter: serving cached policy after load failure",
+		"table", a.tableName, "rules", len(cached), "error", err)
+
+	// Drop rows loaded before the failure so the model matches the snapshot
+	model.ClearPolicy()
+	for _, line := range cached {
+		// Loaded as an array like the snapshotted load, so values holding
+		// commas or quotes are not split again
+		if err := persist.LoadPolicyArray(slices.Clone(line), model); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isConnectionError reports whether err means the database could not be reached
+func isConnectionError(err error) bool {
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	var pgErr *pgconn.PgError
+
+	switch {
+	case errors.As(err, &connectErr), errors.As(err, &netErr):
+		return true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case errors.As(err, &pgErr):
+		// Class 08 is connection exception, 57P0x is operator intervention such as shutdown
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "57P")
+	}
+
+	// pgx reports use of a closed connection or pool with plain errors
+	return strings.Contains(err.Error(), "conn closed") || strings.Contains(err.Error(), "closed pool")
+}
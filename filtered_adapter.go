@@ -2,12 +2,16 @@ package pgxadapter
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/casbin/casbin/v3/model"
 	"github.com/casbin/casbin/v3/persist"
+	fileadapter "github.com/casbin/casbin/v3/persist/file-adapter"
+	"github.com/jackc/pgx/v5"
 )
 
 // Filter defines the filtering rules for a FilteredAdapter's policy.
@@ -20,6 +24,79 @@ type Filter struct {
 	V3    []string
 	V4    []string
 	V5    []string
+	// Eft filters on the effect column, requires WithEftColumn
+	Eft []string
+	// AnyLike matches rules containing every term, case insensitively, in
+	// any of ptype, v0..v5 or eft. Terms are plain substrings: %, _ and \
+	// match themselves rather than acting as LIKE wildcards.
+	AnyLike []string
+	// NotPtype..NotEft exclude the rules whose field matches one of the values,
+	// e.g. NotV0 loads everything except the rules of a set of subjects. Rules
+	// without a value for the field are kept.
+	NotPtype []string
+	NotV0    []string
+	NotV1    []string
+	NotV2    []string
+	NotV3    []string
+	NotV4    []string
+	NotV5    []string
+	NotEft   []string
+	// Limit caps the number of rules loaded and Offset skips the first rules,
+	// so admin UIs can page through the rules in the order of OrderBy. Each
+	// filter of a BatchFilter is paged on its own; IntersectFilter does not
+	// support paging.
+	Limit  uint64
+	Offset uint64
+	// OrderBy orders the rules by fields (ptype, v0..v5, eft or id), each
+	// optionally followed by ASC or DESC, e.g. []string{"v0", "v1 DESC"}. Ties
+	// are broken by id. Defaults to the order of LoadPolicy.
+	OrderBy []string
+	// Match sets how the values of a field are compared, keyed by field name
+	// (ptype, v0..v5 or eft), e.g. {"v1": MatchPrefix} with V1 set to
+	// "/api/v2/" loads the rules whose v1 starts with /api/v2/. It applies to
+	// the exclusions of the field as well. Fields not listed are matched exactly.
+	Match map[string]MatchMode
+}
+
+// paged reports whether the filter sets Limit, Offset or OrderBy
+func (f Filter) paged() bool {
+	return f.Limit > 0 || f.Offset > 0 || len(f.OrderBy) > 0
+}
+
+// MatchMode sets how a Filter compares the values of a field
+type MatchMode int
+
+const (
+	// MatchExact matches values equal to one of the filter values
+	MatchExact MatchMode = iota
+	// MatchPrefix matches values starting with one of the filter values. The
+	// filter values are plain strings: %, _ and \ match themselves.
+	MatchPrefix
+	// MatchLike matches values against the filter values as LIKE patterns
+	MatchLike
+	// MatchRegex matches values against the filter values as POSIX regular
+	// expressions with the ~ operator, e.g. ^team:.*:admin$
+	MatchRegex
+	// MatchRegexInsensitive is MatchRegex ignoring case, with the ~* operator
+	MatchRegexInsensitive
+)
+
+// SQLFilter loads the rules matching a raw SQL condition, for conditions a
+// Filter cannot express such as "v3::int > ?". Args are bound to the ?
+// placeholders of Where, so values are never spliced into the SQL, but Where
+// itself runs as written and must not be built from user input. Columns are
+// referred to by their names in the table.
+type SQLFilter struct {
+	Where string
+	Args  []any
+}
+
+// ToSql returns the condition of the filter, making it a squirrel Sqlizer
+func (f SQLFilter) ToSql() (string, []any, error) {
+	if strings.TrimSpace(f.Where) == "" {
+		return "", nil, errors.New("filter condition must not be empty")
+	}
+	return f.Where, f.Args, nil
 }
 
 // BatchFilter wraps multiple filters for OR-based filtering.
@@ -28,6 +105,15 @@ type BatchFilter struct {
 	Filters []Filter
 }
 
+// IntersectFilter wraps multiple filters for AND-based filtering.
+// The conditions of every filter are combined in a single query, so a rule is
+// loaded only if it matches all of them. When several filters constrain the
+// same field, the rule must match each value set, which loads the intersection
+// of the sets; appending the values into one Filter field would load their union.
+type IntersectFilter struct {
+	Filters []Filter
+}
+
 // LoadFilteredPolicy loads only policy rules that match the filter
 func (a *PgxAdapter) LoadFilteredPolicy(model model.Model, filter any) error {
 	return a.LoadFilteredPolicyCtx(context.Background(), model, filter)
@@ -39,16 +125,49 @@ func (a *PgxAdapter) IsFiltered() bool {
 }
 
 // LoadFilteredPolicyCtx loads only policy rules that match the filter.
-// Supports Filter for single filter or BatchFilter for OR-based filtering.
+// Supports Filter for single filter, BatchFilter for OR-based filtering or
+// IntersectFilter for AND-based filtering.
+// For drop-in use with code written against other adapters it also accepts
+// the positional shapes below, where an empty value matches anything:
+//   - []string: ptype followed by v0..v5 values
+//   - [][]string: several of the above, combined with OR
+//   - fileadapter.Filter or *fileadapter.Filter: positional values per ptype
+//     (P, G, G1..G5). Unlike the file adapter, rules of other ptypes are not loaded.
+//
+// Conditions the shapes above cannot express can be passed as an SQLFilter or
+// any squirrel Sqlizer, such as sq.Expr("v3::int > ?", 5). The condition is
+// parenthesized and combined with the tenant scope of the adapter.
+//
+// Loads are serialized with LoadPolicyCtx, see its documentation.
 func (a *PgxAdapter) LoadFilteredPolicyCtx(ctx context.Context, model model.Model, filter any) error {
+	ctx, cancel := a.readContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	a.loadMu.Lock()
+	defer a.loadMu.Unlock()
+
 	if filter == nil {
 		a.mu.Lock()
 		a.isFiltered = false
 		a.mu.Unlock()
-		return a.LoadPolicyCtx(ctx, model)
+		return a.loadPolicy(ctx, model)
+	}
+
+	if err := a.learnEft(model); err != nil {
+		return err
 	}
 
 	var filters []Filter
+	var intersect bool
+	var cond sq.Sqlizer
 	switch f := filter.(type) {
 	case Filter:
 		filters = []Filter{f}
@@ -60,101 +179,267 @@ func (a *PgxAdapter) LoadFilteredPolicyCtx(ctx context.Context, model model.Mode
 		filters = f.Filters
 	case []Filter:
 		filters = f
+	case IntersectFilter:
+		filters, intersect = f.Filters, true
+	case *IntersectFilter:
+		filters, intersect = f.Filters, true
+	case []string:
+		filters = []Filter{filterFromFields(f)}
+	case [][]string:
+		for _, fields := range f {
+			filters = append(filters, filterFromFields(fields))
+		}
+	case fileadapter.Filter:
+		filters = filtersFromFileFilter(&f)
+	case *fileadapter.Filter:
+		filters = filtersFromFileFilter(f)
+	case sq.Sqlizer:
+		cond = f
 	default:
 		return fmt.Errorf("invalid filter type")
 	}
 
+	var queries []sq.SelectBuilder
+	switch {
+	case cond != nil:
+		// Parenthesized, so an OR in the condition cannot escape the tenant scope
+		queries = []sq.SelectBuilder{a.limitLoad(a.filteredSelect(ctx, sq.And{cond}))}
+	case intersect:
+		conds := sq.And{}
+		for _, filterValue := range filters {
+			if filterValue.paged() {
+				return errors.New("IntersectFilter does not support Limit, Offset or OrderBy")
+			}
+			conds = append(conds, a.filterConditions(filterValue))
+		}
+		queries = []sq.SelectBuilder{a.limitLoad(a.filteredSelect(ctx, conds))}
+	default:
+		for _, filterValue := range filters {
+			query, err := a.pagedSelect(ctx, filterValue)
+			if err != nil {
+				return err
+			}
+			queries = append(queries, query)
+		}
+	}
+
 	a.mu.Lock()
 	a.isFiltered = true
 	a.mu.Unlock()
 
-	for _, filterValue := range filters {
-		if err := a.loadFilteredPolicies(ctx, model, filterValue); err != nil {
+	// WithMaxLoadRows caps the rows of all queries together
+	loaded := 0
+	for _, query := range queries {
+		n, err := a.loadFilteredPolicies(ctx, model, query, loaded)
+		if err != nil {
 			return err
 		}
+		loaded += n
 	}
 
 	return nil
 }
 
-func (a *PgxAdapter) loadFilteredPolicies(ctx context.Context, model model.Model, filterValue Filter) error {
-	query := a.psql.
-		Select(selectColumns...).
-		From(a.tableName).
-		OrderBy("id")
+// filterConditions returns the conditions of a filter, combined with AND
+func (a *PgxAdapter) filterConditions(filterValue Filter) sq.And {
+	conds := sq.And{}
+
+	fields := []struct {
+		name   string
+		expr   string
+		values []string
+		not    []string
+	}{
+		{"ptype", a.column("ptype"), filterValue.Ptype, filterValue.NotPtype},
+		{"v0", a.columnExpr("v0"), filterValue.V0, filterValue.NotV0},
+		{"v1", a.columnExpr("v1"), filterValue.V1, filterValue.NotV1},
+		{"v2", a.columnExpr("v2"), filterValue.V2, filterValue.NotV2},
+		{"v3", a.columnExpr("v3"), filterValue.V3, filterValue.NotV3},
+		{"v4", a.columnExpr("v4"), filterValue.V4, filterValue.NotV4},
+		{"v5", a.columnExpr("v5"), filterValue.V5, filterValue.NotV5},
+		{"eft", eftColumn, filterValue.Eft, filterValue.NotEft},
+	}
+	for _, field := range fields {
+		if len(field.values) > 0 {
+			conds = append(conds, matchValues(field.expr, field.values, filterValue.Match[field.name]))
+		}
+		if len(field.not) > 0 {
+			conds = append(conds, excludeValues(field.expr, field.not, filterValue.Match[field.name]))
+		}
+	}
+	for _, term := range filterValue.AnyLike {
+		conds = append(conds, a.anyColumnLike(term))
+	}
+
+	return conds
+}
+
+// matchValues returns the condition matching expr against any of values in mode
+func matchValues(expr string, values []string, mode MatchMode) sq.Sqlizer {
+	if mode == MatchExact {
+		return sq.Eq{expr: values}
+	}
+
+	conds := make(sq.Or, len(values))
+	for i, value := range values {
+		switch mode {
+		case MatchPrefix:
+			conds[i] = sq.Like{expr: likeEscaper.Replace(value) + "%"}
+		case MatchRegex:
+			conds[i] = sq.Expr(expr+" ~ ?", value)
+		case MatchRegexInsensitive:
+			conds[i] = sq.Expr(expr+" ~* ?", value)
+		default:
+			conds[i] = sq.Like{expr: value}
+		}
+	}
+	return conds
+}
+
+// excludeValues returns the condition matching expr against none of values in
+// mode. NOT IN is never true for NULL, so rules without a value are kept explicitly.
+func excludeValues(expr string, values []string, mode MatchMode) sq.Sqlizer {
+	return sq.Or{sq.Eq{expr: nil}, sq.Expr("NOT (?)", matchValues(expr, values, mode))}
+}
+
+// likeEscaper escapes the LIKE wildcards and the default escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// anyColumnLike returns the condition matching rules containing term in any column
+func (a *PgxAdapter) anyColumnLike(term string) sq.Or {
+	pattern := "%" + likeEscaper.Replace(term) + "%"
 
-	if len(filterValue.Ptype) > 0 {
-		query = query.Where(sq.Eq{"ptype": filterValue.Ptype})
+	columns := []string{a.column("ptype")}
+	for i := range a.valueColumnTotal() {
+		columns = append(columns, a.columnExpr(valueColumn(i)))
 	}
-	if len(filterValue.V0) > 0 {
-		query = query.Where(sq.Eq{"v0": filterValue.V0})
+	if a.useEftColumn {
+		columns = append(columns, eftColumn)
 	}
-	if len(filterValue.V1) > 0 {
-		query = query.Where(sq.Eq{"v1": filterValue.V1})
+
+	conds := make(sq.Or, len(columns))
+	for i, col := range columns {
+		conds[i] = sq.ILike{col: pattern}
 	}
-	if len(filterValue.V2) > 0 {
-		query = query.Where(sq.Eq{"v2": filterValue.V2})
+	return conds
+}
+
+// filteredSelect returns the query reading the rules matching conds
+func (a *PgxAdapter) filteredSelect(ctx context.Context, conds sq.Sqlizer) sq.SelectBuilder {
+	return a.selectRules(ctx, a.selectColumns()...).
+		Where(conds).
+		OrderBy(a.orderBy()...)
+}
+
+// pagedSelect returns the query loading the rules of filterValue, ordered
+// and paged as it sets
+func (a *PgxAdapter) pagedSelect(ctx context.Context, filterValue Filter) (sq.SelectBuilder, error) {
+	orderBy := a.orderBy()
+	if len(filterValue.OrderBy) > 0 {
+		var err error
+		if orderBy, err = a.filterOrderBy(filterValue.OrderBy); err != nil {
+			return sq.SelectBuilder{}, err
+		}
 	}
-	if len(filterValue.V3) > 0 {
-		query = query.Where(sq.Eq{"v3": filterValue.V3})
+
+	query := a.selectRules(ctx, a.selectColumns()...).
+		Where(a.filterConditions(filterValue)).
+		OrderBy(orderBy...)
+
+	// A page within maxLoadRows can never exceed it, so it replaces the cap of limitLoad
+	query = a.limitLoad(query)
+	if filterValue.Limit > 0 && (a.maxLoadRows <= 0 || filterValue.Limit <= uint64(a.maxLoadRows)) {
+		query = query.Limit(filterValue.Limit)
 	}
-	if len(filterValue.V4) > 0 {
-		query = query.Where(sq.Eq{"v4": filterValue.V4})
+	if filterValue.Offset > 0 {
+		query = query.Offset(filterValue.Offset)
 	}
-	if len(filterValue.V5) > 0 {
-		query = query.Where(sq.Eq{"v5": filterValue.V5})
+	return query, nil
+}
+
+// filterOrderBy returns the ORDER BY list of the OrderBy fields of a Filter,
+// with id breaking ties so pages are stable
+func (a *PgxAdapter) filterOrderBy(fields []string) ([]string, error) {
+	orderBy := make([]string, 0, len(fields)+1)
+	for _, field := range fields {
+		name, direction, _ := strings.Cut(strings.TrimSpace(field), " ")
+		direction = strings.ToUpper(strings.TrimSpace(direction))
+		if direction != "" && direction != "ASC" && direction != "DESC" {
+			return nil, fmt.Errorf("invalid order %q: direction must be ASC or DESC", field)
+		}
+
+		var expr string
+		switch {
+		case name == "id":
+			expr = "id"
+		case name == "eft" && a.useEftColumn:
+			expr = eftColumn
+		case name == "ptype":
+			expr = a.column("ptype")
+		case slices.Contains(a.tableRuleColumns(), name):
+			expr = a.columnExpr(name)
+		default:
+			return nil, fmt.Errorf("invalid order %q: unknown field %q", field, name)
+		}
+		orderBy = append(orderBy, strings.TrimSpace(expr+" "+direction))
 	}
+	return append(orderBy, "id"), nil
+}
 
+// loadFilteredPolicies loads the rules read by query into the model and
+// returns the number of rows read. loaded is the number of rows already
+// loaded by earlier queries of the same load.
+func (a *PgxAdapter) loadFilteredPolicies(ctx context.Context, model model.Model, query sq.SelectBuilder, loaded int) (int, error) {
 	sqlQuery, args, err := query.ToSql()
 	if err != nil {
-		return fmt.Errorf("failed to build query: %w", err)
+		return 0, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if a.loadFetchSize > 0 {
+		count := 0
+		err := a.loadWithCursor(ctx, sqlQuery, args, func(rows pgx.Rows, fetched int) (int, error) {
+			n, err := a.loadFilteredRows(rows, model, loaded+fetched)
+			count += n
+			return n, err
+		})
+		return count, err
 	}
 
-	rows, err := a.db.Query(ctx, sqlQuery, args...)
+	rows, err := a.dbFrom(ctx).Query(ctx, sqlQuery, args...)
 	if err != nil {
-		return fmt.Errorf("failed to query policies: %w", err)
+		return 0, fmt.Errorf("failed to query policies: %w", err)
 	}
 	defer rows.Close()
 
-	for rows.Next() {
-		var ptypeVal string
-		var v0, v1, v2, v3, v4, v5 sql.NullString
+	return a.loadFilteredRows(rows, model, loaded)
+}
 
-		if err := rows.Scan(&ptypeVal, &v0, &v1, &v2, &v3, &v4, &v5); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+// loadFilteredRows loads every row into the model and returns the number of
+// rows read. loaded is the number of rows already loaded by earlier batches.
+func (a *PgxAdapter) loadFilteredRows(rows pgx.Rows, model model.Model, loaded int) (int, error) {
+	scanner := a.newRuleScanner()
+	count := 0
+	for rows.Next() {
+		count++
+		if err := a.checkLoadRows(loaded + count); err != nil {
+			return count, err
 		}
 
-		line := []string{ptypeVal}
-
-		if v0.Valid {
-			line = append(line, v0.String)
-		}
-		if v1.Valid {
-			line = append(line, v1.String)
-		}
-		if v2.Valid {
-			line = append(line, v2.String)
-		}
-		if v3.Valid {
-			line = append(line, v3.String)
-		}
-		if v4.Valid {
-			line = append(line, v4.String)
-		}
-		if v5.Valid {
-			line = append(line, v5.String)
+		line, err := scanner.scanLine(rows, nil)
+		if err != nil {
+			return count, err
 		}
 
 		if err := persist.LoadPolicyArray(line, model); err != nil {
-			return err
+			return count, err
 		}
 	}
 
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating rows: %w", err)
+		return count, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	return nil
+	return count, nil
 }
 
 // IsFilteredCtx returns true if the loaded policy has been filtered
@@ -163,3 +448,34 @@ func (a *PgxAdapter) IsFilteredCtx(ctx context.Context) bool {
 	defer a.mu.RUnlock()
 	return a.isFiltered
 }
+
+// filterFromFields converts a positional ptype, v0..v5 slice into a Filter
+func filterFromFields(fields []string) Filter {
+	var filter Filter
+	targets := []*[]string{&filter.Ptype, &filter.V0, &filter.V1, &filter.V2, &filter.V3, &filter.V4, &filter.V5}
+
+	for i, value := range fields {
+		if i < len(targets) && value != "" {
+			*targets[i] = []string{value}
+		}
+	}
+
+	return filter
+}
+
+// filtersFromFileFilter converts the file adapter's per ptype filter into one Filter per ptype
+func filtersFromFileFilter(f *fileadapter.Filter) []Filter {
+	fields := []struct {
+		ptype  string
+		values []string
+	}{
+		{"p", f.P}, {"g", f.G}, {"g1", f.G1}, {"g2", f.G2}, {"g3", f.G3}, {"g4", f.G4}, {"g5", f.G5},
+	}
+
+	filters := make([]Filter, 0, len(fields))
+	for _, field := range fields {
+		filters = append(filters, filterFromFields(append([]string{field.ptype}, field.values...)))
+	}
+
+	return filters
+}
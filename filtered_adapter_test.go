@@ -1,11 +1,15 @@
 package pgxadapter_test
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
 	"slices"
 	"testing"
 
+	sq "github.com/Masterminds/squirrel"
 	"github.com/casbin/casbin/v3/model"
+	fileadapter "github.com/casbin/casbin/v3/persist/file-adapter"
 	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
 )
 
@@ -307,6 +311,645 @@ func TestLoadFilteredPolicyInvalidFilter(t *testing.T) {
 	}
 }
 
+func TestLoadFilteredPolicyNativeShapes(t *testing.T) {
+	tests := []struct {
+		name       string
+		filter     any
+		equivalent any
+	}{
+		{
+			name:       "field_slice",
+			filter:     []string{"p", "alice"},
+			equivalent: pgxadapter.Filter{Ptype: []string{"p"}, V0: []string{"alice"}},
+		},
+		{
+			name:       "field_slice_with_wildcard",
+			filter:     []string{"p", "", "data1"},
+			equivalent: pgxadapter.Filter{Ptype: []string{"p"}, V1: []string{"data1"}},
+		},
+		{
+			name:   "multiple_field_slices",
+			filter: [][]string{{"p", "alice"}, {"g", "", "admin"}},
+			equivalent: pgxadapter.BatchFilter{Filters: []pgxadapter.Filter{
+				{Ptype: []string{"p"}, V0: []string{"alice"}},
+				{Ptype: []string{"g"}, V1: []string{"admin"}},
+			}},
+		},
+		{
+			name:   "file_adapter_filter",
+			filter: &fileadapter.Filter{P: []string{"", "data2"}, G: []string{"alice"}},
+			equivalent: pgxadapter.BatchFilter{Filters: []pgxadapter.Filter{
+				{Ptype: []string{"p"}, V1: []string{"data2"}},
+				{Ptype: []string{"g"}, V0: []string{"alice"}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tableName := fmt.Sprintf("casbin_test_filtered_native_%s", tt.name)
+			conn := setupTestDB(t, tableName)
+
+			adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			policies := [][]string{
+				{"p", "alice", "data1", "read"},
+				{"p", "bob", "data1", "write"},
+				{"p", "bob", "data2", "read"},
+				{"g", "alice", "admin"},
+				{"g", "bob", "member"},
+			}
+			for _, policy := range policies {
+				if err := adapter.AddPolicy(policy[0], policy[0], policy[1:]); err != nil {
+					t.Fatalf("Failed to insert test policy: %v", err)
+				}
+			}
+
+			m, _ := model.NewModelFromString(TestModelText)
+			if err := adapter.LoadFilteredPolicy(m, tt.filter); err != nil {
+				t.Fatalf("LoadFilteredPolicy() unexpected error: %v", err)
+			}
+
+			expected, _ := model.NewModelFromString(TestModelText)
+			if err := adapter.LoadFilteredPolicy(expected, tt.equivalent); err != nil {
+				t.Fatalf("LoadFilteredPolicy() with equivalent filter unexpected error: %v", err)
+			}
+
+			for _, ptype := range []string{"p", "g"} {
+				sec := ptype
+				got, _ := m.GetPolicy(sec, ptype)
+				want, _ := expected.GetPolicy(sec, ptype)
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("LoadFilteredPolicy() loaded %s policies %v, want %v", ptype, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFilteredPolicyIntersectFilter(t *testing.T) {
+	tests := []struct {
+		name          string
+		filter        any
+		expectedCount int
+	}{
+		{
+			name: "and_across_columns",
+			filter: pgxadapter.IntersectFilter{Filters: []pgxadapter.Filter{
+				{Ptype: []string{"p"}, V0: []string{"alice", "bob"}},
+				{V2: []string{"read"}},
+			}},
+			expectedCount: 2,
+		},
+		{
+			name: "same_field_intersects_value_sets",
+			filter: &pgxadapter.IntersectFilter{Filters: []pgxadapter.Filter{
+				{V0: []string{"alice", "bob"}},
+				{V0: []string{"bob", "carol"}},
+			}},
+			expectedCount: 2,
+		},
+		{
+			name: "merged_fields_union_value_sets",
+			filter: pgxadapter.Filter{
+				V0: []string{"alice", "bob", "bob", "carol"},
+			},
+			expectedCount: 5,
+		},
+		{
+			name: "disjoint_value_sets",
+			filter: pgxadapter.IntersectFilter{Filters: []pgxadapter.Filter{
+				{V0: []string{"alice"}},
+				{V0: []string{"carol"}},
+			}},
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tableName := fmt.Sprintf("casbin_test_filtered_intersect_%s", tt.name)
+			conn := setupTestDB(t, tableName)
+
+			adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			policies := [][]string{
+				{"p", "alice", "data1", "read"},
+				{"p", "alice", "data1", "write"},
+				{"p", "bob", "data2", "read"},
+				{"p", "carol", "data3", "read"},
+				{"g", "bob", "admin"},
+			}
+			for _, policy := range policies {
+				if err := adapter.AddPolicy(policy[0], policy[0], policy[1:]); err != nil {
+					t.Fatalf("Failed to insert test policy: %v", err)
+				}
+			}
+
+			m, _ := model.NewModelFromString(TestModelText)
+			if err := adapter.LoadFilteredPolicy(m, tt.filter); err != nil {
+				t.Fatalf("LoadFilteredPolicy() unexpected error: %v", err)
+			}
+
+			pPolicies, _ := m.GetPolicy("p", "p")
+			gPolicies, _ := m.GetPolicy("g", "g")
+			if count := len(pPolicies) + len(gPolicies); count != tt.expectedCount {
+				t.Errorf("LoadFilteredPolicy() loaded %d policies, want %d", count, tt.expectedCount)
+			}
+		})
+	}
+}
+
+func TestLoadFilteredPolicyAnyLike(t *testing.T) {
+	tests := []struct {
+		name          string
+		terms         []string
+		expectedCount int
+	}{
+		{
+			name:          "term_in_different_columns",
+			terms:         []string{"alice"},
+			expectedCount: 3,
+		},
+		{
+			name:          "terms_are_anded",
+			terms:         []string{"alice", "write"},
+			expectedCount: 1,
+		},
+		{
+			name:          "wildcards_match_literally",
+			terms:         []string{"%"},
+			expectedCount: 0,
+		},
+		{
+			name:          "underscore_matches_literally",
+			terms:         []string{"e_f"},
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tableName := fmt.Sprintf("casbin_test_filtered_any_like_%s", tt.name)
+			conn := setupTestDB(t, tableName)
+
+			adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			policies := [][]string{
+				{"p", "alice", "data1", "read"},
+				{"p", "bob", "alice_files", "write"},
+				{"p", "dave", "data2", "read"},
+				{"p", "erin", "dataexf", "read"},
+				{"g", "carol", "ALICE_group"},
+			}
+			for _, policy := range policies {
+				if err := adapter.AddPolicy(policy[0], policy[0], policy[1:]); err != nil {
+					t.Fatalf("Failed to insert test policy: %v", err)
+				}
+			}
+
+			m, _ := model.NewModelFromString(TestModelText)
+			if err := adapter.LoadFilteredPolicy(m, pgxadapter.Filter{AnyLike: tt.terms}); err != nil {
+				t.Fatalf("LoadFilteredPolicy() unexpected error: %v", err)
+			}
+
+			pPolicies, _ := m.GetPolicy("p", "p")
+			gPolicies, _ := m.GetPolicy("g", "g")
+			if count := len(pPolicies) + len(gPolicies); count != tt.expectedCount {
+				t.Errorf("LoadFilteredPolicy() loaded %d policies, want %d", count, tt.expectedCount)
+			}
+		})
+	}
+}
+
+func TestLoadFilteredPolicyMatchModes(t *testing.T) {
+	tests := []struct {
+		name          string
+		filter        pgxadapter.Filter
+		expectedCount int
+	}{
+		{
+			name:          "exact_by_default",
+			filter:        pgxadapter.Filter{V1: []string{"/api/v2/"}},
+			expectedCount: 0,
+		},
+		{
+			name:          "prefix",
+			filter:        pgxadapter.Filter{V1: []string{"/api/v2/"}, Match: map[string]pgxadapter.MatchMode{"v1": pgxadapter.MatchPrefix}},
+			expectedCount: 2,
+		},
+		{
+			name: "prefix_values_are_ored",
+			filter: pgxadapter.Filter{
+				V1:    []string{"/api/v1/", "/api/v2/"},
+				Match: map[string]pgxadapter.MatchMode{"v1": pgxadapter.MatchPrefix},
+			},
+			expectedCount: 3,
+		},
+		{
+			name:          "prefix_wildcards_match_literally",
+			filter:        pgxadapter.Filter{V1: []string{"/api/v_/"}, Match: map[string]pgxadapter.MatchMode{"v1": pgxadapter.MatchPrefix}},
+			expectedCount: 0,
+		},
+		{
+			name:          "like_pattern",
+			filter:        pgxadapter.Filter{V1: []string{"/api/v_/users%"}, Match: map[string]pgxadapter.MatchMode{"v1": pgxadapter.MatchLike}},
+			expectedCount: 2,
+		},
+		{
+			name:          "regex",
+			filter:        pgxadapter.Filter{V1: []string{"^/api/v[0-9]+/users$"}, Match: map[string]pgxadapter.MatchMode{"v1": pgxadapter.MatchRegex}},
+			expectedCount: 2,
+		},
+		{
+			name:          "regex_is_case_sensitive",
+			filter:        pgxadapter.Filter{V1: []string{"^/API/"}, Match: map[string]pgxadapter.MatchMode{"v1": pgxadapter.MatchRegex}},
+			expectedCount: 0,
+		},
+		{
+			name:          "regex_insensitive",
+			filter:        pgxadapter.Filter{V1: []string{"^/API/V2/"}, Match: map[string]pgxadapter.MatchMode{"v1": pgxadapter.MatchRegexInsensitive}},
+			expectedCount: 2,
+		},
+		{
+			name:          "regex_exclusion",
+			filter:        pgxadapter.Filter{NotV1: []string{"users$"}, Match: map[string]pgxadapter.MatchMode{"v1": pgxadapter.MatchRegex}},
+			expectedCount: 2,
+		},
+		{
+			name: "modes_per_field",
+			filter: pgxadapter.Filter{
+				V0:    []string{"alice"},
+				V1:    []string{"/api/v2/"},
+				Match: map[string]pgxadapter.MatchMode{"v1": pgxadapter.MatchPrefix},
+			},
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tableName := fmt.Sprintf("casbin_test_filtered_match_%s", tt.name)
+			conn := setupTestDB(t, tableName)
+
+			adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			policies := [][]string{
+				{"alice", "/api/v1/users", "read"},
+				{"alice", "/api/v2/users", "read"},
+				{"bob", "/api/v2/orders", "write"},
+				{"carol", "/web/api/v2/", "read"},
+			}
+			if err := adapter.AddPolicies("p", "p", policies); err != nil {
+				t.Fatalf("Failed to insert test policies: %v", err)
+			}
+
+			m, _ := model.NewModelFromString(TestModelText)
+			if err := adapter.LoadFilteredPolicy(m, tt.filter); err != nil {
+				t.Fatalf("LoadFilteredPolicy() unexpected error: %v", err)
+			}
+
+			if pPolicies, _ := m.GetPolicy("p", "p"); len(pPolicies) != tt.expectedCount {
+				t.Errorf("LoadFilteredPolicy() loaded %d policies, want %d", len(pPolicies), tt.expectedCount)
+			}
+		})
+	}
+}
+
+func TestLoadFilteredPolicyExclusions(t *testing.T) {
+	tests := []struct {
+		name          string
+		filter        pgxadapter.Filter
+		expectedCount int
+	}{
+		{
+			name:          "exclude_subjects",
+			filter:        pgxadapter.Filter{NotV0: []string{"system", "cron"}},
+			expectedCount: 3,
+		},
+		{
+			name:          "exclude_with_include",
+			filter:        pgxadapter.Filter{Ptype: []string{"p"}, NotV0: []string{"system", "cron"}},
+			expectedCount: 2,
+		},
+		{
+			name:          "rules_without_value_are_kept",
+			filter:        pgxadapter.Filter{NotV2: []string{"read"}},
+			expectedCount: 3,
+		},
+		{
+			name:          "exclude_by_prefix",
+			filter:        pgxadapter.Filter{NotV1: []string{"/internal/"}, Match: map[string]pgxadapter.MatchMode{"v1": pgxadapter.MatchPrefix}},
+			expectedCount: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tableName := fmt.Sprintf("casbin_test_filtered_not_%s", tt.name)
+			conn := setupTestDB(t, tableName)
+
+			adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			policies := [][]string{
+				{"p", "alice", "/api/data1", "read"},
+				{"p", "bob", "/api/data2", "write"},
+				{"p", "system", "/internal/jobs", "read"},
+				{"p", "cron", "/internal/tasks", "write"},
+				{"g", "carol", "admin"},
+			}
+			for _, policy := range policies {
+				if err := adapter.AddPolicy(policy[0], policy[0], policy[1:]); err != nil {
+					t.Fatalf("Failed to insert test policy: %v", err)
+				}
+			}
+
+			m, _ := model.NewModelFromString(TestModelText)
+			if err := adapter.LoadFilteredPolicy(m, tt.filter); err != nil {
+				t.Fatalf("LoadFilteredPolicy() unexpected error: %v", err)
+			}
+
+			pPolicies, _ := m.GetPolicy("p", "p")
+			gPolicies, _ := m.GetPolicy("g", "g")
+			if count := len(pPolicies) + len(gPolicies); count != tt.expectedCount {
+				t.Errorf("LoadFilteredPolicy() loaded %d policies, want %d", count, tt.expectedCount)
+			}
+		})
+	}
+}
+
+func TestLoadFilteredPolicySQLFilter(t *testing.T) {
+	tests := []struct {
+		name          string
+		filter        any
+		expectedCount int
+		wantErr       bool
+	}{
+		{
+			name:          "raw_condition_with_args",
+			filter:        pgxadapter.SQLFilter{Where: "v2::int > ?", Args: []any{5}},
+			expectedCount: 2,
+		},
+		{
+			name:          "sqlizer",
+			filter:        sq.Expr("v2::int BETWEEN ? AND ?", 1, 10),
+			expectedCount: 2,
+		},
+		{
+			name:          "or_stays_within_tenant",
+			filter:        &pgxadapter.SQLFilter{Where: "v0 = ? OR TRUE", Args: []any{"nobody"}},
+			expectedCount: 3,
+		},
+		{
+			name:    "empty_condition",
+			filter:  pgxadapter.SQLFilter{Where: " "},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tableName := fmt.Sprintf("casbin_test_filtered_sql_%s", tt.name)
+			conn := setupTestDB(t, tableName)
+
+			adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName), pgxadapter.WithTenant("acme"))
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+			other, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName), pgxadapter.WithTenant("beta"))
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			if err := adapter.AddPolicies("p", "p", [][]string{{"alice", "data1", "3"}, {"bob", "data2", "7"}, {"carol", "data3", "12"}}); err != nil {
+				t.Fatalf("Failed to insert test policies: %v", err)
+			}
+			if err := other.AddPolicy("p", "p", []string{"dave", "data4", "9"}); err != nil {
+				t.Fatalf("Failed to insert test policy: %v", err)
+			}
+
+			m, _ := model.NewModelFromString(TestModelText)
+			err = adapter.LoadFilteredPolicy(m, tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadFilteredPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if pPolicies, _ := m.GetPolicy("p", "p"); len(pPolicies) != tt.expectedCount {
+				t.Errorf("LoadFilteredPolicy() loaded %d policies, want %d", len(pPolicies), tt.expectedCount)
+			}
+		})
+	}
+}
+
+func TestLoadFilteredPolicyPaging(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  any
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name:   "first_page",
+			filter: pgxadapter.Filter{Ptype: []string{"p"}, OrderBy: []string{"v0"}, Limit: 2},
+			want:   [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}},
+		},
+		{
+			name:   "second_page",
+			filter: pgxadapter.Filter{Ptype: []string{"p"}, OrderBy: []string{"v0"}, Limit: 2, Offset: 2},
+			want:   [][]string{{"carol", "data3", "read"}, {"dave", "data4", "write"}},
+		},
+		{
+			name:   "descending",
+			filter: pgxadapter.Filter{Ptype: []string{"p"}, OrderBy: []string{"v2 DESC", "v0"}, Limit: 1},
+			want:   [][]string{{"bob", "data2", "write"}},
+		},
+		{
+			name:    "unknown_field",
+			filter:  pgxadapter.Filter{OrderBy: []string{"v0; DROP TABLE x"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid_direction",
+			filter:  pgxadapter.Filter{OrderBy: []string{"v0 SIDEWAYS"}},
+			wantErr: true,
+		},
+		{
+			name:    "intersect_filter",
+			filter:  pgxadapter.IntersectFilter{Filters: []pgxadapter.Filter{{Limit: 1}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tableName := fmt.Sprintf("casbin_test_filtered_paging_%s", tt.name)
+			conn := setupTestDB(t, tableName)
+
+			adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			policies := [][]string{
+				{"dave", "data4", "write"},
+				{"bob", "data2", "write"},
+				{"alice", "data1", "read"},
+				{"carol", "data3", "read"},
+			}
+			if err := adapter.AddPolicies("p", "p", policies); err != nil {
+				t.Fatalf("Failed to insert test policies: %v", err)
+			}
+
+			m, _ := model.NewModelFromString(TestModelText)
+			err = adapter.LoadFilteredPolicy(m, tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadFilteredPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if adapter.IsFiltered() {
+					t.Error("IsFiltered() = true after a rejected filter")
+				}
+				return
+			}
+
+			if got, _ := m.GetPolicy("p", "p"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadFilteredPolicy() loaded %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFilteredPolicyMaxLoadRows(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      any
+		load        string
+		fetchSize   int
+		expectError bool
+	}{
+		{
+			name:   "small_filter_within_limit",
+			filter: pgxadapter.Filter{V0: []string{"alice"}},
+			load:   "filtered",
+		},
+		{
+			name:        "broad_filter_over_limit",
+			filter:      pgxadapter.Filter{Ptype: []string{"p"}},
+			load:        "filtered",
+			expectError: true,
+		},
+		{
+			name:        "full_load_over_limit",
+			load:        "full",
+			expectError: true,
+		},
+		{
+			// Each member stays within the limit, their total does not
+			name: "batch_members_over_limit_together",
+			filter: pgxadapter.BatchFilter{Filters: []pgxadapter.Filter{
+				{V0: []string{"alice"}},
+				{V0: []string{"bob", "carol"}},
+			}},
+			load:        "filtered",
+			expectError: true,
+		},
+		{
+			name: "batch_members_over_limit_together_with_cursor",
+			filter: pgxadapter.BatchFilter{Filters: []pgxadapter.Filter{
+				{V0: []string{"alice"}},
+				{V0: []string{"bob", "carol"}},
+			}},
+			load:        "filtered",
+			fetchSize:   1,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tableName := fmt.Sprintf("casbin_test_max_load_rows_%s", tt.name)
+			conn := setupTestDB(t, tableName)
+
+			adapter, err := pgxadapter.NewAdapterWithConn(conn,
+				pgxadapter.WithTableName(tableName),
+				pgxadapter.WithMaxLoadRows(2),
+				pgxadapter.WithLoadFetchSize(tt.fetchSize),
+			)
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			policies := [][]string{
+				{"p", "alice", "data1", "read"},
+				{"p", "alice", "data1", "write"},
+				{"p", "bob", "data2", "read"},
+				{"p", "carol", "data3", "read"},
+			}
+			for _, policy := range policies {
+				if err := adapter.AddPolicy(policy[0], policy[0], policy[1:]); err != nil {
+					t.Fatalf("Failed to insert test policy: %v", err)
+				}
+			}
+
+			m, _ := model.NewModelFromString(TestModelText)
+			if tt.load == "full" {
+				err = adapter.LoadPolicy(m)
+			} else {
+				err = adapter.LoadFilteredPolicy(m, tt.filter)
+			}
+
+			if tt.expectError {
+				if !errors.Is(err, pgxadapter.ErrMaxLoadRows) {
+					t.Errorf("Expected ErrMaxLoadRows, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			pPolicies, _ := m.GetPolicy("p", "p")
+			if len(pPolicies) != 2 {
+				t.Errorf("Loaded %d policies, want 2", len(pPolicies))
+			}
+		})
+	}
+}
+
 func TestIsFiltered(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -360,3 +1003,61 @@ func TestIsFiltered(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadFilteredPolicyWithFetchSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		fetchSize int
+		maxRows   int
+		want      int
+		wantErr   bool
+	}{
+		{name: "several_batches", fetchSize: 3, want: 10},
+		{name: "exact_batches", fetchSize: 5, want: 10},
+		{name: "limit_across_batches", fetchSize: 3, maxRows: 7, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tableName := fmt.Sprintf("casbin_test_filtered_fetch_%s", tt.name)
+			conn := setupTestDB(t, tableName)
+
+			adapter, err := pgxadapter.NewAdapterWithConn(conn,
+				pgxadapter.WithTableName(tableName),
+				pgxadapter.WithLoadFetchSize(tt.fetchSize),
+				pgxadapter.WithMaxLoadRows(tt.maxRows),
+			)
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			for i := range 10 {
+				if err := adapter.AddPolicy("p", "p", []string{fmt.Sprintf("user%d", i), "data", "read"}); err != nil {
+					t.Fatalf("Failed to insert test policy: %v", err)
+				}
+			}
+			if err := adapter.AddPolicy("p", "p", []string{"other", "data", "write"}); err != nil {
+				t.Fatalf("Failed to insert test policy: %v", err)
+			}
+
+			m, _ := model.NewModelFromString(TestModelText)
+			err = adapter.LoadFilteredPolicy(m, pgxadapter.Filter{V2: []string{"read"}})
+			if tt.wantErr {
+				if !errors.Is(err, pgxadapter.ErrMaxLoadRows) {
+					t.Errorf("Expected ErrMaxLoadRows, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadFilteredPolicy() unexpected error: %v", err)
+			}
+
+			policies, _ := m.GetPolicy("p", "p")
+			if len(policies) != tt.want {
+				t.Errorf("LoadFilteredPolicy() loaded %d policies, want %d", len(policies), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package pgxadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ruleHashColumn is the generated column holding the rule hash of WithHashUniqueIndex
+const ruleHashColumn = "rule_hash"
+
+// WithHashUniqueIndex keeps rules unique through a stored generated column
+// holding the SHA-256 hash of ptype and the value columns, indexed by a plain
+// unique index, instead of the expression index over every COALESCEd column,
+// which is slow to maintain on very large tables. As with the expression
+// index, NULL and empty values are the same. Existing tables get the column,
+// which rewrites the table, and their expression index is replaced by the new
+// one; without this option the hash column is dropped again and the expression
+// index restored. Requires PostgreSQL 12 or later and cannot be combined with
+// WithExistingUniqueConstraint or WithArrayColumn.
+func WithHashUniqueIndex() Option {
+	return func(a *PgxAdapter) {
+		a.hashUniqueIndex = true
+	}
+}
+
+// validateHashUniqueIndex rejects the options the hash column cannot support
+func (a *PgxAdapter) validateHashUniqueIndex() error {
+	if !a.hashUniqueIndex {
+		return nil
+	}
+	if a.uniqueConstraint != "" {
+		return errors.New("WithHashUniqueIndex and WithExistingUniqueConstraint cannot be combined")
+	}
+	if len(a.arrayColumns) > 0 {
+		return errors.New("WithHashUniqueIndex cannot be combined with array columns")
+	}
+	return nil
+}
+
+// ruleHashDDL returns the definition of the hash column, if enabled
+func (a *PgxAdapter) ruleHashDDL() string {
+	if !a.hashUniqueIndex {
+		return ""
+	}
+	return ",\n\t\t" + a.ruleHashColumnDDL()
+}
+
+// ruleHashColumnDDL returns the definition of the hash column
+func (a *PgxAdapter) ruleHashColumnDDL() string {
+	return ruleHashColumn + " BYTEA GENERATED ALWAYS AS (" + a.ruleHashExpr() + ") STORED"
+}
+
+// ruleHashExpr returns the expression hashing the rule columns. Each value is
+// prefixed with its length, so values containing the separator cannot collide.
+// Generated columns only accept immutable functions, which rules out
+// convert_to; decode with the escape format returns the bytes of the text
+// once its backslashes are doubled.
+func (a *PgxAdapter) ruleHashExpr() string {
+	cols := []string{a.column("ptype")}
+	for i := range a.valueColumnTotal() {
+		cols = append(cols, a.column(valueColumn(i)))
+	}
+	if a.useEftColumn {
+		cols = append(cols, eftColumn)
+	}
+	if a.useTenantColumn {
+		cols = append(cols, tenantColumn)
+	}
+
+	parts := make([]string, len(cols))
+	for i, col := range cols {
+		value := "COALESCE(" + col + ",'')"
+		parts[i] = "length(" + value + ")::text || ':' || " + value
+	}
+	return `sha256(decode(replace(` + strings.Join(parts, " || ',' || ") + `, E'\\', E'\\\\'), 'escape'))`
+}
+
+// dropRuleHash drops the hash column of a table created with
+// WithHashUniqueIndex when the option is no longer set. Its unique index goes
+// with it and is recreated over the rule columns in the same statement. The
+// column itself is added by alterColumns, together with any missing value
+// column it covers.
+func (a *PgxAdapter) dropRuleHash(ctx context.Context) error {
+	if a.hashUniqueIndex {
+		return nil
+	}
+
+	var exists bool
+	if err := a.db.QueryRow(ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM pg_attribute
+			WHERE attrelid = to_regclass($1) AND attname = $2 AND attgenerated = 's' AND NOT attisdropped
+		)`,
+		a.quotedTableName(), ruleHashColumn).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check hash column: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+
+	// Sent as one query string, so the table is never left without a unique index
+	ddl := "ALTER TABLE " + a.quotedTableName() + " DROP COLUMN " + ruleHashColumn + ";\n\t\t" + a.uniqueIndexDDL()
+	if _, err := a.db.Exec(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to drop hash column: %w", err)
+	}
+	return nil
+}
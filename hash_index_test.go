@@ -0,0 +1,88 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithHashUniqueIndex(t *testing.T) {
+	tableName := "casbin_test_hash_index"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	indexDef := func() string {
+		t.Helper()
+		var def string
+		if err := pool.QueryRow(ctx,
+			"SELECT indexdef FROM pg_indexes WHERE tablename = $1 AND indexname = $2",
+			tableName, "idx_"+tableName).Scan(&def); err != nil {
+			t.Fatalf("Failed to query index: %v", err)
+		}
+		return def
+	}
+
+	// Start from a table with the expression index and migrate it
+	legacy, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	if err := legacy.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithHashUniqueIndex(),
+		pgxadapter.WithIdempotentWrites(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	if def := indexDef(); !strings.Contains(def, "(rule_hash)") {
+		t.Errorf("Expected the unique index over rule_hash, got %s", def)
+	}
+
+	tests := []struct {
+		name string
+		rule []string
+		want int
+	}{
+		{name: "existing rule", rule: []string{"alice", "data1", "read"}, want: 1},
+		{name: "empty value equals missing value", rule: []string{"alice", "data1", "read", ""}, want: 1},
+		{name: "separator in value", rule: []string{"alice", "data1,read"}, want: 2},
+		{name: "backslash in value", rule: []string{`alice\`, "data1", "read"}, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := adapter.AddPolicyCtx(ctx, "p", "p", tt.rule); err != nil {
+				t.Fatalf("Failed to add policy: %v", err)
+			}
+			var count int
+			if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+tableName).Scan(&count); err != nil {
+				t.Fatalf("Failed to count rules: %v", err)
+			}
+			if count != tt.want {
+				t.Errorf("Expected %d rules, got %d", tt.want, count)
+			}
+		})
+	}
+
+	// Without the option the hash column goes away and the expression index is back
+	if _, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName)); err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	if def := indexDef(); strings.Contains(def, "rule_hash") || !strings.Contains(def, "COALESCE") {
+		t.Errorf("Expected the expression index to be restored, got %s", def)
+	}
+
+	if _, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithHashUniqueIndex(),
+		pgxadapter.WithArrayColumn("v1"),
+	); err == nil {
+		t.Error("Expected an error combining WithHashUniqueIndex and an array column")
+	}
+}
@@ -0,0 +1,55 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithIdentityPrimaryKey creates the id column as BIGINT GENERATED ALWAYS AS
+// IDENTITY instead of SERIAL. Identity columns own their sequence, so dumps
+// restore without sequence ownership issues. The SERIAL id of an existing
+// table is converted when the adapter creates its table: the column becomes
+// BIGINT, its sequence is dropped and the identity continues after the
+// highest id.
+func WithIdentityPrimaryKey() Option {
+	return func(a *PgxAdapter) {
+		a.identityPrimaryKey = true
+	}
+}
+
+// migrateIdentity converts a SERIAL id column into an identity column
+func (a *PgxAdapter) migrateIdentity(ctx context.Context) error {
+	if !a.identityPrimaryKey {
+		return nil
+	}
+
+	var identity string
+	var sequence *string
+	if err := a.db.QueryRow(ctx,
+		`SELECT attidentity::text, pg_get_serial_sequence($1, 'id')
+		FROM pg_attribute WHERE attrelid = to_regclass($1) AND attname = 'id'`,
+		a.quotedTableName()).Scan(&identity, &sequence); err != nil {
+		return fmt.Errorf("failed to query id column: %w", err)
+	}
+	if identity != "" {
+		return nil
+	}
+
+	// Sent as one query string, so the statements run in a single implicit
+	// transaction and the identity restarts after the highest id while the
+	// table is locked
+	ddl := "ALTER TABLE " + a.quotedTableName() + " ALTER COLUMN id DROP DEFAULT"
+	if sequence != nil {
+		// The name is returned quoted where needed
+		ddl += ";\n\t\tDROP SEQUENCE " + *sequence
+	}
+	ddl += ";\n\t\tALTER TABLE " + a.quotedTableName() +
+		" ALTER COLUMN id TYPE BIGINT, ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY" +
+		";\n\t\tSELECT setval(pg_get_serial_sequence(" + quoteLiteral(a.quotedTableName()) + ", 'id'), COALESCE(MAX(id), 0) + 1, false) FROM " +
+		a.quotedTableName()
+
+	if _, err := a.db.Exec(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to convert id column to identity: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"testing"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithIdentityPrimaryKey(t *testing.T) {
+	tableName := "casbin_test_identity"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	// An existing SERIAL table with rows
+	serial, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	lastID, err := serial.AddPolicyReturningID(ctx, "p", "p", []string{"alice", "data1", "read"})
+	if err != nil {
+		t.Fatalf("AddPolicyReturningID() unexpected error: %v", err)
+	}
+
+	// Converted when opened with the option, and left alone afterwards
+	for range 2 {
+		if _, err := pgxadapter.NewAdapterWithPool(pool,
+			pgxadapter.WithTableName(tableName),
+			pgxadapter.WithIdentityPrimaryKey(),
+		); err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+	}
+
+	var identity, typ string
+	if err := pool.QueryRow(ctx,
+		"SELECT attidentity::text, format_type(atttypid, atttypmod) FROM pg_attribute WHERE attrelid = $1::regclass AND attname = 'id'",
+		tableName).Scan(&identity, &typ); err != nil {
+		t.Fatalf("Failed to query id column: %v", err)
+	}
+	if identity != "a" || typ != "bigint" {
+		t.Errorf("Expected a BIGINT GENERATED ALWAYS identity, got %q %q", typ, identity)
+	}
+
+	var sequences int
+	if err := pool.QueryRow(ctx,
+		"SELECT COUNT(*) FROM pg_class WHERE relkind = 'S' AND relname = $1", tableName+"_id_seq",
+	).Scan(&sequences); err != nil {
+		t.Fatalf("Failed to query sequences: %v", err)
+	}
+	if sequences != 0 {
+		t.Error("Expected the SERIAL sequence to be dropped")
+	}
+
+	id, err := serial.AddPolicyReturningID(ctx, "p", "p", []string{"bob", "data2", "write"})
+	if err != nil {
+		t.Fatalf("AddPolicyReturningID() unexpected error: %v", err)
+	}
+	if id <= lastID {
+		t.Errorf("Expected the identity to continue after %d, got %d", lastID, id)
+	}
+}
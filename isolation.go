@@ -0,0 +1,47 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// WithIsolationLevel runs the transactions of policy writes, such as
+// SavePolicy, UpdatePolicies and UpdateFilteredPolicies, at level instead of
+// the server default. Under pgx.RepeatableRead and pgx.Serializable concurrent
+// writers can fail with serialization errors that the caller has to retry.
+// Writes made in a caller's transaction keep that transaction's level.
+func WithIsolationLevel(level pgx.TxIsoLevel) Option {
+	return func(a *PgxAdapter) {
+		a.isoLevel = level
+	}
+}
+
+// txBeginner is implemented by connections and pools, but not transactions
+type txBeginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// beginWrite begins the transaction of a write at the configured isolation level
+func (a *PgxAdapter) beginWrite(ctx context.Context) (pgx.Tx, error) {
+	db := a.dbFrom(ctx)
+	if a.isoLevel == "" {
+		return db.Begin(ctx)
+	}
+
+	// A transaction nests as a savepoint, which inherits its isolation level
+	if b, ok := db.(txBeginner); ok {
+		return b.BeginTx(ctx, pgx.TxOptions{IsoLevel: a.isoLevel})
+	}
+	return db.Begin(ctx)
+}
+
+// validateIsolationLevel checks that the isolation level is one Postgres knows
+func (a *PgxAdapter) validateIsolationLevel() error {
+	switch a.isoLevel {
+	case "", pgx.Serializable, pgx.RepeatableRead, pgx.ReadCommitted, pgx.ReadUncommitted:
+		return nil
+	}
+	return fmt.Errorf("invalid isolation level %q", a.isoLevel)
+}
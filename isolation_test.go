@@ -0,0 +1,63 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithIsolationLevel(t *testing.T) {
+	tableName := "casbin_test_isolation_level"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		level pgx.TxIsoLevel
+		want  string
+	}{
+		{name: "default", want: "read committed"},
+		{name: "repeatable_read", level: pgx.RepeatableRead, want: "repeatable read"},
+		{name: "serializable", level: pgx.Serializable, want: "serializable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, err := pgxadapter.NewAdapterWithPool(pool,
+				pgxadapter.WithTableName(tableName),
+				pgxadapter.WithIsolationLevel(tt.level),
+			)
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			var got string
+			err = adapter.Transaction(ctx, func(tx *pgxadapter.PgxAdapter) error {
+				return tx.GetDB().QueryRow(ctx, "SELECT current_setting('transaction_isolation')").Scan(&got)
+			})
+			if err != nil {
+				t.Fatalf("Transaction() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("transaction_isolation = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithIsolationLevelInvalid(t *testing.T) {
+	tableName := "casbin_test_isolation_level_invalid"
+	conn := setupTestDB(t, tableName)
+
+	_, err := pgxadapter.NewAdapterWithConn(conn,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithIsolationLevel("chaotic"),
+	)
+	if err == nil || !strings.Contains(err.Error(), "invalid isolation level") {
+		t.Errorf("Expected invalid isolation level error, got %v", err)
+	}
+}
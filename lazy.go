@@ -0,0 +1,54 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithLazyInit defers connecting, pinging and creating the table until the
+// first operation, so constructors return without any round trip. This keeps
+// cold starts cheap in serverless environments. Initialization runs once; its
+// error is returned by the first operation and every operation after it.
+// GetConn returns nil until a NewAdapter created connection has been established.
+func WithLazyInit() Option {
+	return func(a *PgxAdapter) {
+		a.lazyInit = true
+	}
+}
+
+// ensureInit runs the deferred initialization of a lazy adapter once
+func (a *PgxAdapter) ensureInit(ctx context.Context) error {
+	if !a.lazyInit {
+		return nil
+	}
+
+	a.initOnce.Do(func() {
+		a.initErr = a.initialize(ctx)
+	})
+	return a.initErr
+}
+
+// initialize connects if needed, pings the database and creates the table
+func (a *PgxAdapter) initialize(ctx context.Context) error {
+	if a.connect != nil {
+		if err := a.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	if a.pool != nil {
+		if err := a.pool.Ping(ctx); err != nil {
+			return fmt.Errorf("failed to ping database: %w", err)
+		}
+	} else if a.conn != nil {
+		if err := a.conn.Ping(ctx); err != nil {
+			return fmt.Errorf("failed to ping database: %w", err)
+		}
+	}
+
+	if err := a.createTable(ctx); err != nil {
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithAdvisoryLock serializes SavePolicy and table creation across processes
+// with a transaction level advisory lock, so pods starting or saving at the
+// same time do not race on the index creation or overwrite each other's saves
+// halfway. The lock is taken on GetAdvisoryLockKey: the key set with
+// WithAdvisoryLockKey, else one derived from WithNamespace or the table name.
+func WithAdvisoryLock() Option {
+	return func(a *PgxAdapter) {
+		a.advisoryLock = true
+	}
+}
+
+// lockTx takes the advisory lock for the rest of tx, if enabled
+func (a *PgxAdapter) lockTx(ctx context.Context, tx DB) error {
+	if !a.advisoryLock {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", a.GetAdvisoryLockKey()); err != nil {
+		return fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+	return nil
+}
+
+// withAdvisoryLock runs fn while holding the advisory lock, if enabled. The
+// lock is held by a transaction that only ends after fn, so fn's statements
+// run outside of it unless the adapter has a single connection.
+func (a *PgxAdapter) withAdvisoryLock(ctx context.Context, fn func() error) error {
+	if !a.advisoryLock {
+		return fn()
+	}
+
+	tx, err := a.dbFrom(ctx).Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := a.lockTx(ctx, tx); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
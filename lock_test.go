@@ -0,0 +1,120 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3/model"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithAdvisoryLockConcurrentCreate(t *testing.T) {
+	tableName := "casbin_test_advisory_lock_create"
+	pool := setupTestPool(t, tableName)
+	if _, err := pool.Exec(context.Background(), "DROP TABLE IF EXISTS "+tableName+" CASCADE"); err != nil {
+		t.Fatalf("Failed to drop table: %v", err)
+	}
+
+	const pods = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, pods)
+	for range pods {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := pgxadapter.NewAdapterWithPool(pool,
+				pgxadapter.WithTableName(tableName),
+				pgxadapter.WithAdvisoryLock(),
+			)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Concurrent adapter creation failed: %v", err)
+		}
+	}
+}
+
+func TestWithAdvisoryLockSavePolicy(t *testing.T) {
+	tableName := "casbin_test_advisory_lock_save"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithAdvisoryLock(),
+		pgxadapter.WithAdvisoryLockKey(4343),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	if got := adapter.GetAdvisoryLockKey(); got != 4343 {
+		t.Errorf("GetAdvisoryLockKey() = %v, want 4343", got)
+	}
+
+	// Another pod holds the lock
+	holder, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer holder.Rollback(ctx)
+	if _, err := holder.Exec(ctx, "SELECT pg_advisory_xact_lock(4343)"); err != nil {
+		t.Fatalf("Failed to take lock: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(TestModelText)
+	m.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+
+	saveCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	if err := adapter.SavePolicyCtx(saveCtx, m); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected SavePolicy to wait for the lock, got %v", err)
+	}
+
+	if err := holder.Rollback(ctx); err != nil {
+		t.Fatalf("Failed to release lock: %v", err)
+	}
+	if err := adapter.SavePolicyCtx(ctx, m); err != nil {
+		t.Errorf("Failed to save policy after the lock was released: %v", err)
+	}
+}
+
+func TestWithAdvisoryLockNamespaceKey(t *testing.T) {
+	tableName := "casbin_test_advisory_lock_namespace"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithNamespace("acme"),
+		pgxadapter.WithAdvisoryLock(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	// The lock is taken on the key of the namespace
+	holder, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer holder.Rollback(ctx)
+	if _, err := holder.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", adapter.GetAdvisoryLockKey()); err != nil {
+		t.Fatalf("Failed to take lock: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(TestModelText)
+	saveCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	if err := adapter.SavePolicyCtx(saveCtx, m); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected SavePolicy to wait for the namespace lock, got %v", err)
+	}
+}
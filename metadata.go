@@ -0,0 +1,120 @@
+package pgxadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// metadataColumn is the name of the JSONB column enabled by WithMetadataColumn
+const metadataColumn = "metadata"
+
+// WithMetadataColumn adds a nullable JSONB metadata column for attaching data
+// such as who granted a rule and why. Metadata is never loaded into the model.
+func WithMetadataColumn() Option {
+	return func(a *PgxAdapter) {
+		a.useMetadataColumn = true
+	}
+}
+
+// metadataColumnDDL returns the column definition of the metadata column, if enabled
+func (a *PgxAdapter) metadataColumnDDL() string {
+	if !a.useMetadataColumn {
+		return ""
+	}
+	return ",\n\t\t" + metadataColumn + " JSONB NULL"
+}
+
+// AddPolicyWithMetadata adds a policy rule to the storage together with meta marshaled as JSON.
+// Requires WithMetadataColumn.
+func (a *PgxAdapter) AddPolicyWithMetadata(ctx context.Context, ptype string, rule []string, meta any) error {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !a.useMetadataColumn {
+		return fmt.Errorf("metadata column is not enabled")
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	sql, args, err := a.psql.
+		Insert(a.quotedTableName()).
+		Columns(append(a.insertColumns(), metadataColumn)...).
+		Values(append(a.insertValues(ctx, ptype, rule), data)...).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	err = a.auditTx(ctx, func(db DB) error {
+		if _, err := db.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+		return a.writeAudit(ctx, db, AuditOpAdd, []auditChange{{ptype: ptype, newRule: rule}})
+	})
+
+	if err != nil {
+		// Check if it's a unique constraint violation
+		if strings.Contains(err.Error(), "duplicate key") {
+			return fmt.Errorf("policy already exists")
+		}
+		return fmt.Errorf("failed to add policy: %w", mapWriteError(err))
+	}
+
+	return nil
+}
+
+// GetMetadata returns the metadata stored with a policy rule, or nil if it has none.
+// Requires WithMetadataColumn.
+func (a *PgxAdapter) GetMetadata(ctx context.Context, ptype string, rule []string) (json.RawMessage, error) {
+	ctx, cancel := a.readContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return nil, err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !a.useMetadataColumn {
+		return nil, fmt.Errorf("metadata column is not enabled")
+	}
+
+	sql, args, err := a.selectRules(ctx, metadataColumn).
+		Where(sq.Eq{a.column("ptype"): ptype}).
+		Where(a.ruleEq(ptype, rule)).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var meta json.RawMessage
+	if err := a.dbFrom(ctx).QueryRow(ctx, sql, args...).Scan(&meta); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("policy not found")
+		}
+		return nil, fmt.Errorf("failed to query metadata: %w", err)
+	}
+
+	return meta, nil
+}
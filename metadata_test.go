@@ -0,0 +1,83 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/casbin/casbin/v3/model"
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestPolicyMetadata(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tableName := "casbin_test_metadata"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithMetadataColumn(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	type grantMeta struct {
+		GrantedBy string `json:"granted_by"`
+		Ticket    string `json:"ticket"`
+	}
+
+	rule := []string{"alice", "data1", "read"}
+	meta := grantMeta{GrantedBy: "bob", Ticket: "SEC-42"}
+	if err := adapter.AddPolicyWithMetadata(ctx, "p", rule, meta); err != nil {
+		t.Fatalf("AddPolicyWithMetadata() unexpected error: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("AddPolicyCtx() unexpected error: %v", err)
+	}
+
+	raw, err := adapter.GetMetadata(ctx, "p", rule)
+	if err != nil {
+		t.Fatalf("GetMetadata() unexpected error: %v", err)
+	}
+
+	var got grantMeta
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("Failed to unmarshal metadata %s: %v", raw, err)
+	}
+	if got != meta {
+		t.Errorf("GetMetadata() = %+v, want %+v", got, meta)
+	}
+
+	// Rules added without metadata have none
+	raw, err = adapter.GetMetadata(ctx, "p", []string{"bob", "data2", "write"})
+	if err != nil {
+		t.Fatalf("GetMetadata() unexpected error: %v", err)
+	}
+	if raw != nil {
+		t.Errorf("GetMetadata() = %s, want nil", raw)
+	}
+
+	if _, err := adapter.GetMetadata(ctx, "p", []string{"carol", "data3", "read"}); err == nil {
+		t.Errorf("GetMetadata() expected error for missing policy but got none")
+	}
+
+	// Loads must not surface metadata into the model
+	m, _ := model.NewModelFromString(TestModelText)
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("LoadPolicyCtx() unexpected error: %v", err)
+	}
+
+	policies, _ := m.GetPolicy("p", "p")
+	if len(policies) != 2 {
+		t.Fatalf("LoadPolicyCtx() loaded %d policies, want 2", len(policies))
+	}
+	for _, policy := range policies {
+		if len(policy) != 3 {
+			t.Errorf("LoadPolicyCtx() loaded %v, want exactly 3 values", policy)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MigrateFromTable copies rules from a compatibly shaped source table, such as
+// the casbin_rule table created by casbin/gorm-adapter, into the adapter's table.
+// The source table must have ptype and v0..v5 columns; any other columns are ignored.
+// Empty strings are stored as NULL and rules that already exist are skipped.
+// It returns the number of rows migrated.
+func (a *PgxAdapter) MigrateFromTable(ctx context.Context, sourceTable string) (int64, error) {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return 0, err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := a.validateSourceTable(ctx, sourceTable); err != nil {
+		return 0, err
+	}
+
+	sourceColumns := make([]string, len(ruleColumns))
+	sourceColumns[0] = "ptype::text"
+	for i := range len(ruleColumns) - 1 {
+		col := valueColumn(i)
+		sourceColumns[i+1] = "NULLIF(" + col + "::text, '')"
+		if a.emptyValue(col) != nil {
+			sourceColumns[i+1] = "COALESCE(" + col + "::text, '')"
+		}
+		if a.isArrayColumn(col) {
+			sourceColumns[i+1] = "string_to_array(" + sourceColumns[i+1] + ", " + quoteLiteral(a.arrayDelimiter) + ")"
+		}
+	}
+
+	columns := a.columns(ruleColumns)
+	if a.useTenantColumn {
+		sourceColumns = append(sourceColumns, quoteLiteral(a.tenantOf(ctx)))
+		columns = append(columns, tenantColumn)
+	}
+
+	selectBuilder := a.psql.
+		Select(sourceColumns...).
+		From(pgx.Identifier{sourceTable}.Sanitize()).
+		OrderBy("ptype", "v0", "v1", "v2", "v3", "v4", "v5")
+	columns, selectBuilder = a.stampSelect(columns, selectBuilder)
+
+	sql, args, err := a.psql.
+		Insert(a.quotedTableName()).
+		Columns(columns...).
+		Select(selectBuilder).
+		Suffix(a.onConflictDoNothing()).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build migrate query: %w", err)
+	}
+
+	result, err := a.dbFrom(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to migrate policies: %w", err)
+	}
+
+	a.maybeAnalyze(ctx, result.RowsAffected())
+
+	return result.RowsAffected(), nil
+}
+
+// validateSourceTable checks that the source table has the columns expected by MigrateFromTable
+func (a *PgxAdapter) validateSourceTable(ctx context.Context, sourceTable string) error {
+	rows, err := a.dbFrom(ctx).Query(ctx,
+		`SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1`,
+		sourceTable)
+	if err != nil {
+		return fmt.Errorf("failed to query source table columns: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[string]bool)
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return fmt.Errorf("failed to scan column name: %w", err)
+		}
+		found[column] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if len(found) == 0 {
+		return fmt.Errorf("source table %s does not exist", sourceTable)
+	}
+
+	var missing []string
+	for _, column := range ruleColumns {
+		if !found[column] {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("source table %s is missing columns: %s", sourceTable, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestMigrateFromTable(t *testing.T) {
+	tests := []struct {
+		name             string
+		sourceDDL        string
+		sourceRows       [][]string
+		existingPolicies [][]string
+		expectedMigrated int64
+		expectedCount    int
+		wantErr          bool
+	}{
+		{
+			name: "gorm_shaped_source",
+			sourceDDL: `(
+				id BIGSERIAL PRIMARY KEY,
+				ptype VARCHAR(100),
+				v0 VARCHAR(100), v1 VARCHAR(100), v2 VARCHAR(100),
+				v3 VARCHAR(100), v4 VARCHAR(100), v5 VARCHAR(100)
+			)`,
+			sourceRows: [][]string{
+				{"p", "alice", "data1", "read", "", "", ""},
+				{"p", "bob", "data2", "write", "", "", ""},
+				{"g", "alice", "admin", "", "", "", ""},
+			},
+			expectedMigrated: 3,
+			expectedCount:    3,
+		},
+		{
+			name: "skips_existing_rules",
+			sourceDDL: `(
+				id BIGSERIAL PRIMARY KEY,
+				ptype TEXT,
+				v0 TEXT, v1 TEXT, v2 TEXT, v3 TEXT, v4 TEXT, v5 TEXT
+			)`,
+			sourceRows: [][]string{
+				{"p", "alice", "data1", "read", "", "", ""},
+				{"p", "bob", "data2", "write", "", "", ""},
+			},
+			existingPolicies: [][]string{
+				{"alice", "data1", "read"},
+			},
+			expectedMigrated: 1,
+			expectedCount:    2,
+		},
+		{
+			name:      "missing_columns",
+			sourceDDL: `(id BIGSERIAL PRIMARY KEY, ptype TEXT, v0 TEXT)`,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			tableName := fmt.Sprintf("casbin_test_migrate_%s", tt.name)
+			sourceTable := fmt.Sprintf("casbin_test_migrate_source_%s", tt.name)
+			conn := setupTestDB(t, tableName)
+
+			quotedSourceTable := pgx.Identifier{sourceTable}.Sanitize()
+			_, _ = conn.Exec(ctx, "DROP TABLE IF EXISTS "+quotedSourceTable)
+			if _, err := conn.Exec(ctx, "CREATE TABLE "+quotedSourceTable+" "+tt.sourceDDL); err != nil {
+				t.Fatalf("Failed to create source table: %v", err)
+			}
+			t.Cleanup(func() {
+				_, _ = conn.Exec(ctx, "DROP TABLE IF EXISTS "+quotedSourceTable)
+			})
+
+			for _, row := range tt.sourceRows {
+				_, err := conn.Exec(ctx,
+					"INSERT INTO "+quotedSourceTable+" (ptype, v0, v1, v2, v3, v4, v5) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+					row[0], row[1], row[2], row[3], row[4], row[5], row[6])
+				if err != nil {
+					t.Fatalf("Failed to insert source row: %v", err)
+				}
+			}
+
+			adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			for _, rule := range tt.existingPolicies {
+				if err := adapter.AddPolicyCtx(ctx, "p", "p", rule); err != nil {
+					t.Fatalf("Failed to add existing policy: %v", err)
+				}
+			}
+
+			migrated, err := adapter.MigrateFromTable(ctx, sourceTable)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("MigrateFromTable() expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("MigrateFromTable() unexpected error: %v", err)
+			}
+
+			if migrated != tt.expectedMigrated {
+				t.Errorf("MigrateFromTable() migrated %d rows, want %d", migrated, tt.expectedMigrated)
+			}
+
+			var count int
+			quotedTableName := pgx.Identifier{tableName}.Sanitize()
+			err = conn.QueryRow(ctx, "SELECT COUNT(*) FROM "+quotedTableName).Scan(&count)
+			if err != nil {
+				t.Fatalf("Failed to count policies: %v", err)
+			}
+
+			if count != tt.expectedCount {
+				t.Errorf("MigrateFromTable() table has %d policies, want %d", count, tt.expectedCount)
+			}
+
+			var emptyValues int
+			err = conn.QueryRow(ctx, "SELECT COUNT(*) FROM "+quotedTableName+" WHERE v3 = ''").Scan(&emptyValues)
+			if err != nil {
+				t.Fatalf("Failed to count empty values: %v", err)
+			}
+
+			if emptyValues != 0 {
+				t.Errorf("MigrateFromTable() stored %d empty strings, want NULL", emptyValues)
+			}
+		})
+	}
+}
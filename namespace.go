@@ -0,0 +1,133 @@
+package pgxadapter
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+)
+
+// defaultNotifyChannel is the NOTIFY channel used when no namespace or channel is configured
+const defaultNotifyChannel = "casbin_change"
+
+// maxChannelLength is the longest identifier Postgres keeps; LISTEN silently
+// truncates longer channel names while pg_notify rejects them
+const maxChannelLength = 63
+
+// minTenantSuffixLength is the shortest tenant suffix a channel must leave room for
+const minTenantSuffixLength = 8
+
+// WithNamespace namespaces the adapter's shared database resources so several
+// services can run the adapter against one database without colliding. It
+// derives the NOTIFY channel (<ns>_casbin_change) and the advisory lock key
+// (a hash of ns). WithNotifyChannel and WithAdvisoryLockKey take precedence.
+func WithNamespace(ns string) Option {
+	return func(a *PgxAdapter) {
+		a.namespace = ns
+	}
+}
+
+// WithNotifyChannel sets the NOTIFY channel explicitly, overriding WithNamespace.
+// Adapters on different tables in one database share the default channel, so
+// give each its own channel to keep their watchers from reloading on each
+// other's changes. Tenants get channels of their own, see GetNotifyChannel.
+func WithNotifyChannel(channel string) Option {
+	return func(a *PgxAdapter) {
+		a.notifyChannel = channel
+	}
+}
+
+// WithAdvisoryLockKey sets the advisory lock key explicitly, overriding WithNamespace.
+// It only chooses the key; locking is enabled with WithAdvisoryLock.
+func WithAdvisoryLockKey(key int64) Option {
+	return func(a *PgxAdapter) {
+		a.advisoryLockKey = key
+		a.hasAdvisoryLockKey = true
+	}
+}
+
+// GetNamespace returns the namespace used by the adapter
+func (a *PgxAdapter) GetNamespace() string {
+	return a.namespace
+}
+
+// GetNotifyChannel returns the NOTIFY channel used by the adapter. With
+// WithTenantColumn every tenant has a channel of its own, the adapter's
+// channel suffixed with _<tenant>, so watchers only hear about changes to
+// their tenant's rules; this returns the channel of the tenant set with
+// WithTenant. The empty tenant keeps the unsuffixed channel, and tenants too
+// long for a channel name are suffixed with a prefix of their md5 instead.
+func (a *PgxAdapter) GetNotifyChannel() string {
+	return a.tenantNotifyChannel(a.tenant)
+}
+
+// channelOf returns the NOTIFY channel of the tenant of a call
+func (a *PgxAdapter) channelOf(ctx context.Context) string {
+	return a.tenantNotifyChannel(a.tenantOf(ctx))
+}
+
+// tenantNotifyChannel returns the NOTIFY channel of tenant. The change trigger
+// derives it in SQL the same way, see tenantNotifyChannelExpr.
+func (a *PgxAdapter) tenantNotifyChannel(tenant string) string {
+	if !a.useTenantColumn || tenant == "" {
+		return a.baseNotifyChannel()
+	}
+
+	prefix := a.baseNotifyChannel() + "_"
+	if keep := maxChannelLength - len(prefix); len(tenant) > keep {
+		sum := md5.Sum([]byte(tenant))
+		tenant = hex.EncodeToString(sum[:])[:min(keep, 2*md5.Size)]
+	}
+	return prefix + tenant
+}
+
+// tenantNotifyChannelExpr returns the SQL expression computing the NOTIFY
+// channel of the tenant held by the expression tenant
+func (a *PgxAdapter) tenantNotifyChannelExpr(tenant string) string {
+	prefix := quoteLiteral(a.baseNotifyChannel() + "_")
+	keep := fmt.Sprint(maxChannelLength - len(a.baseNotifyChannel()) - 1)
+	return `CASE WHEN ` + tenant + ` = '' THEN ` + quoteLiteral(a.baseNotifyChannel()) +
+		` WHEN octet_length(` + tenant + `) <= ` + keep + ` THEN ` + prefix + ` || ` + tenant +
+		` ELSE ` + prefix + ` || left(md5(` + tenant + `), ` + keep + `) END`
+}
+
+// baseNotifyChannel returns the channel set with WithNotifyChannel or derived from WithNamespace
+func (a *PgxAdapter) baseNotifyChannel() string {
+	if a.notifyChannel != "" {
+		return a.notifyChannel
+	}
+	if a.namespace != "" {
+		return a.namespace + "_" + defaultNotifyChannel
+	}
+	return defaultNotifyChannel
+}
+
+// validateNotifyChannel checks that the NOTIFY channel is a usable identifier
+func (a *PgxAdapter) validateNotifyChannel() error {
+	channel := a.baseNotifyChannel()
+	if len(channel) > maxChannelLength {
+		return fmt.Errorf("invalid notify channel %q: longer than %d bytes", channel, maxChannelLength)
+	}
+	if a.useTenantColumn && len(channel)+1+minTenantSuffixLength > maxChannelLength {
+		return fmt.Errorf("invalid notify channel %q: too long to suffix with a tenant", channel)
+	}
+	return nil
+}
+
+// GetAdvisoryLockKey returns the advisory lock key used by the adapter.
+// Without a namespace the key is derived from the table name.
+func (a *PgxAdapter) GetAdvisoryLockKey() int64 {
+	if a.hasAdvisoryLockKey {
+		return a.advisoryLockKey
+	}
+
+	source := a.namespace
+	if source == "" {
+		source = a.tableName
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(source))
+	return int64(h.Sum64())
+}
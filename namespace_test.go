@@ -0,0 +1,139 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithNamespace(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            []pgxadapter.Option
+		expectedChannel string
+		expectedLockKey int64
+	}{
+		{
+			name:            "default_channel",
+			expectedChannel: "casbin_change",
+		},
+		{
+			name:            "namespace_billing",
+			opts:            []pgxadapter.Option{pgxadapter.WithNamespace("billing")},
+			expectedChannel: "billing_casbin_change",
+		},
+		{
+			name:            "namespace_orders",
+			opts:            []pgxadapter.Option{pgxadapter.WithNamespace("orders")},
+			expectedChannel: "orders_casbin_change",
+		},
+		{
+			name: "explicit_overrides",
+			opts: []pgxadapter.Option{
+				pgxadapter.WithNamespace("billing"),
+				pgxadapter.WithNotifyChannel("custom_channel"),
+				pgxadapter.WithAdvisoryLockKey(42),
+			},
+			expectedChannel: "custom_channel",
+			expectedLockKey: 42,
+		},
+		{
+			name:            "tenant_suffix",
+			opts:            []pgxadapter.Option{pgxadapter.WithNamespace("tenants"), pgxadapter.WithTenant("acme")},
+			expectedChannel: "tenants_casbin_change_acme",
+		},
+		{
+			name:            "long_tenant_hashed",
+			opts:            []pgxadapter.Option{pgxadapter.WithTenant(strings.Repeat("t", 60))},
+			expectedChannel: "casbin_change_007cbdb4c00d46a4c422131141732415",
+		},
+	}
+
+	lockKeys := make(map[int64]string)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tableName := fmt.Sprintf("casbin_test_namespace_%s", tt.name)
+			conn := setupTestDB(t, tableName)
+
+			opts := append([]pgxadapter.Option{pgxadapter.WithTableName(tableName)}, tt.opts...)
+			adapter, err := pgxadapter.NewAdapterWithConn(conn, opts...)
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			if got := adapter.GetNotifyChannel(); got != tt.expectedChannel {
+				t.Errorf("GetNotifyChannel() = %v, want %v", got, tt.expectedChannel)
+			}
+
+			key := adapter.GetAdvisoryLockKey()
+			if tt.expectedLockKey != 0 && key != tt.expectedLockKey {
+				t.Errorf("GetAdvisoryLockKey() = %v, want %v", key, tt.expectedLockKey)
+			}
+			if other, ok := lockKeys[key]; ok {
+				t.Errorf("GetAdvisoryLockKey() = %v collides with %s", key, other)
+			}
+			lockKeys[key] = tt.name
+
+			// Keys are deterministic for the same namespace
+			again, err := pgxadapter.NewAdapterWithConn(conn, opts...)
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+			if again.GetAdvisoryLockKey() != key {
+				t.Errorf("GetAdvisoryLockKey() is not deterministic: %v vs %v", again.GetAdvisoryLockKey(), key)
+			}
+		})
+	}
+}
+
+func TestWithNotifyChannelInvalid(t *testing.T) {
+	tableName := "casbin_test_notify_channel_invalid"
+	conn := setupTestDB(t, tableName)
+
+	_, err := pgxadapter.NewAdapterWithConn(conn,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithNotifyChannel(strings.Repeat("c", 64)),
+	)
+	if err == nil || !strings.Contains(err.Error(), "invalid notify channel") {
+		t.Errorf("Expected invalid notify channel error, got %v", err)
+	}
+}
+
+func TestWithNotifyChannelIsolation(t *testing.T) {
+	pool := setupTestPool(t, "casbin_test_notify_tenant_a")
+	setupTestPool(t, "casbin_test_notify_tenant_b")
+
+	newWatcher := func(tenant string) (*pgxadapter.Watcher, chan string) {
+		adapter, err := pgxadapter.NewAdapterWithPool(pool,
+			pgxadapter.WithTableName("casbin_test_notify_tenant_"+tenant),
+			pgxadapter.WithNotifyChannel("tenant_"+tenant+"_casbin_change"),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		w, err := pgxadapter.NewWatcher(context.Background(), adapter)
+		if err != nil {
+			t.Fatalf("Failed to create watcher: %v", err)
+		}
+		t.Cleanup(w.Close)
+		called := make(chan string, 1)
+		_ = w.SetUpdateCallback(func(payload string) { called <- payload })
+		return w, called
+	}
+
+	a, _ := newWatcher("a")
+	_, bCalled := newWatcher("b")
+
+	if err := a.Update(); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	select {
+	case payload := <-bCalled:
+		t.Errorf("Tenant b received tenant a's notification %q", payload)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
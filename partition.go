@@ -0,0 +1,78 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithPartitionByPtype creates the policy table as a table partitioned by
+// LIST (ptype), with one partition per given ptype, or for p and g if none
+// are given, and a default partition for every other ptype. Partitions are
+// named <table>_<ptype> and <table>_default. Filtered loads and writes all
+// constrain ptype, so the planner only reads the partition of that ptype,
+// which keeps loading the rules of one ptype fast when another one, such as
+// the grouping rules, holds tens of millions of rows.
+//
+// Only newly created tables are partitioned. A partition can only be added
+// for a ptype the default partition holds no rules of. The primary key is
+// (id, ptype) and WithFillFactor applies to every partition.
+func WithPartitionByPtype(ptypes ...string) Option {
+	return func(a *PgxAdapter) {
+		a.partitionByPtype = true
+		a.partitionPtypes = ptypes
+	}
+}
+
+// partitionKeyDDL returns the primary key of a partitioned table, which must include ptype
+func (a *PgxAdapter) partitionKeyDDL() string {
+	if !a.partitionByPtype {
+		return ""
+	}
+	return ",\n\t\tPRIMARY KEY (id, " + a.column("ptype") + ")"
+}
+
+// partitionDDL returns the clause following the column list of the table
+func (a *PgxAdapter) partitionDDL() string {
+	if !a.partitionByPtype {
+		return a.storageParamsDDL()
+	}
+	return " PARTITION BY LIST (" + a.column("ptype") + ")"
+}
+
+// createPartitions creates the partitions of every configured ptype and the default partition
+func (a *PgxAdapter) createPartitions(ctx context.Context) error {
+	if !a.partitionByPtype {
+		return nil
+	}
+
+	// Tables created before the option was set stay as they are
+	var kind string
+	if err := a.db.QueryRow(ctx,
+		"SELECT relkind::text FROM pg_class WHERE oid = to_regclass($1)", a.quotedTableName(),
+	).Scan(&kind); err != nil {
+		return fmt.Errorf("failed to query table: %w", err)
+	}
+	if kind != "p" {
+		return nil
+	}
+
+	ptypes := a.partitionPtypes
+	if len(ptypes) == 0 {
+		ptypes = []string{"p", "g"}
+	}
+
+	for _, ptype := range ptypes {
+		ddl := `CREATE ` + a.unloggedDDL() + `TABLE IF NOT EXISTS ` + a.qualifiedName(a.tableName+"_"+ptype) +
+			` PARTITION OF ` + a.quotedTableName() + ` FOR VALUES IN (` + quoteLiteral(ptype) + `)` + a.storageParamsDDL()
+		if _, err := a.db.Exec(ctx, ddl); err != nil {
+			return fmt.Errorf("failed to create partition for ptype %s: %w", ptype, err)
+		}
+	}
+
+	ddl := `CREATE ` + a.unloggedDDL() + `TABLE IF NOT EXISTS ` + a.qualifiedName(a.tableName+"_default") +
+		` PARTITION OF ` + a.quotedTableName() + ` DEFAULT` + a.storageParamsDDL()
+	if _, err := a.db.Exec(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to create default partition: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithPartitionByPtype(t *testing.T) {
+	tableName := "casbin_test_partition"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithPartitionByPtype(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	m, err := model.NewModelFromString(TestModelText)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("Failed to create enforcer: %v", err)
+	}
+	if _, err := e.AddPolicy("admin", "data1", "read"); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if _, err := e.AddGroupingPolicy("alice", "admin"); err != nil {
+		t.Fatalf("Failed to add grouping policy: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "g", "g2", []string{"data1", "group1"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"alice", "admin"}); err == nil {
+		t.Error("Expected the unique index to reject a duplicate within a partition")
+	}
+
+	tests := []struct {
+		ptype     string
+		partition string
+	}{
+		{ptype: "p", partition: tableName + "_p"},
+		{ptype: "g", partition: tableName + "_g"},
+		{ptype: "g2", partition: tableName + "_default"},
+	}
+	for _, tt := range tests {
+		var partition string
+		if err := pool.QueryRow(ctx,
+			"SELECT tableoid::regclass::text FROM "+tableName+" WHERE ptype = $1", tt.ptype,
+		).Scan(&partition); err != nil {
+			t.Fatalf("Failed to query partition of %s: %v", tt.ptype, err)
+		}
+		if partition != tt.partition {
+			t.Errorf("Expected ptype %s in partition %s, got %s", tt.ptype, tt.partition, partition)
+		}
+	}
+
+	if err := e.LoadFilteredPolicy(pgxadapter.Filter{Ptype: []string{"g"}}); err != nil {
+		t.Fatalf("Failed to load filtered policy: %v", err)
+	}
+	if rules, _ := e.GetGroupingPolicy(); len(rules) != 1 {
+		t.Errorf("Expected one grouping rule, got %v", rules)
+	}
+	if rules, _ := e.GetPolicy(); len(rules) != 0 {
+		t.Errorf("Expected no policy rules, got %v", rules)
+	}
+}
@@ -2,9 +2,15 @@ package pgxadapter
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/casbin/casbin/v3/persist"
@@ -13,19 +19,33 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-var _ persist.Adapter = (*PgxAdapter)(nil)
+var (
+	_ persist.Adapter                 = (*PgxAdapter)(nil)
+	_ persist.FilteredAdapter         = (*PgxAdapter)(nil)
+	_ persist.BatchAdapter            = (*PgxAdapter)(nil)
+	_ persist.UpdatableAdapter        = (*PgxAdapter)(nil)
+	_ persist.ContextAdapter          = (*PgxAdapter)(nil)
+	_ persist.ContextFilteredAdapter  = (*PgxAdapter)(nil)
+	_ persist.ContextBatchAdapter     = (*PgxAdapter)(nil)
+	_ persist.ContextUpdatableAdapter = (*PgxAdapter)(nil)
+)
 
 // DB represents database operations needed by the adapter.
 // Both *pgx.Conn and *pgxpool.Pool implement this interface.
 type DB interface {
 	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
 const (
-	defaultTableName = "casbin_rule"
-	defaultDatabase  = "casbin"
+	defaultTableName      = "casbin_rule"
+	defaultDatabase       = "casbin"
+	defaultArrayDelimiter = ","
+
+	// resetTimeout bounds resetting a released connection, see WithResetOnRelease
+	resetTimeout = 5 * time.Second
 )
 
 // PgxAdapter represents the pgx adapter for policy persistence
@@ -34,16 +54,169 @@ type PgxAdapter struct {
 	conn       *pgx.Conn
 	pool       *pgxpool.Pool
 	tableName  string
+	schema     string
 	database   string
+	indexName  string
 	psql       sq.StatementBuilderType
 	isFiltered bool
-	indexes    [][]string
+	indexes    []indexDef
 	mu         sync.RWMutex
+	// loadMu serializes loads into models, which are not concurrency safe.
+	// It is separate from mu so IsFiltered does not wait on a running load.
+	loadMu sync.Mutex
+
+	// unique constraint of an existing table, replaces the unique index
+	uniqueConstraint string
+
+	// options the adapter was created with, reapplied to transaction views
+	opts []Option
 
 	// pool configuration
-	usePool bool
+	usePool               bool
+	poolConfig            *pgxpool.Config
+	poolMaxConns          int32
+	poolMinConns          int32
+	poolHealthCheckPeriod time.Duration
+	poolMaxConnLifetime   time.Duration
+
+	// connection configuration
+	resetOnRelease     bool
+	statementCacheMode StatementCacheMode
+
+	// load configuration
+	loadFetchSize  int
+	maxLoadRows    int
+	canonicalOrder bool
+
+	// SQL of the hot path statements, see cachedSQL
+	stmts sync.Map
+
+	// write configuration
+	saveMode           SaveMode
+	insertValueColumns []string
+	auditTable         string
+	subjectField       int
+	isoLevel           pgx.TxIsoLevel
+	idempotentWrites   bool
+	dedupeRules        bool
+	dedupedRules       atomic.Int64
+	retryAttempts      int
+	retryBackoff       time.Duration
+
+	// table storage
+	fillFactor int
+	unlogged   bool
+
+	// table column names of the rule columns, see WithColumnNames
+	columnNames map[string]string
+
+	// value columns after v5, see WithExtraColumns
+	extraColumns int
+
+	// user supplied CREATE TABLE statement, see WithCreateTableDDL
+	createTableTemplate string
+
+	// tenant the rules are scoped to, see WithTenantColumn
+	useTenantColumn  bool
+	tenant           string
+	tenantResolver   func(ctx context.Context) (string, error)
+	rowLevelSecurity bool
+
+	// generated hash column backing the unique index, see WithHashUniqueIndex
+	hashUniqueIndex bool
+
+	// LIST partitions per ptype, see WithPartitionByPtype
+	partitionByPtype bool
+	partitionPtypes  []string
+
+	// UUID or identity instead of SERIAL ids, see WithUUIDPrimaryKey and WithIdentityPrimaryKey
+	uuidPrimaryKey     bool
+	identityPrimaryKey bool
+
+	// check the table instead of creating it, see WithSkipTableCreate
+	skipTableCreate bool
+
+	// value column types, VARCHAR(columnLength) unless textColumns is set
+	columnLength int
+	textColumns  bool
+
+	// value columns stored as TEXT[]
+	arrayColumns   map[string]bool
+	arrayDelimiter string
+
+	// dedicated effect column, eftIndexes is guarded by mu
+	useEftColumn bool
+	eftIndexes   map[string]int
+
+	// allowed values per value column, enforced with CHECK constraints
+	checkConstraints map[string][]string
+
+	// clock stamps client side managed timestamps
+	clock func() time.Time
+
+	// JSONB metadata column
+	useMetadataColumn bool
+
+	// created_at and updated_at columns
+	timestamps bool
+
+	// ANALYZE after bulk inserts
+	autoAnalyze          bool
+	autoAnalyzeThreshold int64
+
+	// batch size from which AddPolicies copies the rules, zero disables COPY
+	copyThreshold int
+
+	// operation timeouts, zero means no timeout
+	queryTimeout     time.Duration
+	readTimeout      time.Duration
+	writeTimeout     time.Duration
+	statementTimeout time.Duration
+
+	// namespacing of shared database resources
+	namespace          string
+	notifyChannel      string
+	advisoryLockKey    int64
+	hasAdvisoryLockKey bool
+	advisoryLock       bool
+
+	// watch configuration
+	notifyDebounce time.Duration
+	changeTrigger  bool
+
+	// policy version, see WithPolicyVersion
+	policyVersion bool
+	loadedVersion atomic.Int64
+
+	// deferred initialization, see WithLazyInit
+	lazyInit bool
+	initOnce sync.Once
+	initErr  error
+	connect  func(ctx context.Context) error
+
+	// last known good policy, snapshot is guarded by mu
+	inMemoryFallback bool
+	snapshot         [][]string
 }
 
+// StatementCacheMode controls how pgx caches prepared statements and
+// statement descriptions on connections created by NewAdapter.
+type StatementCacheMode int
+
+const (
+	// StatementCacheDefault leaves the pgx defaults untouched.
+	StatementCacheDefault StatementCacheMode = iota
+	// StatementCachePrepare caches named prepared statements per connection
+	// (pgx.QueryExecModeCacheStatement).
+	StatementCachePrepare
+	// StatementCacheDescribe caches only statement descriptions and executes
+	// queries with unnamed statements (pgx.QueryExecModeCacheDescribe).
+	StatementCacheDescribe
+	// StatementCacheDisabled disables both caches and describes every query
+	// before executing it (pgx.QueryExecModeExec).
+	StatementCacheDisabled
+)
+
 // Option is a function that configures the adapter
 type Option func(*PgxAdapter)
 
@@ -54,32 +227,286 @@ func WithTableName(tableName string) Option {
 	}
 }
 
-// WithDatabaseName sets a custom database name for the adapter
+// WithDatabaseName sets the database NewAdapter connects to, overriding the
+// one of the connection string. The database is created if it does not exist,
+// through a connection to the database of the connection string, or to the
+// postgres database when that is the same one. Without this option NewAdapter
+// uses the database of the connection string as is, or the casbin database,
+// created if needed, when the connection string names none. It has no effect
+// on NewAdapterWithConn or NewAdapterWithPool, which use the database they
+// are connected to.
 func WithDatabaseName(database string) Option {
 	return func(a *PgxAdapter) {
 		a.database = database
 	}
 }
 
+// WithUniqueIndexName overrides the name of the unique index over
+// (ptype, v0..v5), which defaults to idx_<table>.
+func WithUniqueIndexName(name string) Option {
+	return func(a *PgxAdapter) {
+		a.indexName = name
+	}
+}
+
+// WithExistingUniqueConstraint makes the adapter use the named unique
+// constraint of an existing table instead of creating its own unique index.
+// Idempotent writes target the constraint with ON CONFLICT ON CONSTRAINT, and
+// table creation fails if the constraint does not exist. Empty values are
+// stored as empty strings, as in tables whose value columns are NOT NULL with
+// an empty string default, so a plain UNIQUE constraint rejects duplicates of
+// rules with fewer than six values. Rows holding NULL match and load the same.
+func WithExistingUniqueConstraint(name string) Option {
+	return func(a *PgxAdapter) {
+		a.uniqueConstraint = name
+	}
+}
+
+// WithLoadFetchSize makes LoadPolicy and LoadFilteredPolicy read rules through
+// a server side cursor, fetching n rows per round trip and loading each batch
+// into the model before fetching the next. By default all rows are streamed by
+// a single query. Larger values mean fewer round trips, which helps on high latency links,
+// at the cost of buffering up to n rows at a time; smaller values keep memory
+// flat but pay the network latency once per batch.
+func WithLoadFetchSize(n int) Option {
+	return func(a *PgxAdapter) {
+		a.loadFetchSize = n
+	}
+}
+
+// WithMaxLoadRows makes LoadPolicy and LoadFilteredPolicy fail with an error
+// wrapping ErrMaxLoadRows instead of loading more than n rules, so an overly
+// broad filter cannot exhaust memory. Unlike a page, nothing is loaded partially
+// on purpose: the load fails as a whole. Defaults to 0, which means unlimited.
+func WithMaxLoadRows(n int) Option {
+	return func(a *PgxAdapter) {
+		a.maxLoadRows = n
+	}
+}
+
+// WithArrayColumn stores the given value column (v0..v5) as TEXT[] instead of VARCHAR.
+// Tokens written to the column are split on the array delimiter and joined back
+// on load, so Casbin still sees a single string while the column can be queried
+// with array operators. Only affects newly created tables.
+func WithArrayColumn(col string) Option {
+	return func(a *PgxAdapter) {
+		if a.arrayColumns == nil {
+			a.arrayColumns = make(map[string]bool)
+		}
+		a.arrayColumns[col] = true
+	}
+}
+
+// WithArrayDelimiter sets the delimiter used to split and join array column tokens.
+// Defaults to ",".
+func WithArrayDelimiter(delimiter string) Option {
+	return func(a *PgxAdapter) {
+		a.arrayDelimiter = delimiter
+	}
+}
+
+// WithCanonicalOrder reads rules ordered by ptype and then v0..v5 instead of by id.
+// Ids differ between databases holding the same rules, so this makes loads and
+// exports deterministic across environments. Values are compared bytewise.
+func WithCanonicalOrder() Option {
+	return func(a *PgxAdapter) {
+		a.canonicalOrder = true
+	}
+}
+
+// WithCheckConstraint restricts a value column (v0..v5) to the allowed values
+// with a CHECK constraint, added when the table is created. Writes with any
+// other value fail with an error wrapping ErrCheckViolation.
+func WithCheckConstraint(col string, allowed []string) Option {
+	return func(a *PgxAdapter) {
+		if a.checkConstraints == nil {
+			a.checkConstraints = make(map[string][]string)
+		}
+		a.checkConstraints[col] = allowed
+	}
+}
+
+// WithClock sets the clock stamping the created_at and updated_at columns of
+// WithTimestamps, audit entries and dispatched changes, instead of the
+// database's now(). Timestamps are bound as parameters, which makes them
+// deterministic in tests. Defaults to time.Now.
+func WithClock(clock func() time.Time) Option {
+	return func(a *PgxAdapter) {
+		a.clock = clock
+	}
+}
+
+// indexDef is an index added with WithIndex or WithPartialIndex
+type indexDef struct {
+	columns []string
+	// predicate of a partial index, empty for a full index
+	where string
+	// GIN index with gin_trgm_ops over the single column, see WithGinTrigramIndex
+	trigram bool
+}
+
 // WithIndex adds a composite index on the specified columns.
-// Valid columns are: ptype, v0, v1, v2, v3, v4, v5.
+// Valid columns are: ptype, v0, v1, v2, v3, v4, v5, and eft with WithEftColumn.
+// Names are case insensitive; an invalid name fails table creation before any DDL runs.
 // Can be called multiple times to add multiple indexes.
 func WithIndex(columns ...string) Option {
 	return func(a *PgxAdapter) {
 		if len(columns) > 0 {
-			a.indexes = append(a.indexes, columns)
+			a.indexes = append(a.indexes, indexDef{columns: normalizeColumns(columns)})
+		}
+	}
+}
+
+// WithPartialIndex adds an index on the specified columns that only covers
+// the rows matching whereClause, e.g.
+//
+//	WithPartialIndex([]string{"v0", "v1"}, "ptype = 'g'")
+//
+// to index the grouping rules hit by filtered loads without also indexing
+// every p rule. Columns are validated like those of WithIndex. whereClause is
+// embedded in the DDL as is, so it must be trusted SQL; queries only use the
+// index when their conditions imply it. The index name includes a hash of
+// whereClause, so changing it creates a new index next to the old one.
+func WithPartialIndex(columns []string, whereClause string) Option {
+	return func(a *PgxAdapter) {
+		if len(columns) > 0 {
+			a.indexes = append(a.indexes, indexDef{columns: normalizeColumns(columns), where: whereClause})
 		}
 	}
 }
 
+// normalizeColumns lower cases and trims index column names
+func normalizeColumns(columns []string) []string {
+	normalized := make([]string, len(columns))
+	for i, col := range columns {
+		normalized[i] = strings.ToLower(strings.TrimSpace(col))
+	}
+	return normalized
+}
+
+// WithIdempotentWrites makes AddPolicy and AddPolicies skip rules that already
+// exist with INSERT ... ON CONFLICT DO NOTHING instead of failing, so retries
+// and at-least-once message processing are safe. AddPolicyReturningID then
+// returns the id of the existing rule.
+func WithIdempotentWrites() Option {
+	return func(a *PgxAdapter) {
+		a.idempotentWrites = true
+	}
+}
+
+// WithInsertColumns limits inserts to the given value columns, which must be
+// v0..vN in order, e.g. WithInsertColumns("v0", "v1", "v2") for a model with
+// three tokens. The remaining columns are omitted from inserts and default to
+// NULL, which the unique index treats like empty values. This saves bind
+// parameters, so more rules fit into a batch. SavePolicy rejects models with
+// more tokens than listed columns.
+func WithInsertColumns(cols ...string) Option {
+	return func(a *PgxAdapter) {
+		a.insertValueColumns = cols
+	}
+}
+
+// WithFillFactor creates the table with the given fillfactor (10..100), leaving
+// free space in each page so UpdatePolicy can update rows in place (HOT
+// updates) instead of moving them. Only affects newly created tables.
+func WithFillFactor(n int) Option {
+	return func(a *PgxAdapter) {
+		a.fillFactor = n
+	}
+}
+
 // WithPool configures the adapter to use a connection pool instead of a single connection.
-// Pool settings can be configured via connection string parameters (e.g., pool_max_conns, pool_min_conns).
+// Pool settings can be configured via connection string parameters (e.g., pool_max_conns, pool_min_conns)
+// or with WithPoolMaxConns and the other pool options.
 func WithPool() Option {
 	return func(a *PgxAdapter) {
 		a.usePool = true
 	}
 }
 
+// WithPoolConfig makes NewAdapter create its pool from a copy of config, such
+// as one from pgxpool.ParseConfig with hooks or a tracer set, instead of
+// parsing the connection string, which is then ignored. It enables WithPool.
+// The other pool and connection options still apply on top of config.
+func WithPoolConfig(config *pgxpool.Config) Option {
+	return func(a *PgxAdapter) {
+		a.usePool = true
+		a.poolConfig = config
+	}
+}
+
+// WithPoolMaxConns sets the maximum size of the pool created by NewAdapter with WithPool
+func WithPoolMaxConns(n int32) Option {
+	return func(a *PgxAdapter) {
+		a.poolMaxConns = n
+	}
+}
+
+// WithPoolMinConns sets the number of connections the pool created by
+// NewAdapter with WithPool keeps open even when idle
+func WithPoolMinConns(n int32) Option {
+	return func(a *PgxAdapter) {
+		a.poolMinConns = n
+	}
+}
+
+// WithPoolHealthCheckPeriod sets how often the pool created by NewAdapter
+// with WithPool checks its idle connections
+func WithPoolHealthCheckPeriod(d time.Duration) Option {
+	return func(a *PgxAdapter) {
+		a.poolHealthCheckPeriod = d
+	}
+}
+
+// WithPoolMaxConnLifetime sets how long a connection of the pool created by
+// NewAdapter with WithPool is used before it is closed and replaced, so
+// connections move to new servers after a failover or DNS change
+func WithPoolMaxConnLifetime(d time.Duration) Option {
+	return func(a *PgxAdapter) {
+		a.poolMaxConnLifetime = d
+	}
+}
+
+// parsePoolConfig returns the config of the pool created by NewAdapter:
+// a copy of the one set with WithPoolConfig, or the one parsed from connStr
+func (a *PgxAdapter) parsePoolConfig(connStr string) (*pgxpool.Config, error) {
+	if a.poolConfig != nil {
+		return a.poolConfig.Copy(), nil
+	}
+
+	config, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
+	return config, nil
+}
+
+// WithResetOnRelease runs DISCARD ALL on pooled connections when they are
+// released, so session state such as SET parameters, temporary tables,
+// advisory locks and LISTEN registrations never carries over to the next
+// checkout. The pgx statement caches are cleared with it. A connection whose
+// reset fails is closed instead of being returned to the pool. It only applies
+// to pools created by NewAdapter with WithPool.
+func WithResetOnRelease() Option {
+	return func(a *PgxAdapter) {
+		a.resetOnRelease = true
+	}
+}
+
+// WithStatementCacheMode sets how connections created by NewAdapter cache
+// prepared statements. It has no effect on NewAdapterWithConn or
+// NewAdapterWithPool, where the caller owns the connection config.
+//
+// PgBouncer in transaction pooling mode cannot track named prepared statements
+// across server connections, so use StatementCacheDescribe or
+// StatementCacheDisabled behind it. Unlike default_query_exec_mode=simple_protocol,
+// both modes still use the extended protocol with bound parameters.
+func WithStatementCacheMode(mode StatementCacheMode) Option {
+	return func(a *PgxAdapter) {
+		a.statementCacheMode = mode
+	}
+}
+
 // NewAdapter creates a new adapter with a connection string.
 // If WithPool is provided, a connection pool is created. Otherwise, a single connection is used.
 func NewAdapter(connStr string, opts ...Option) (*PgxAdapter, error) {
@@ -92,11 +519,35 @@ func NewAdapter(connStr string, opts ...Option) (*PgxAdapter, error) {
 	}
 
 	if cfg.usePool {
-		pool, err := pgxpool.New(ctx, connStr)
+		poolConfig, err := cfg.parsePoolConfig(connStr)
+		if err != nil {
+			return nil, err
+		}
+		cfg.applyConnConfig(poolConfig.ConnConfig)
+		cfg.applyPoolConfig(poolConfig)
+		createDB := cfg.applyDatabase(poolConfig.ConnConfig)
+		opts = append(slices.Clone(opts), WithDatabaseName(poolConfig.ConnConfig.Database))
+
+		if !cfg.lazyInit {
+			if err := createDB(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create connection pool: %w", err)
 		}
 
+		// pgxpool connects on demand, so a lazy adapter only skips the ping
+		if cfg.lazyInit {
+			a, err := NewAdapterWithPool(pool, opts...)
+			if err != nil {
+				return nil, err
+			}
+			a.connect = createDB
+			return a, nil
+		}
 		if err := pool.Ping(ctx); err != nil {
 			pool.Close()
 			return nil, fmt.Errorf("failed to ping database: %w", err)
@@ -105,7 +556,35 @@ func NewAdapter(connStr string, opts ...Option) (*PgxAdapter, error) {
 		return NewAdapterWithPool(pool, opts...)
 	}
 
-	conn, err := pgx.Connect(ctx, connStr)
+	connConfig, err := pgx.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
+	cfg.applyConnConfig(connConfig)
+	createDB := cfg.applyDatabase(connConfig)
+	opts = append(slices.Clone(opts), WithDatabaseName(connConfig.Database))
+
+	if cfg.lazyInit {
+		a := newAdapter(opts...)
+		a.connect = func(ctx context.Context) error {
+			if err := createDB(ctx); err != nil {
+				return err
+			}
+			conn, err := pgx.ConnectConfig(ctx, connConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create connection: %w", err)
+			}
+			a.db = conn
+			a.conn = conn
+			return nil
+		}
+		return a, nil
+	}
+
+	if err := createDB(ctx); err != nil {
+		return nil, err
+	}
+	conn, err := pgx.ConnectConfig(ctx, connConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection: %w", err)
 	}
@@ -118,23 +597,100 @@ func NewAdapter(connStr string, opts ...Option) (*PgxAdapter, error) {
 	return NewAdapterWithConn(conn, opts...)
 }
 
-// NewAdapterWithConn creates a new adapter with an existing connection
-func NewAdapterWithConn(conn *pgx.Conn, opts ...Option) (*PgxAdapter, error) {
+// NewContextAdapter creates a new adapter like NewAdapter, narrowed to the
+// context aware Casbin adapter interface.
+func NewContextAdapter(connStr string, opts ...Option) (persist.ContextAdapter, error) {
+	a, err := NewAdapter(connStr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// applyConnConfig applies connection level options to a parsed pgx config
+func (a *PgxAdapter) applyConnConfig(config *pgx.ConnConfig) {
+	if a.statementTimeout > 0 {
+		config.RuntimeParams["statement_timeout"] = strconv.FormatInt(a.statementTimeout.Milliseconds(), 10)
+	}
+
+	switch a.statementCacheMode {
+	case StatementCachePrepare:
+		config.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+	case StatementCacheDescribe:
+		config.DefaultQueryExecMode = pgx.QueryExecModeCacheDescribe
+		config.StatementCacheCapacity = 0
+	case StatementCacheDisabled:
+		config.DefaultQueryExecMode = pgx.QueryExecModeExec
+		config.StatementCacheCapacity = 0
+		config.DescriptionCacheCapacity = 0
+	}
+}
+
+// newAdapter creates an adapter with default settings and applies the options
+func newAdapter(opts ...Option) *PgxAdapter {
 	a := &PgxAdapter{
-		db:        conn,
-		conn:      conn,
-		tableName: defaultTableName,
-		database:  defaultDatabase,
-		psql:      sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		tableName:            defaultTableName,
+		database:             defaultDatabase,
+		arrayDelimiter:       defaultArrayDelimiter,
+		autoAnalyzeThreshold: defaultAutoAnalyzeThreshold,
+		copyThreshold:        defaultCopyThreshold,
+		psql:                 sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
 	}
 
 	// Apply options
 	for _, opt := range opts {
 		opt(a)
 	}
+	a.opts = opts
+
+	return a
+}
+
+// applyPoolConfig applies pool level options to a parsed pgxpool config
+func (a *PgxAdapter) applyPoolConfig(config *pgxpool.Config) {
+	if a.resetOnRelease {
+		config.AfterRelease = resetConn
+	}
+	if a.poolMaxConns > 0 {
+		config.MaxConns = a.poolMaxConns
+	}
+	if a.poolMinConns > 0 {
+		config.MinConns = a.poolMinConns
+	}
+	if a.poolHealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = a.poolHealthCheckPeriod
+	}
+	if a.poolMaxConnLifetime > 0 {
+		config.MaxConnLifetime = a.poolMaxConnLifetime
+	}
+}
+
+// resetConn discards the session state of a released connection.
+// It reports whether the connection can be returned to the pool.
+func resetConn(conn *pgx.Conn) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), resetTimeout)
+	defer cancel()
+
+	if _, err := conn.Exec(ctx, "DISCARD ALL"); err != nil {
+		return false
+	}
+
+	// DISCARD ALL deallocated the server side statements the caches refer to
+	return conn.DeallocateAll(ctx) == nil
+}
+
+// NewAdapterWithConn creates a new adapter with an existing connection
+func NewAdapterWithConn(conn *pgx.Conn, opts ...Option) (*PgxAdapter, error) {
+	a := newAdapter(opts...)
+	a.db = conn
+	a.conn = conn
+
+	if a.lazyInit {
+		return a, nil
+	}
 
 	// Create table if it doesn't exist
-	if err := a.createTable(); err != nil {
+	if err := a.createTable(context.Background()); err != nil {
 		return nil, fmt.Errorf("failed to create table: %w", err)
 	}
 
@@ -143,21 +699,40 @@ func NewAdapterWithConn(conn *pgx.Conn, opts ...Option) (*PgxAdapter, error) {
 
 // NewAdapterWithPool creates a new adapter with an existing connection pool
 func NewAdapterWithPool(pool *pgxpool.Pool, opts ...Option) (*PgxAdapter, error) {
-	a := &PgxAdapter{
-		db:        pool,
-		pool:      pool,
-		tableName: defaultTableName,
-		database:  defaultDatabase,
-		psql:      sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	a := newAdapter(opts...)
+	a.db = pool
+	a.pool = pool
+	if a.rowLevelSecurity {
+		a.db = &tenantPool{pool: pool, tenant: a.tenantOf}
 	}
 
-	// Apply options
-	for _, opt := range opts {
-		opt(a)
+	if a.lazyInit {
+		return a, nil
+	}
+
+	// Create table if it doesn't exist
+	if err := a.createTable(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to create table: %w", err)
+	}
+
+	return a, nil
+}
+
+// NewAdapterWithTx creates a new adapter bound to an open transaction, so its
+// writes commit or roll back together with the caller's own writes in tx.
+// The adapter must not be used after tx ends. A failing statement, such as
+// adding a rule that already exists, aborts tx like any other statement in it.
+// The table is created within tx unless WithLazyInit is set.
+func NewAdapterWithTx(tx pgx.Tx, opts ...Option) (*PgxAdapter, error) {
+	a := newAdapter(opts...)
+	a.db = tx
+
+	if a.lazyInit {
+		return a, nil
 	}
 
 	// Create table if it doesn't exist
-	if err := a.createTable(); err != nil {
+	if err := a.createTable(context.Background()); err != nil {
 		return nil, fmt.Errorf("failed to create table: %w", err)
 	}
 
@@ -165,57 +740,261 @@ func NewAdapterWithPool(pool *pgxpool.Pool, opts ...Option) (*PgxAdapter, error)
 }
 
 // createTable creates the casbin_rule table if it doesn't exist
-func (a *PgxAdapter) createTable() error {
-	ctx := context.Background()
+func (a *PgxAdapter) createTable(ctx context.Context) error {
+	if err := a.validateColumnNames(); err != nil {
+		return err
+	}
+	if err := a.validateIndexes(); err != nil {
+		return err
+	}
+	if err := a.validateInsertColumns(); err != nil {
+		return err
+	}
+	if err := a.validateNotifyChannel(); err != nil {
+		return err
+	}
+	if err := a.validateIsolationLevel(); err != nil {
+		return err
+	}
+	if err := a.validateHashUniqueIndex(); err != nil {
+		return err
+	}
+	if a.rowLevelSecurity && a.tenantResolver != nil && a.pool == nil {
+		return errors.New("WithRowLevelSecurity and WithTenantResolver require a pool")
+	}
+	if a.uuidPrimaryKey && a.identityPrimaryKey {
+		return errors.New("WithUUIDPrimaryKey and WithIdentityPrimaryKey cannot be combined")
+	}
+	if a.fillFactor != 0 && (a.fillFactor < 10 || a.fillFactor > 100) {
+		return fmt.Errorf("invalid fillfactor %d: must be between 10 and 100", a.fillFactor)
+	}
 
-	// Use pgx identifier quoting for secure table name handling
-	quotedTableName := pgx.Identifier{a.tableName}.Sanitize()
-	quotedIndexName := pgx.Identifier{"idx_" + a.tableName}.Sanitize()
+	if _, err := a.createTableDDL(); err != nil {
+		return err
+	}
+	if err := a.setTenantSetting(ctx); err != nil {
+		return err
+	}
+	if a.skipTableCreate {
+		return a.checkTable(ctx)
+	}
 
-	createTableSQL := `CREATE TABLE IF NOT EXISTS ` + quotedTableName + ` (
-		id SERIAL PRIMARY KEY,
-		ptype VARCHAR(100) NOT NULL,
-		v0 VARCHAR(100),
-		v1 VARCHAR(100),
-		v2 VARCHAR(100),
-		v3 VARCHAR(100),
-		v4 VARCHAR(100),
-		v5 VARCHAR(100)
-	)`
+	return a.withAdvisoryLock(ctx, func() error {
+		return a.createSchema(ctx)
+	})
+}
 
-	createIndexSQL := `CREATE UNIQUE INDEX IF NOT EXISTS ` + quotedIndexName + `
-		ON ` + quotedTableName + `(ptype, COALESCE(v0,''), COALESCE(v1,''), COALESCE(v2,''), COALESCE(v3,''), COALESCE(v4,''), COALESCE(v5,''))`
+// createSchema creates the table, its indexes and the optional audit table and trigger
+func (a *PgxAdapter) createSchema(ctx context.Context) error {
+	if err := a.createSchemaNamespace(ctx); err != nil {
+		return err
+	}
+
+	createTableSQL, err := a.createTableDDL()
+	if err != nil {
+		return err
+	}
 
 	// Execute creation statements
 	if _, err := a.db.Exec(ctx, createTableSQL); err != nil {
 		return fmt.Errorf("failed to create table: %w", err)
 	}
-	if _, err := a.db.Exec(ctx, createIndexSQL); err != nil {
+	if err := a.createPartitions(ctx); err != nil {
+		return err
+	}
+	if err := a.dropRuleHash(ctx); err != nil {
+		return err
+	}
+	if err := a.alterColumns(ctx); err != nil {
+		return err
+	}
+	if err := a.migrateIdentity(ctx); err != nil {
+		return err
+	}
+	if err := a.installTimestamps(ctx); err != nil {
+		return err
+	}
+	if err := a.installRowLevelSecurity(ctx); err != nil {
+		return err
+	}
+	if a.auditTable != "" {
+		if _, err := a.db.Exec(ctx, a.auditTableDDL()); err != nil {
+			return fmt.Errorf("failed to create audit table: %w", err)
+		}
+	}
+	if a.uniqueConstraint != "" {
+		if err := a.validateUniqueConstraint(ctx); err != nil {
+			return err
+		}
+	} else if _, err := a.db.Exec(ctx, a.uniqueIndexDDL()); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return a.duplicateRulesError(ctx, err)
+		}
 		return fmt.Errorf("failed to create index: %w", err)
 	}
 
 	// Create custom indexes
-	for _, columns := range a.indexes {
-		if err := a.createIndex(ctx, columns); err != nil {
+	for _, index := range a.indexes {
+		if err := a.createIndex(ctx, index); err != nil {
+			return err
+		}
+	}
+
+	if a.changeTrigger {
+		if err := a.installChangeTrigger(ctx); err != nil {
 			return err
 		}
 	}
+	if a.policyVersion {
+		if err := a.createVersionTable(ctx); err != nil {
+			return err
+		}
+		if err := a.installVersionTrigger(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// uniqueIndexDDL returns the statement creating the unique index over the rule columns
+func (a *PgxAdapter) uniqueIndexDDL() string {
+	return `CREATE UNIQUE INDEX IF NOT EXISTS ` + pgx.Identifier{a.uniqueIndexName()}.Sanitize() + `
+		ON ` + a.quotedTableName() + a.uniqueIndexColumns()
+}
+
+// now returns the current time of the adapter's clock
+func (a *PgxAdapter) now() time.Time {
+	if a.clock != nil {
+		return a.clock()
+	}
+	return time.Now()
+}
+
+// quotedTableName returns the sanitized identifier of the adapter's table
+func (a *PgxAdapter) quotedTableName() string {
+	return a.qualifiedName(a.tableName)
+}
+
+// DropTable drops the adapter's table together with its indexes.
+// It is a no-op if the table does not exist.
+func (a *PgxAdapter) DropTable(ctx context.Context) error {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+
+	if _, err := a.dbFrom(ctx).Exec(ctx, "DROP TABLE IF EXISTS "+a.quotedTableName()+" CASCADE"); err != nil {
+		return fmt.Errorf("failed to drop table: %w", err)
+	}
+
+	return nil
+}
+
+// uniqueIndexName returns the name of the unique index over the rule columns
+func (a *PgxAdapter) uniqueIndexName() string {
+	if a.uniqueConstraint != "" {
+		// A unique constraint is backed by an index of the same name
+		return a.uniqueConstraint
+	}
+	if a.indexName != "" {
+		return a.indexName
+	}
+	return "idx_" + a.tableName
+}
+
+// onConflictDoNothing returns an ON CONFLICT clause bound to the unique index.
+// The index is built over expressions and therefore has no backing constraint,
+// so the clause infers it from uniqueIndexColumns instead of ON CONSTRAINT.
+// This also matches an equivalent index created out-of-band under another name.
+// A constraint set with WithExistingUniqueConstraint is named directly.
+func (a *PgxAdapter) onConflictDoNothing() string {
+	if a.uniqueConstraint != "" {
+		return "ON CONFLICT ON CONSTRAINT " + pgx.Identifier{a.uniqueConstraint}.Sanitize() + " DO NOTHING"
+	}
+	return "ON CONFLICT " + a.uniqueIndexColumns() + " DO NOTHING"
+}
+
+// validateUniqueConstraint checks that the constraint set with
+// WithExistingUniqueConstraint is a unique constraint of the table
+func (a *PgxAdapter) validateUniqueConstraint(ctx context.Context) error {
+	var exists bool
+	if err := a.db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM pg_constraint
+		WHERE conrelid = to_regclass($1) AND conname = $2 AND contype IN ('u', 'p'))`,
+		a.quotedTableName(), a.uniqueConstraint).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to query unique constraint: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("unique constraint %s does not exist on table %s", a.uniqueConstraint, a.tableName)
+	}
+	return nil
+}
+
+// storageParamsDDL returns the WITH clause of the table, if any storage parameter is set
+func (a *PgxAdapter) storageParamsDDL() string {
+	if a.fillFactor == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" WITH (fillfactor = %d)", a.fillFactor)
+}
+
+// validateInsertColumns checks that WithInsertColumns lists v0..vN in order
+func (a *PgxAdapter) validateInsertColumns() error {
+	for i, col := range a.insertValueColumns {
+		if i >= a.valueColumnTotal() || col != valueColumn(i) {
+			return fmt.Errorf("invalid insert columns (%s): expected value columns v0..vN in order",
+				strings.Join(a.insertValueColumns, ", "))
+		}
+	}
+	return nil
+}
 
+// validateIndexes checks that the columns of every WithIndex index exist
+func (a *PgxAdapter) validateIndexes() error {
+	valid := a.storedColumns()
+	for _, index := range a.indexes {
+		for _, col := range index.columns {
+			if !slices.Contains(valid, col) {
+				return fmt.Errorf("invalid index column %q in index (%s): valid columns are %s",
+					col, strings.Join(index.columns, ", "), strings.Join(valid, ", "))
+			}
+			if index.trigram && a.isArrayColumn(col) {
+				return fmt.Errorf("invalid trigram index column %q: array columns are not supported", col)
+			}
+		}
+	}
 	return nil
 }
 
-func (a *PgxAdapter) createIndex(ctx context.Context, columns []string) error {
-	quotedTableName := pgx.Identifier{a.tableName}.Sanitize()
-	indexName := "idx_" + a.tableName + "_" + strings.Join(columns, "_")
+func (a *PgxAdapter) createIndex(ctx context.Context, index indexDef) error {
+	if index.trigram {
+		return a.createTrigramIndex(ctx, index.columns[0])
+	}
+
+	quotedTableName := a.quotedTableName()
+	indexName := "idx_" + a.tableName + "_" + strings.Join(index.columns, "_")
+	if index.where != "" {
+		// Partial indexes over the same columns differ by their predicate
+		h := fnv.New32a()
+		h.Write([]byte(index.where))
+		indexName += fmt.Sprintf("_%08x", h.Sum32())
+	}
 	quotedIndexName := pgx.Identifier{indexName}.Sanitize()
 
 	var quotedColumns []string
-	for _, col := range columns {
-		quotedColumns = append(quotedColumns, pgx.Identifier{col}.Sanitize())
+	for _, col := range index.columns {
+		quotedColumns = append(quotedColumns, pgx.Identifier{a.columnName(col)}.Sanitize())
 	}
 
 	createIndexSQL := `CREATE INDEX IF NOT EXISTS ` + quotedIndexName +
 		` ON ` + quotedTableName + `(` + strings.Join(quotedColumns, ", ") + `)`
+	if index.where != "" {
+		createIndexSQL += ` WHERE ` + index.where
+	}
 
 	if _, err := a.db.Exec(ctx, createIndexSQL); err != nil {
 		return fmt.Errorf("failed to create index %s: %w", indexName, err)
@@ -225,13 +1004,13 @@ func (a *PgxAdapter) createIndex(ctx context.Context, columns []string) error {
 }
 
 // GetConn returns the underlying database connection.
-// Returns nil if the adapter was created with a pool.
+// Returns nil if the adapter was created with a pool or a transaction.
 func (a *PgxAdapter) GetConn() *pgx.Conn {
 	return a.conn
 }
 
 // GetPool returns the underlying connection pool.
-// Returns nil if the adapter was created with a single connection.
+// Returns nil if the adapter was created with a single connection or a transaction.
 func (a *PgxAdapter) GetPool() *pgxpool.Pool {
 	return a.pool
 }
@@ -242,6 +1021,14 @@ func (a *PgxAdapter) GetDB() DB {
 	return a.db
 }
 
+// SelectBuilder returns a squirrel select over the adapter's table using the
+// adapter's placeholder format and rule columns, scoped to the tenant of
+// WithTenant, as it has no context to resolve one from. It can be further
+// constrained for custom queries and executed with GetDB.
+func (a *PgxAdapter) SelectBuilder() sq.SelectBuilder {
+	return a.selectRules(context.Background(), a.selectColumns()...)
+}
+
 // GetTableName returns the table name used by the adapter
 func (a *PgxAdapter) GetTableName() string {
 	return a.tableName
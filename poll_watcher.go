@@ -0,0 +1,132 @@
+package pgxadapter
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v3/persist"
+)
+
+var _ persist.Watcher = (*PollingWatcher)(nil)
+
+// defaultPollInterval is the interval used when NewPollingWatcher gets none
+const defaultPollInterval = 5 * time.Second
+
+// PollingWatcher is a persist.Watcher for deployments where LISTEN/NOTIFY is
+// unavailable, such as behind PgBouncer in transaction pooling mode. Update
+// bumps the counter of the version table shared with WithPolicyVersion, and
+// every watcher polls it and calls its callback when the version moves past
+// the one it last saw.
+type PollingWatcher struct {
+	adapter  *PgxAdapter
+	interval time.Duration
+
+	mu       sync.Mutex
+	callback func(string)
+	version  int64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPollingWatcher creates the version table if needed and starts polling it
+// every interval, or every 5 seconds when interval is not positive.
+func NewPollingWatcher(ctx context.Context, adapter *PgxAdapter, interval time.Duration) (*PollingWatcher, error) {
+	if err := adapter.ensureInit(ctx); err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	w := &PollingWatcher{
+		adapter:  adapter,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+
+	if err := adapter.createVersionTable(ctx); err != nil {
+		return nil, err
+	}
+	version, err := adapter.readVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w.version = version
+
+	pollCtx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	go w.poll(pollCtx)
+
+	return w, nil
+}
+
+// SetUpdateCallback sets the function called when another instance changes the policy
+func (w *PollingWatcher) SetUpdateCallback(fn func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.callback = fn
+	return nil
+}
+
+// Update bumps the version so other watchers pick up the change
+func (w *PollingWatcher) Update() error {
+	ctx, cancel := w.adapter.writeContext(context.Background())
+	defer cancel()
+
+	version, err := w.adapter.bumpVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Skip our own bump, but only when no other change slipped in before it
+	w.mu.Lock()
+	if version == w.version+1 {
+		w.version = version
+	}
+	w.mu.Unlock()
+
+	return nil
+}
+
+// Close stops polling; the callback is not called any more
+func (w *PollingWatcher) Close() {
+	w.cancel()
+	<-w.done
+}
+
+// poll checks the version every interval until ctx is done
+func (w *PollingWatcher) poll(ctx context.Context) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		version, err := w.adapter.readVersion(ctx)
+		if err != nil {
+			// Transient errors are retried on the next tick
+			continue
+		}
+
+		w.mu.Lock()
+		changed := version != w.version
+		w.version = version
+		fn := w.callback
+		w.mu.Unlock()
+
+		if changed && fn != nil {
+			payload, _ := json.Marshal(WatcherMessage{Method: MethodUpdate})
+			fn(string(payload))
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestPollingWatcher(t *testing.T) {
+	tableName := "casbin_test_poll_watcher"
+	pool := setupTestPool(t, tableName)
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), "DROP TABLE IF EXISTS "+tableName+"_version")
+	})
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	newWatcher := func() (*pgxadapter.PollingWatcher, chan string) {
+		w, err := pgxadapter.NewPollingWatcher(context.Background(), adapter, 50*time.Millisecond)
+		if err != nil {
+			t.Fatalf("Failed to create watcher: %v", err)
+		}
+		t.Cleanup(w.Close)
+		called := make(chan string, 10)
+		_ = w.SetUpdateCallback(func(payload string) { called <- payload })
+		return w, called
+	}
+
+	writer, writerCalled := newWatcher()
+	_, readerCalled := newWatcher()
+
+	if err := writer.Update(); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	select {
+	case <-readerCalled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the polling watcher callback")
+	}
+
+	select {
+	case <-writerCalled:
+		t.Error("Expected the writer not to be notified of its own update")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
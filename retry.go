@@ -0,0 +1,61 @@
+package pgxadapter
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// WithRetry retries SavePolicy, AddPolicies, RemovePolicies, UpdatePolicies
+// and UpdateFilteredPolicies up to attempts times in total when they fail with
+// a serialization failure (40001) or a deadlock (40P01), which concurrent
+// writers can cause. The delay before a retry starts at backoff and doubles
+// with every attempt, with jitter. Writes made in a caller's transaction are
+// never retried, as the failure aborted that transaction.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(a *PgxAdapter) {
+		a.retryAttempts = attempts
+		a.retryBackoff = backoff
+	}
+}
+
+// withRetry runs fn, retrying it as configured with WithRetry
+func (a *PgxAdapter) withRetry(ctx context.Context, fn func() error) error {
+	if a.retryAttempts <= 1 || a.inCallerTx(ctx) {
+		return fn()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= a.retryAttempts || !isRetryable(err) {
+			return err
+		}
+
+		delay := a.retryBackoff << (attempt - 1)
+		if delay > 0 {
+			delay = delay/2 + rand.N(delay/2+1)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// inCallerTx reports whether writes run in a transaction owned by the caller
+func (a *PgxAdapter) inCallerTx(ctx context.Context) bool {
+	_, ok := a.dbFrom(ctx).(pgx.Tx)
+	return ok
+}
+
+// isRetryable reports whether err is a serialization failure or a deadlock
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
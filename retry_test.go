@@ -0,0 +1,81 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithRetry(t *testing.T) {
+	tableName := "casbin_test_retry"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		failures int
+		attempts int
+		wantErr  bool
+	}{
+		{name: "recovers_within_attempts", failures: 2, attempts: 3},
+		{name: "gives_up_after_attempts", failures: 3, attempts: 3, wantErr: true},
+		{name: "no_retry_by_default", failures: 1, attempts: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, err := pgxadapter.NewAdapterWithPool(pool,
+				pgxadapter.WithTableName(tableName),
+				pgxadapter.WithRetry(tt.attempts, 10*time.Millisecond),
+			)
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			// Sequences are not transactional, so the trigger fails exactly the
+			// first tt.failures inserts with a serialization failure
+			setup := []string{
+				"DELETE FROM " + tableName,
+				"DROP SEQUENCE IF EXISTS casbin_test_retry_seq",
+				"CREATE SEQUENCE casbin_test_retry_seq",
+				`CREATE OR REPLACE FUNCTION casbin_test_retry_fail() RETURNS trigger AS $$
+				BEGIN
+					IF nextval('casbin_test_retry_seq') <= ` + strconv.Itoa(tt.failures) + ` THEN
+						RAISE EXCEPTION 'forced serialization failure' USING ERRCODE = '40001';
+					END IF;
+					RETURN NULL;
+				END;
+				$$ LANGUAGE plpgsql`,
+				"DROP TRIGGER IF EXISTS casbin_test_retry_fail ON " + tableName,
+				"CREATE TRIGGER casbin_test_retry_fail BEFORE INSERT ON " + tableName +
+					" FOR EACH STATEMENT EXECUTE FUNCTION casbin_test_retry_fail()",
+			}
+			for _, stmt := range setup {
+				if _, err := pool.Exec(ctx, stmt); err != nil {
+					t.Fatalf("Failed to execute %q: %v", stmt, err)
+				}
+			}
+			t.Cleanup(func() {
+				_, _ = pool.Exec(ctx, "DROP SEQUENCE IF EXISTS casbin_test_retry_seq")
+			})
+
+			err = adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{{"alice", "data1", "read"}})
+			if tt.wantErr {
+				var pgErr *pgconn.PgError
+				if !errors.As(err, &pgErr) || pgErr.Code != "40001" {
+					t.Errorf("Expected a serialization failure, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddPolicies unexpected error: %v", err)
+			}
+		})
+	}
+}
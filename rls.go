@@ -0,0 +1,184 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// tenantSetting is the setting the row level security policy compares tenant_id with
+const tenantSetting = "app.tenant_id"
+
+// WithRowLevelSecurity enforces the tenant scoping of WithTenant in the
+// database as well: row level security is enabled and forced on the table,
+// with a policy only admitting rows whose tenant_id equals the app.tenant_id
+// setting, and the adapter sets app.tenant_id to the tenant of WithTenant or
+// WithTenantResolver. Even a query that forgot the tenant condition, or SQL
+// run through GetDB, then only sees and writes the rules of the tenant. It
+// enables WithTenantColumn.
+//
+// With a pool every statement runs in a transaction setting app.tenant_id
+// locally, so a connection never carries a tenant over to its next user. A
+// connection is set up once for its session, and a transaction once for
+// itself. Transactions passed through the context must set app.tenant_id
+// themselves. Superusers and roles with BYPASSRLS are not restricted.
+func WithRowLevelSecurity() Option {
+	return func(a *PgxAdapter) {
+		a.useTenantColumn = true
+		a.rowLevelSecurity = true
+	}
+}
+
+// installRowLevelSecurity enables row level security on the table and
+// creates or replaces its tenant policy
+func (a *PgxAdapter) installRowLevelSecurity(ctx context.Context) error {
+	if !a.rowLevelSecurity {
+		return nil
+	}
+
+	name := pgx.Identifier{a.tableName + "_tenant"}.Sanitize()
+	check := tenantColumn + " = current_setting('" + tenantSetting + "', true)"
+
+	// FORCE applies the policy to the table owner, which the adapter usually connects as
+	ddl := `ALTER TABLE ` + a.quotedTableName() + ` ENABLE ROW LEVEL SECURITY;
+		ALTER TABLE ` + a.quotedTableName() + ` FORCE ROW LEVEL SECURITY;
+		DROP POLICY IF EXISTS ` + name + ` ON ` + a.quotedTableName() + `;
+		CREATE POLICY ` + name + ` ON ` + a.quotedTableName() + `
+			USING (` + check + `) WITH CHECK (` + check + `)`
+
+	if _, err := a.db.Exec(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to create row level security policy: %w", err)
+	}
+	return nil
+}
+
+// setTenantSetting sets app.tenant_id for the session of a connection or the
+// rest of a transaction. Pools set it per statement through tenantPool.
+func (a *PgxAdapter) setTenantSetting(ctx context.Context) error {
+	if !a.rowLevelSecurity || a.pool != nil {
+		return nil
+	}
+	local := a.conn == nil
+	if _, err := a.db.Exec(ctx, "SELECT set_config($1, $2, $3)", tenantSetting, a.tenant, local); err != nil {
+		return fmt.Errorf("failed to set tenant: %w", err)
+	}
+	return nil
+}
+
+// tenantPool runs every statement on pool in a transaction setting app.tenant_id
+// locally, to the tenant of the statement's context
+type tenantPool struct {
+	pool   *pgxpool.Pool
+	tenant func(ctx context.Context) string
+}
+
+// Exec runs sql in its own tenant transaction
+func (p *tenantPool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tx, err := p.Begin(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return tag, err
+	}
+	return tag, tx.Commit(ctx)
+}
+
+// Query runs sql in a tenant transaction that ends once the rows are closed or read
+func (p *tenantPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	tx, err := p.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, err
+	}
+	return &tenantRows{Rows: rows, ctx: ctx, tx: tx}, nil
+}
+
+// QueryRow runs sql in its own tenant transaction when the row is scanned
+func (p *tenantPool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return tenantRow(func(dest ...any) error {
+		tx, err := p.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		if err := tx.QueryRow(ctx, sql, args...).Scan(dest...); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	})
+}
+
+// Begin starts a transaction with app.tenant_id set for its duration
+func (p *tenantPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	return p.BeginTx(ctx, pgx.TxOptions{})
+}
+
+// BeginTx starts a transaction with txOptions and app.tenant_id set for its duration
+func (p *tenantPool) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	tx, err := p.pool.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, "SELECT set_config($1, $2, true)", tenantSetting, p.tenant(ctx)); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, fmt.Errorf("failed to set tenant: %w", err)
+	}
+	return tx, nil
+}
+
+// tenantRows commits the transaction of tenantPool.Query once the rows are done
+type tenantRows struct {
+	pgx.Rows
+	ctx  context.Context
+	tx   pgx.Tx
+	done bool
+}
+
+// Next advances to the next row and ends the transaction after the last one
+func (r *tenantRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.finish()
+	return false
+}
+
+// Close closes the rows and ends the transaction
+func (r *tenantRows) Close() {
+	r.Rows.Close()
+	r.finish()
+}
+
+// finish commits the transaction, or rolls it back if reading the rows failed
+func (r *tenantRows) finish() {
+	if r.done {
+		return
+	}
+	r.done = true
+	if r.Rows.Err() != nil {
+		_ = r.tx.Rollback(r.ctx)
+		return
+	}
+	_ = r.tx.Commit(r.ctx)
+}
+
+// tenantRow is a pgx.Row running its query when scanned
+type tenantRow func(dest ...any) error
+
+// Scan runs the query and scans its first row into dest
+func (r tenantRow) Scan(dest ...any) error {
+	return r(dest...)
+}
@@ -0,0 +1,90 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v3/model"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithRowLevelSecurity(t *testing.T) {
+	tableName := "casbin_test_rls"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	newAdapter := func(tenant string) *pgxadapter.PgxAdapter {
+		adapter, err := pgxadapter.NewAdapterWithPool(pool,
+			pgxadapter.WithTableName(tableName),
+			pgxadapter.WithTenant(tenant),
+			pgxadapter.WithRowLevelSecurity(),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		return adapter
+	}
+	acme := newAdapter("acme")
+	beta := newAdapter("beta")
+
+	if err := acme.AddPoliciesCtx(ctx, "p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+	if err := beta.AddPolicyCtx(ctx, "p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	m, err := model.NewModelFromString(TestModelText)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	if err := beta.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if policies, _ := m.GetPolicy("p", "p"); len(policies) != 1 {
+		t.Errorf("Expected only the rule of beta, got %v", policies)
+	}
+
+	// Superusers bypass row level security, so check the policy as a plain role
+	if _, err := pool.Exec(ctx, `DO $$ BEGIN CREATE ROLE casbin_rls_reader; EXCEPTION WHEN duplicate_object THEN NULL; END $$`); err != nil {
+		t.Fatalf("Failed to create role: %v", err)
+	}
+	if _, err := pool.Exec(ctx, "GRANT SELECT ON "+tableName+" TO casbin_rls_reader"); err != nil {
+		t.Fatalf("Failed to grant select: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tenant string
+		want   int
+	}{
+		{name: "tenant sees its own rules", tenant: "acme", want: 2},
+		{name: "other tenant sees its own rules", tenant: "beta", want: 1},
+		{name: "unknown tenant sees nothing", tenant: "gamma", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := pool.Begin(ctx)
+			if err != nil {
+				t.Fatalf("Failed to begin transaction: %v", err)
+			}
+			defer tx.Rollback(ctx)
+
+			if _, err := tx.Exec(ctx, "SET LOCAL ROLE casbin_rls_reader"); err != nil {
+				t.Fatalf("Failed to set role: %v", err)
+			}
+			if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", tt.tenant); err != nil {
+				t.Fatalf("Failed to set tenant: %v", err)
+			}
+
+			var count int
+			if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM "+tableName).Scan(&count); err != nil {
+				t.Fatalf("Failed to count rules: %v", err)
+			}
+			if count != tt.want {
+				t.Errorf("Expected %d visible rules, got %d", tt.want, count)
+			}
+		})
+	}
+}
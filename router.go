@@ -0,0 +1,79 @@
+package pgxadapter
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// tenantSchemaPrefix prefixes the schema of every tenant of a TenantRouter
+const tenantSchemaPrefix = "tenant_"
+
+// TenantRouter hands out adapters for tenants kept in schemas of their own,
+// such as tenant_acme.casbin_rule and tenant_beta.casbin_rule, all sharing
+// one pool. Adapters are created on first use and cached, so each tenant's
+// table is only checked once. It is safe for concurrent use.
+type TenantRouter struct {
+	pool *pgxpool.Pool
+	opts []Option
+
+	mu       sync.Mutex
+	adapters map[string]*PgxAdapter
+}
+
+// NewTenantRouter creates a router handing out adapters on pool, created
+// with opts. WithSchema is set per tenant and must not be part of opts.
+func NewTenantRouter(pool *pgxpool.Pool, opts ...Option) *TenantRouter {
+	return &TenantRouter{
+		pool:     pool,
+		opts:     opts,
+		adapters: make(map[string]*PgxAdapter),
+	}
+}
+
+// TenantSchema returns the schema holding the rules of tenant
+func (r *TenantRouter) TenantSchema(tenant string) string {
+	return tenantSchemaPrefix + tenant
+}
+
+// Adapter returns the adapter of tenant. The tenant must have been
+// provisioned; otherwise the error wraps ErrTableNotReady.
+func (r *TenantRouter) Adapter(ctx context.Context, tenant string) (*PgxAdapter, error) {
+	return r.adapter(ctx, tenant, WithSkipTableCreate())
+}
+
+// Provision creates the schema and table of tenant if they do not exist,
+// like the adapter does for its table, and returns the tenant's adapter.
+func (r *TenantRouter) Provision(ctx context.Context, tenant string) (*PgxAdapter, error) {
+	return r.adapter(ctx, tenant)
+}
+
+// adapter returns the cached adapter of tenant or creates it with the router's options and extra
+func (r *TenantRouter) adapter(ctx context.Context, tenant string, extra ...Option) (*PgxAdapter, error) {
+	if tenant == "" {
+		return nil, errors.New("tenant must not be empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if a, ok := r.adapters[tenant]; ok {
+		return a, nil
+	}
+
+	// Lazy init creates or checks the table with ctx rather than context.Background
+	opts := append(slices.Clone(r.opts), WithSchema(r.TenantSchema(tenant)), WithLazyInit())
+	a, err := NewAdapterWithPool(r.pool, append(opts, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.ensureInit(ctx); err != nil {
+		return nil, err
+	}
+
+	r.adapters[tenant] = a
+	return a, nil
+}
@@ -0,0 +1,69 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestTenantRouter(t *testing.T) {
+	tableName := "casbin_test_router"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	router := pgxadapter.NewTenantRouter(pool, pgxadapter.WithTableName(tableName))
+	tenants := []string{"router_acme", "router_beta"}
+	for _, tenant := range tenants {
+		schema := router.TenantSchema(tenant)
+		_, _ = pool.Exec(ctx, "DROP SCHEMA IF EXISTS "+schema+" CASCADE")
+		t.Cleanup(func() {
+			_, _ = pool.Exec(ctx, "DROP SCHEMA IF EXISTS "+schema+" CASCADE")
+		})
+	}
+
+	if _, err := router.Adapter(ctx, "router_acme"); !errors.Is(err, pgxadapter.ErrTableNotReady) {
+		t.Fatalf("Expected ErrTableNotReady before provisioning, got %v", err)
+	}
+
+	for _, tenant := range tenants {
+		adapter, err := router.Provision(ctx, tenant)
+		if err != nil {
+			t.Fatalf("Failed to provision %s: %v", tenant, err)
+		}
+		if adapter.GetSchema() != "tenant_"+tenant {
+			t.Errorf("Expected schema tenant_%s, got %q", tenant, adapter.GetSchema())
+		}
+	}
+
+	acme, err := router.Adapter(ctx, "router_acme")
+	if err != nil {
+		t.Fatalf("Failed to get adapter: %v", err)
+	}
+	if again, _ := router.Adapter(ctx, "router_acme"); again != acme {
+		t.Error("Expected the cached adapter to be returned")
+	}
+	if err := acme.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	tests := []struct {
+		tenant string
+		want   int
+	}{
+		{tenant: "router_acme", want: 1},
+		{tenant: "router_beta", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tenant, func(t *testing.T) {
+			var count int
+			if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+router.TenantSchema(tt.tenant)+"."+tableName).Scan(&count); err != nil {
+				t.Fatalf("Failed to count rules: %v", err)
+			}
+			if count != tt.want {
+				t.Errorf("Expected %d rules, got %d", tt.want, count)
+			}
+		})
+	}
+}
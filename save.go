@@ -0,0 +1,67 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveMode controls how SavePolicy replaces the stored rules
+type SaveMode int
+
+const (
+	// SaveModeReplace deletes the stored rules and inserts every rule of the
+	// model. With WithTenantColumn only the rules of the tenant are replaced.
+	SaveModeReplace SaveMode = iota
+	// SaveModeUpsertDiff inserts the rules missing from the table and deletes
+	// the rows missing from the model, leaving unchanged rows untouched. It
+	// reaches the same end state as SaveModeReplace without rewriting every row,
+	// so unchanged rows keep their ids, timestamps and metadata, fire no
+	// triggers and cause no index churn.
+	SaveModeUpsertDiff
+)
+
+// WithSaveMode sets how SavePolicy replaces the stored rules. Defaults to SaveModeReplace.
+func WithSaveMode(mode SaveMode) Option {
+	return func(a *PgxAdapter) {
+		a.saveMode = mode
+	}
+}
+
+// savePolicyDiff makes the table hold exactly the given rules, writing only the rows that differ.
+// The rules are staged in a temporary table so the difference is computed by the database.
+// It returns the number of rows removed and inserted.
+func (a *PgxAdapter) savePolicyDiff(ctx context.Context, tx pgx.Tx, ptypes []string, lines [][]string) (int64, error) {
+	quotedTableName := a.quotedTableName()
+	columns := strings.Join(a.insertColumns(), ", ")
+
+	if err := a.stageRules(ctx, tx, saveStagingTable, ptypes, lines); err != nil {
+		return 0, err
+	}
+
+	exprs := a.stagedRuleExprs()
+	deleteSQL, args, err := a.deleteRules(ctx).
+		Where("(" + exprs + ") NOT IN (SELECT " + exprs + " FROM " + saveStagingTable + ")").
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build delete query: %w", err)
+	}
+	removed, err := tx.Exec(ctx, deleteSQL, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove policies: %w", err)
+	}
+
+	insertSQL := "INSERT INTO " + quotedTableName + " (" + columns + ") SELECT " + columns +
+		" FROM " + saveStagingTable + " " + a.onConflictDoNothing()
+	inserted, err := tx.Exec(ctx, insertSQL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert policies: %w", mapWriteError(err))
+	}
+	if err := a.dropStaging(ctx, tx, saveStagingTable); err != nil {
+		return 0, err
+	}
+
+	return removed.RowsAffected() + inserted.RowsAffected(), nil
+}
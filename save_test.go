@@ -0,0 +1,216 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/jackc/pgx/v5"
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestSavePolicyUpsertDiff(t *testing.T) {
+	tableName := "casbin_test_save_upsert_diff"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithSaveMode(pgxadapter.SaveModeUpsertDiff),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	policies := [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"carol", "data3", "read"},
+	}
+	if err := adapter.AddPolicies("p", "p", policies); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+
+	idsBefore := policyIDs(t, conn, tableName)
+
+	// Keep alice and bob, drop carol and add dave
+	m, _ := model.NewModelFromString(TestModelText)
+	m.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	m.AddPolicy("p", "p", []string{"bob", "data2", "write"})
+	m.AddPolicy("p", "p", []string{"dave", "data4", "read"})
+	m.AddPolicy("g", "g", []string{"alice", "admin"})
+
+	if err := adapter.SavePolicy(m); err != nil {
+		t.Fatalf("SavePolicy() unexpected error: %v", err)
+	}
+
+	idsAfter := policyIDs(t, conn, tableName)
+
+	for _, subject := range []string{"alice", "bob"} {
+		if idsAfter[subject] != idsBefore[subject] {
+			t.Errorf("Unchanged rule for %s was rewritten: id %d became %d", subject, idsBefore[subject], idsAfter[subject])
+		}
+	}
+	if _, ok := idsAfter["carol"]; ok {
+		t.Error("Expected rule missing from the model to be deleted")
+	}
+	if _, ok := idsAfter["dave"]; !ok {
+		t.Error("Expected rule new in the model to be inserted")
+	}
+	if len(idsAfter) != 3 {
+		t.Errorf("Table has %d distinct subjects, want 3", len(idsAfter))
+	}
+
+	loaded, _ := model.NewModelFromString(TestModelText)
+	if err := adapter.LoadPolicy(loaded); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	pPolicies, _ := loaded.GetPolicy("p", "p")
+	gPolicies, _ := loaded.GetPolicy("g", "g")
+	if len(pPolicies) != 3 || len(gPolicies) != 1 {
+		t.Errorf("Loaded %d p and %d g rules, want 3 and 1", len(pPolicies), len(gPolicies))
+	}
+}
+
+// policyIDs returns the row id of every rule keyed by its v0
+func policyIDs(t *testing.T, conn *pgx.Conn, tableName string) map[string]int {
+	t.Helper()
+
+	rows, err := conn.Query(context.Background(), "SELECT id, v0 FROM "+tableName+" WHERE ptype = 'p'")
+	if err != nil {
+		t.Fatalf("Failed to query ids: %v", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int)
+	for rows.Next() {
+		var id int
+		var v0 string
+		if err := rows.Scan(&id, &v0); err != nil {
+			t.Fatalf("Failed to scan id: %v", err)
+		}
+		ids[v0] = id
+	}
+	return ids
+}
+
+func TestSavePolicyLarge(t *testing.T) {
+	tableName := "casbin_test_save_large"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	if err := adapter.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}}); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+
+	const rules = 100000
+	m, _ := model.NewModelFromString(TestModelText)
+	for i := range rules {
+		m.AddPolicy("p", "p", []string{fmt.Sprintf("user%d", i), "data", "read"})
+	}
+
+	// Readers keep seeing the old rules while the save runs
+	stop := make(chan struct{})
+	sawEmpty := make(chan bool, 1)
+	go func() {
+		empty := false
+		defer func() { sawEmpty <- empty }()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			var count int
+			if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+tableName).Scan(&count); err == nil && count == 0 {
+				empty = true
+			}
+		}
+	}()
+
+	start := time.Now()
+	err = adapter.SavePolicyCtx(ctx, m)
+	close(stop)
+	if err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+	t.Logf("Saved %d rules in %v", rules, time.Since(start))
+
+	if <-sawEmpty {
+		t.Error("A concurrent reader observed an empty table during SavePolicy")
+	}
+
+	var count int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+tableName).Scan(&count); err != nil {
+		t.Fatalf("Failed to count rules: %v", err)
+	}
+	if count != rules {
+		t.Errorf("Expected %d rules, got %d", rules, count)
+	}
+}
+
+func TestSavePolicyUpsertDiffKeepsRows(t *testing.T) {
+	ctx := context.Background()
+	tableName := "casbin_test_save_diff_keeps_rows"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithSaveMode(pgxadapter.SaveModeUpsertDiff),
+		pgxadapter.WithTimestamps(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+
+	type row struct {
+		id        int
+		updatedAt time.Time
+	}
+	rowsOf := func() map[string]row {
+		rows, err := conn.Query(ctx, "SELECT id, v0, updated_at FROM "+tableName)
+		if err != nil {
+			t.Fatalf("Failed to query rows: %v", err)
+		}
+		defer rows.Close()
+
+		result := make(map[string]row)
+		for rows.Next() {
+			var r row
+			var v0 string
+			if err := rows.Scan(&r.id, &v0, &r.updatedAt); err != nil {
+				t.Fatalf("Failed to scan row: %v", err)
+			}
+			result[v0] = r
+		}
+		return result
+	}
+	before := rowsOf()
+
+	m, _ := model.NewModelFromString(TestModelText)
+	m.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	m.AddPolicy("p", "p", []string{"carol", "data3", "read"})
+	if err := adapter.SavePolicy(m); err != nil {
+		t.Fatalf("SavePolicy() unexpected error: %v", err)
+	}
+
+	after := rowsOf()
+	if after["alice"] != before["alice"] {
+		t.Errorf("Unchanged row was rewritten: %+v became %+v", before["alice"], after["alice"])
+	}
+	if _, ok := after["bob"]; ok {
+		t.Error("Expected the rule missing from the model to be deleted")
+	}
+	if _, ok := after["carol"]; !ok {
+		t.Error("Expected the rule new in the model to be inserted")
+	}
+}
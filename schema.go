@@ -0,0 +1,47 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// WithSchema keeps the adapter's table and every table, function and index it
+// creates in the given Postgres schema instead of the one first on the
+// search_path, usually public. The schema is created if it does not exist and
+// all generated SQL is schema-qualified, so search_path does not matter.
+func WithSchema(schema string) Option {
+	return func(a *PgxAdapter) {
+		a.schema = schema
+	}
+}
+
+// GetSchema returns the schema set with WithSchema, empty for the search_path default
+func (a *PgxAdapter) GetSchema() string {
+	return a.schema
+}
+
+// qualifiedIdentifier returns name qualified with the adapter's schema, if any
+func (a *PgxAdapter) qualifiedIdentifier(name string) pgx.Identifier {
+	if a.schema == "" {
+		return pgx.Identifier{name}
+	}
+	return pgx.Identifier{a.schema, name}
+}
+
+// qualifiedName returns the sanitized, schema-qualified identifier of name
+func (a *PgxAdapter) qualifiedName(name string) string {
+	return a.qualifiedIdentifier(name).Sanitize()
+}
+
+// createSchemaNamespace creates the schema set with WithSchema if it does not exist
+func (a *PgxAdapter) createSchemaNamespace(ctx context.Context) error {
+	if a.schema == "" {
+		return nil
+	}
+	if _, err := a.db.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+pgx.Identifier{a.schema}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithSchema(t *testing.T) {
+	tableName := "casbin_test_schema"
+	schema := "casbin_test_authz"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	_, _ = pool.Exec(ctx, "DROP SCHEMA IF EXISTS "+schema+" CASCADE")
+	t.Cleanup(func() {
+		_, _ = pool.Exec(ctx, "DROP SCHEMA IF EXISTS "+schema+" CASCADE")
+	})
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithSchema(schema),
+		pgxadapter.WithPolicyVersion(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	if adapter.GetSchema() != schema {
+		t.Errorf("Expected schema %q, got %q", schema, adapter.GetSchema())
+	}
+
+	m, err := model.NewModelFromString(TestModelText)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("Failed to create enforcer: %v", err)
+	}
+	if _, err := e.AddPolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	tables := []struct {
+		name   string
+		exists bool
+	}{
+		{name: schema + "." + tableName, exists: true},
+		{name: schema + "." + tableName + "_version", exists: true},
+		{name: "public." + tableName, exists: false},
+	}
+	for _, tt := range tables {
+		var exists bool
+		if err := pool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", tt.name).Scan(&exists); err != nil {
+			t.Fatalf("Failed to look up %s: %v", tt.name, err)
+		}
+		if exists != tt.exists {
+			t.Errorf("Expected %s to exist: %v, got %v", tt.name, tt.exists, exists)
+		}
+	}
+
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if ok, _ := e.Enforce("alice", "data1", "read"); !ok {
+		t.Error("Expected the policy to be loaded from the schema")
+	}
+	if version, err := adapter.GetPolicyVersion(ctx); err != nil || version == 0 {
+		t.Errorf("Expected the version trigger to bump the version, got %d (%v)", version, err)
+	}
+}
@@ -0,0 +1,57 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Temporary tables holding rules to compare against the policy table
+const (
+	addStagingTable     = "casbin_add_staging"
+	saveStagingTable    = "casbin_save_staging"
+	removeStagingTable  = "casbin_remove_staging"
+	replaceStagingTable = "casbin_replace_staging"
+)
+
+// stageRules copies rules into the temporary table name. Rules are compared
+// with the policy table through stagedRuleExprs, and the table is dropped with
+// dropStaging once the operation is done with it.
+func (a *PgxAdapter) stageRules(ctx context.Context, tx pgx.Tx, name string, ptypes []string, lines [][]string) error {
+	// CREATE TABLE AS copies the column types but not NOT NULL or defaults.
+	// Every rule column is staged so a comparison never resolves a column of
+	// the staging table to the policy table.
+	if _, err := tx.Exec(ctx, "CREATE TEMP TABLE "+name+" ON COMMIT DROP AS SELECT "+
+		strings.Join(append(a.columns(a.storedColumns()), a.timestampColumns()...), ", ")+" FROM "+a.quotedTableName()+" WITH NO DATA"); err != nil {
+		return fmt.Errorf("failed to create staging table: %w", err)
+	}
+
+	rows := make([][]any, len(lines))
+	for i, line := range lines {
+		rows[i] = a.copyValues(ctx, ptypes[i], line)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{name}, a.copyColumns(), pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("failed to stage policies: %w", err)
+	}
+
+	return nil
+}
+
+// stagedRuleExprs returns the unique index expressions identifying a rule, as a
+// select list. Comparing them compares rules like the unique index, treating
+// NULL and empty values alike.
+func (a *PgxAdapter) stagedRuleExprs() string {
+	return strings.Join(a.uniqueIndexExprs(), ", ")
+}
+
+// dropStaging drops the temporary table name. ON COMMIT DROP alone would keep
+// it until the caller's transaction of WithTx commits, failing the next
+// operation staging into the same table.
+func (a *PgxAdapter) dropStaging(ctx context.Context, tx pgx.Tx, name string) error {
+	if _, err := tx.Exec(ctx, "DROP TABLE "+name); err != nil {
+		return fmt.Errorf("failed to drop staging table: %w", err)
+	}
+	return nil
+}
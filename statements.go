@@ -0,0 +1,124 @@
+package pgxadapter
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// stmtKind identifies a hot path statement in the statement cache
+type stmtKind uint8
+
+const (
+	stmtInsert stmtKind = iota
+	stmtRemove
+	stmtLoad
+)
+
+// stmtKey identifies the SQL of a cached statement: its kind and, for
+// statements matching a rule, the values that are empty and compared with IS NULL
+type stmtKey struct {
+	kind  stmtKind
+	nulls uint64
+}
+
+// cachesStatements reports whether the SQL of AddPolicy, RemovePolicy and
+// LoadPolicy only depends on the adapter's settings, so it can be built once.
+// Stable SQL also lets pgx reuse the statement it prepared for it, where the
+// statement cache mode prepares statements. Array columns take their values
+// through SQL expressions and are not cached.
+func (a *PgxAdapter) cachesStatements() bool {
+	return len(a.arrayColumns) == 0 && a.valueColumnTotal() <= 64
+}
+
+// cachedSQL returns the SQL cached under key, building it with build on first use.
+// The arguments of a cached statement are computed by the caller.
+func (a *PgxAdapter) cachedSQL(key stmtKey, build func() (string, []any, error)) (string, error) {
+	if sql, ok := a.stmts.Load(key); ok {
+		return sql.(string), nil
+	}
+
+	sql, _, err := build()
+	if err != nil {
+		return "", err
+	}
+	a.stmts.Store(key, sql)
+	return sql, nil
+}
+
+// insertRuleSQL returns the statement inserting a rule followed by suffix, and its arguments
+func (a *PgxAdapter) insertRuleSQL(ctx context.Context, ptype string, rule []string, suffix string) (string, []any, error) {
+	build := func() (string, []any, error) {
+		return a.psql.
+			Insert(a.quotedTableName()).
+			Columns(a.insertColumns()...).
+			Values(a.insertValues(ctx, ptype, rule)...).
+			Suffix(suffix).
+			ToSql()
+	}
+	if !a.cachesStatements() {
+		return build()
+	}
+
+	sql, err := a.cachedSQL(stmtKey{kind: stmtInsert}, build)
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, a.insertValues(ctx, ptype, rule), nil
+}
+
+// removeRuleSQL returns the statement deleting a rule, and its arguments
+func (a *PgxAdapter) removeRuleSQL(ctx context.Context, ptype string, rule []string) (string, []any, error) {
+	build := func() (string, []any, error) {
+		return a.deleteRules(ctx).
+			Where(sq.Eq{a.column("ptype"): ptype}).
+			Where(a.ruleEq(ptype, rule)).
+			ToSql()
+	}
+	if !a.cachesStatements() {
+		return build()
+	}
+
+	// The arguments follow the order of the conditions of build
+	values, eft := a.splitEft(ptype, rule)
+	var nulls uint64
+	args := make([]any, 0, a.valueColumnTotal()+3)
+	if a.useTenantColumn {
+		args = append(args, a.tenantOf(ctx))
+	}
+	args = append(args, ptype)
+	for i := range a.valueColumnTotal() {
+		if i < len(values) && values[i] != "" {
+			args = append(args, values[i])
+		} else {
+			nulls |= 1 << i
+		}
+	}
+	if a.useEftColumn {
+		args = append(args, eft)
+	}
+
+	sql, err := a.cachedSQL(stmtKey{kind: stmtRemove, nulls: nulls}, build)
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, args, nil
+}
+
+// loadSQL returns the statement loading every rule, and its arguments
+func (a *PgxAdapter) loadSQL(ctx context.Context) (string, []any, error) {
+	build := func() (string, []any, error) {
+		return a.limitLoad(a.selectRules(ctx, a.selectColumns()...).
+			OrderBy(a.orderBy()...)).
+			ToSql()
+	}
+
+	sql, err := a.cachedSQL(stmtKey{kind: stmtLoad}, build)
+	if err != nil {
+		return "", nil, err
+	}
+	if a.useTenantColumn {
+		return sql, []any{a.tenantOf(ctx)}, nil
+	}
+	return sql, nil, nil
+}
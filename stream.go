@@ -0,0 +1,163 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// StreamAdd writes the rules received on ch into the table through a single
+// COPY stream and returns the number of rows written once ch is closed. COPY
+// consumes rules as fast as the database accepts them, so a slow database
+// slows down the sender. All rules are written in one transaction: if ctx is
+// cancelled, or a rule already exists, the whole stream is rolled back.
+// The write timeout does not apply, as the stream lasts as long as ch is open.
+func (a *PgxAdapter) StreamAdd(ctx context.Context, ch <-chan []string, ptype string) (int64, error) {
+	if err := a.ensureInit(ctx); err != nil {
+		return 0, err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// The source watches ctx itself so a cancellation aborts the COPY cleanly
+	// instead of interrupting the connection, which keeps the rollback possible
+	copyCtx := context.WithoutCancel(ctx)
+
+	tx, err := a.beginWrite(copyCtx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(copyCtx)
+
+	source := &ruleStream{ctx: ctx, ch: ch, ptype: ptype, adapter: a}
+	n, err := tx.CopyFrom(copyCtx, a.qualifiedIdentifier(a.tableName), a.copyColumns(), source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stream policies: %w", mapWriteError(err))
+	}
+
+	if err := a.writeAudit(copyCtx, tx, AuditOpAdd, source.changes); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(copyCtx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	a.maybeAnalyze(copyCtx, n)
+
+	return n, nil
+}
+
+// ruleStream is a pgx.CopyFromSource reading rules from a channel
+type ruleStream struct {
+	ctx     context.Context
+	ch      <-chan []string
+	ptype   string
+	adapter *PgxAdapter
+
+	rule    []string
+	changes []auditChange
+	err     error
+}
+
+// Next waits for the next rule, it returns false once ch is closed or ctx is done
+func (s *ruleStream) Next() bool {
+	select {
+	case rule, ok := <-s.ch:
+		if !ok {
+			return false
+		}
+		s.rule = rule
+		if s.adapter.auditTable != "" {
+			s.changes = append(s.changes, auditChange{ptype: s.ptype, newRule: rule})
+		}
+		return true
+	case <-s.ctx.Done():
+		s.err = s.ctx.Err()
+		return false
+	}
+}
+
+// Values returns the current rule in insertColumns order
+func (s *ruleStream) Values() ([]any, error) {
+	return s.adapter.copyValues(s.ctx, s.ptype, s.rule), nil
+}
+
+// Err returns the context error if the stream was cancelled
+func (s *ruleStream) Err() error {
+	return s.err
+}
+
+// StreamPolicies sends the rules matching filter, or every rule if filter is nil,
+// on the returned channel as ptype followed by its values, and closes it once
+// all rules were sent. A failure, including the cancellation of ctx, is sent
+// on the error channel, which is closed after the rule channel. The query is
+// released as soon as ctx is done, so consumers that stop early must cancel ctx.
+func (a *PgxAdapter) StreamPolicies(ctx context.Context, filter *Filter) (<-chan []string, <-chan error) {
+	out := make(chan []string)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(out)
+
+		if err := a.streamPolicies(ctx, filter, out); err != nil {
+			errc <- err
+		}
+	}()
+
+	return out, errc
+}
+
+// streamPolicies sends the rules matching filter on out until done or ctx is cancelled
+func (a *PgxAdapter) streamPolicies(ctx context.Context, filter *Filter, out chan<- []string) error {
+	ctx, cancel := a.readContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	var conds sq.Sqlizer = sq.And{}
+	if filter != nil {
+		conds = a.filterConditions(*filter)
+	}
+
+	sql, args, err := a.filteredSelect(ctx, conds).ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := a.dbFrom(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query policies: %w", err)
+	}
+	defer rows.Close()
+
+	scanner := a.newRuleScanner()
+	for rows.Next() {
+		line, err := scanner.scanLine(rows, nil)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case out <- line:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestStreamAdd(t *testing.T) {
+	tableName := "casbin_test_stream_add"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	const total = 50000
+	ch := make(chan []string)
+	go func() {
+		defer close(ch)
+		for i := range total {
+			ch <- []string{fmt.Sprintf("user%d", i), "data1", "read"}
+		}
+	}()
+
+	n, err := adapter.StreamAdd(context.Background(), ch, "p")
+	if err != nil {
+		t.Fatalf("StreamAdd() unexpected error: %v", err)
+	}
+	if n != total {
+		t.Errorf("StreamAdd() = %d, want %d", n, total)
+	}
+
+	var count int
+	if err := conn.QueryRow(context.Background(), "SELECT COUNT(*) FROM "+tableName).Scan(&count); err != nil {
+		t.Fatalf("Failed to count policies: %v", err)
+	}
+	if count != total {
+		t.Errorf("Table has %d policies, want %d", count, total)
+	}
+}
+
+func TestStreamAddCancel(t *testing.T) {
+	tableName := "casbin_test_stream_add_cancel"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan []string)
+	go func() {
+		for i := range 100 {
+			ch <- []string{fmt.Sprintf("user%d", i), "data1", "read"}
+		}
+		// Leave the channel open so only the cancellation ends the stream
+		cancel()
+	}()
+
+	if _, err := adapter.StreamAdd(ctx, ch, "p"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("StreamAdd() error = %v, want context.Canceled", err)
+	}
+
+	var count int
+	if err := conn.QueryRow(context.Background(), "SELECT COUNT(*) FROM "+tableName).Scan(&count); err != nil {
+		t.Fatalf("Failed to count policies: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Table has %d policies after cancellation, want 0", count)
+	}
+}
+
+func TestStreamPolicies(t *testing.T) {
+	tableName := "casbin_test_stream_policies"
+	pool := setupTestPool(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	rules := make([][]string, 1000)
+	for i := range rules {
+		rules[i] = []string{fmt.Sprintf("user%d", i), "data1", "read"}
+	}
+	if err := adapter.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+	if err := adapter.AddPolicy("g", "g", []string{"user0", "admin"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	t.Run("filtered", func(t *testing.T) {
+		out, errc := adapter.StreamPolicies(context.Background(), &pgxadapter.Filter{Ptype: []string{"g"}})
+
+		var lines [][]string
+		for line := range out {
+			lines = append(lines, line)
+		}
+		if err := <-errc; err != nil {
+			t.Fatalf("StreamPolicies() unexpected error: %v", err)
+		}
+		if len(lines) != 1 || lines[0][0] != "g" {
+			t.Errorf("StreamPolicies() = %v, want the single g rule", lines)
+		}
+	})
+
+	t.Run("cancel_mid_stream", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		out, errc := adapter.StreamPolicies(ctx, nil)
+
+		for range 3 {
+			if _, ok := <-out; !ok {
+				t.Fatal("Stream closed before three rules were read")
+			}
+		}
+		cancel()
+
+		// The stream must close without the rest being consumed
+		for range out {
+		}
+		if err := <-errc; !errors.Is(err, context.Canceled) {
+			t.Errorf("StreamPolicies() error = %v, want context.Canceled", err)
+		}
+
+		if acquired := pool.Stat().AcquiredConns(); acquired != 0 {
+			t.Errorf("Expected the query connection to be released, %d still acquired", acquired)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// WithSubjectField sets the index of the rule value holding the subject, used
+// by ReplaceSubjectPolicies. Defaults to 0, the v0 column.
+func WithSubjectField(index int) Option {
+	return func(a *PgxAdapter) {
+		a.subjectField = index
+	}
+}
+
+// ReplaceSubjectPolicies makes rules the complete set of ptype rules of subject
+// in one transaction: stored rules of the subject missing from rules are
+// deleted and rules not yet stored are inserted. Rules that are already stored
+// are left untouched, so replacing with identical rules changes nothing.
+// Every rule must hold subject at the subject field, see WithSubjectField.
+func (a *PgxAdapter) ReplaceSubjectPolicies(ctx context.Context, ptype string, subject string, rules [][]string) (added, removed int64, err error) {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return 0, 0, err
+	}
+	ctx, err = a.resolveTenant(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if a.subjectField < 0 || a.subjectField >= a.valueColumnTotal() {
+		return 0, 0, fmt.Errorf("invalid subject field index: %d", a.subjectField)
+	}
+
+	ptypes := make([]string, len(rules))
+	for i, rule := range rules {
+		if len(rule) <= a.subjectField || rule[a.subjectField] != subject {
+			return 0, 0, fmt.Errorf("rule at index %d does not belong to subject %s", i, subject)
+		}
+		ptypes[i] = ptype
+	}
+
+	tx, err := a.beginWrite(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := a.stageRules(ctx, tx, replaceStagingTable, ptypes, rules); err != nil {
+		return 0, 0, err
+	}
+
+	exprs := a.stagedRuleExprs()
+	deleteSQL, args, err := a.deleteRules(ctx).
+		Where(sq.Eq{a.column("ptype"): ptype}).
+		Where(a.columnEq(a.fieldColumn(ptype, a.subjectField), subject)).
+		Where("(" + exprs + ") NOT IN (SELECT " + exprs + " FROM " + replaceStagingTable + ")").
+		ToSql()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	removed, err = a.execAudited(ctx, tx, AuditOpRemove, deleteSQL, args...)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to remove policies: %w", err)
+	}
+
+	columns := strings.Join(a.insertColumns(), ", ")
+	insertSQL := "INSERT INTO " + a.quotedTableName() + " (" + columns + ") SELECT " + columns +
+		" FROM " + replaceStagingTable + " " + a.onConflictDoNothing()
+
+	added, err = a.execAudited(ctx, tx, AuditOpAdd, insertSQL)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to insert policies: %w", mapWriteError(err))
+	}
+	if err := a.dropStaging(ctx, tx, replaceStagingTable); err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return added, removed, nil
+}
@@ -0,0 +1,124 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/casbin/casbin/v3/model"
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestReplaceSubjectPolicies(t *testing.T) {
+	tests := []struct {
+		name            string
+		rules           [][]string
+		expectedAdded   int64
+		expectedRemoved int64
+	}{
+		{
+			name: "add_rules",
+			rules: [][]string{
+				{"alice", "data1", "read"},
+				{"alice", "data1", "write"},
+				{"alice", "data2", "read"},
+				{"alice", "data3", "read"},
+			},
+			expectedAdded: 2,
+		},
+		{
+			name: "remove_rules",
+			rules: [][]string{
+				{"alice", "data1", "read"},
+			},
+			expectedRemoved: 1,
+		},
+		{
+			name: "replace_rules",
+			rules: [][]string{
+				{"alice", "data3", "write"},
+			},
+			expectedAdded:   1,
+			expectedRemoved: 2,
+		},
+		{
+			name: "identical_rules",
+			rules: [][]string{
+				{"alice", "data1", "read"},
+				{"alice", "data1", "write"},
+			},
+		},
+		{
+			name:            "no_rules",
+			expectedRemoved: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tableName := fmt.Sprintf("casbin_test_replace_subject_%s", tt.name)
+			conn := setupTestDB(t, tableName)
+
+			adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			if err := adapter.AddPolicies("p", "p", [][]string{
+				{"alice", "data1", "read"},
+				{"alice", "data1", "write"},
+				{"bob", "data1", "read"},
+			}); err != nil {
+				t.Fatalf("Failed to add policies: %v", err)
+			}
+
+			added, removed, err := adapter.ReplaceSubjectPolicies(context.Background(), "p", "alice", tt.rules)
+			if err != nil {
+				t.Fatalf("ReplaceSubjectPolicies() unexpected error: %v", err)
+			}
+			if added != tt.expectedAdded || removed != tt.expectedRemoved {
+				t.Errorf("ReplaceSubjectPolicies() = (%d, %d), want (%d, %d)", added, removed, tt.expectedAdded, tt.expectedRemoved)
+			}
+
+			m, _ := model.NewModelFromString(TestModelText)
+			if err := adapter.LoadPolicy(m); err != nil {
+				t.Fatalf("Failed to load policy: %v", err)
+			}
+
+			alice, _ := m.GetFilteredPolicy("p", "p", 0, "alice")
+			if len(alice) != len(tt.rules) {
+				t.Errorf("alice has %d rules, want %d", len(alice), len(tt.rules))
+			}
+			for _, rule := range tt.rules {
+				if ok, _ := m.HasPolicy("p", "p", rule); !ok {
+					t.Errorf("Expected rule %v to be stored", rule)
+				}
+			}
+			if ok, _ := m.HasPolicy("p", "p", []string{"bob", "data1", "read"}); !ok {
+				t.Error("Expected rules of other subjects to be untouched")
+			}
+		})
+	}
+}
+
+func TestReplaceSubjectPoliciesForeignRule(t *testing.T) {
+	tableName := "casbin_test_replace_subject_foreign"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithSubjectField(1),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	if _, _, err := adapter.ReplaceSubjectPolicies(context.Background(), "p", "data1", [][]string{
+		{"alice", "data1", "read"},
+		{"alice", "data2", "read"},
+	}); err == nil {
+		t.Error("Expected a rule of another subject to be rejected")
+	}
+}
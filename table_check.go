@@ -0,0 +1,40 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// WithSkipTableCreate makes the adapter run no DDL at all, for roles that are
+// not allowed to create tables. Instead of creating the table, its indexes and
+// any trigger, the adapter checks at startup that the table, its columns and
+// its unique index exist, and fails with an error wrapping ErrTableNotReady
+// if they do not. Options that need DDL of their own, such as
+// WithChangeTrigger or WithPolicyVersion, expect it to have been run already.
+func WithSkipTableCreate() Option {
+	return func(a *PgxAdapter) {
+		a.skipTableCreate = true
+	}
+}
+
+// checkTable checks that the table created by createSchema exists
+func (a *PgxAdapter) checkTable(ctx context.Context) error {
+	d := Diagnostics{UniqueIndexName: a.uniqueIndexName()}
+	if err := a.inspectTable(ctx, &d); err != nil {
+		return err
+	}
+
+	if !d.TableExists {
+		return fmt.Errorf("%w: table %s does not exist", ErrTableNotReady, a.quotedTableName())
+	}
+	if len(d.MissingColumns) > 0 {
+		return fmt.Errorf("%w: table %s is missing columns: %s",
+			ErrTableNotReady, a.quotedTableName(), strings.Join(d.MissingColumns, ", "))
+	}
+	if !d.UniqueIndexExists {
+		return fmt.Errorf("%w: table %s has no unique index %s",
+			ErrTableNotReady, a.quotedTableName(), d.UniqueIndexName)
+	}
+	return nil
+}
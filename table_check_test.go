@@ -0,0 +1,51 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithSkipTableCreate(t *testing.T) {
+	tableName := "casbin_test_skip_create"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	newAdapter := func() error {
+		_, err := pgxadapter.NewAdapterWithPool(pool,
+			pgxadapter.WithTableName(tableName),
+			pgxadapter.WithSkipTableCreate(),
+		)
+		return err
+	}
+
+	if err := newAdapter(); !errors.Is(err, pgxadapter.ErrTableNotReady) {
+		t.Fatalf("Expected ErrTableNotReady for a missing table, got %v", err)
+	}
+	var exists bool
+	if err := pool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", tableName).Scan(&exists); err != nil {
+		t.Fatalf("Failed to look up table: %v", err)
+	}
+	if exists {
+		t.Fatal("Expected the table not to be created")
+	}
+
+	// Provisioned by a migration role
+	if _, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName)); err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	if err := newAdapter(); err != nil {
+		t.Fatalf("Expected the provisioned table to pass the check, got %v", err)
+	}
+
+	if _, err := pool.Exec(ctx, "DROP INDEX "+pgx.Identifier{"idx_" + tableName}.Sanitize()); err != nil {
+		t.Fatalf("Failed to drop index: %v", err)
+	}
+	if err := newAdapter(); !errors.Is(err, pgxadapter.ErrTableNotReady) {
+		t.Errorf("Expected ErrTableNotReady for a missing unique index, got %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package pgxadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// Column scoping rules to a tenant, see WithTenantColumn
+const (
+	tenantColumn     = "tenant_id"
+	tenantColumnType = "VARCHAR(100) NOT NULL DEFAULT ''"
+)
+
+// WithTenantColumn adds a tenant_id column, so one table can back many
+// isolated enforcers. Every rule read, written or removed by the adapter is
+// scoped to the tenant set with WithTenant, the empty tenant by default, and
+// the unique index includes tenant_id, so tenants can hold the same rules.
+// Existing tables get the column with the empty tenant for their rows. A
+// constraint named with WithExistingUniqueConstraint must include tenant_id.
+func WithTenantColumn() Option {
+	return func(a *PgxAdapter) {
+		a.useTenantColumn = true
+	}
+}
+
+// WithTenant scopes the adapter to the rules of tenant, enabling WithTenantColumn.
+// Adapters of different tenants can share the table, a connection or a pool.
+func WithTenant(tenant string) Option {
+	return func(a *PgxAdapter) {
+		a.useTenantColumn = true
+		a.tenant = tenant
+	}
+}
+
+// WithTenantResolver derives the tenant of every call from its context, for
+// instance from the claims set by auth middleware, so one adapter can serve
+// every tenant instead of one adapter per tenant with WithTenant. The tenant
+// is resolved once per call and an error fails the call before any statement
+// runs. Methods without a context, such as LoadPolicy, resolve it from
+// context.Background. It enables WithTenantColumn; with WithRowLevelSecurity
+// the adapter must use a pool.
+func WithTenantResolver(resolve func(ctx context.Context) (string, error)) Option {
+	return func(a *PgxAdapter) {
+		a.useTenantColumn = true
+		a.tenantResolver = resolve
+	}
+}
+
+// GetTenant returns the tenant set with WithTenant
+func (a *PgxAdapter) GetTenant() string {
+	return a.tenant
+}
+
+// tenantKey is the context key of the tenant resolved for a call
+type tenantKey struct{}
+
+// resolveTenant returns ctx carrying the tenant of the call, if a resolver is set.
+// A context that already carries one, such as that of a nested call, is kept.
+func (a *PgxAdapter) resolveTenant(ctx context.Context) (context.Context, error) {
+	if a.tenantResolver == nil {
+		return ctx, nil
+	}
+	if _, ok := ctx.Value(tenantKey{}).(string); ok {
+		return ctx, nil
+	}
+
+	tenant, err := a.tenantResolver(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve tenant: %w", err)
+	}
+	return context.WithValue(ctx, tenantKey{}, tenant), nil
+}
+
+// tenantOf returns the tenant of a call: the one resolved into ctx, or the one set with WithTenant
+func (a *PgxAdapter) tenantOf(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		return tenant
+	}
+	return a.tenant
+}
+
+// tenantColumnDDL returns the definition of the tenant column, if enabled
+func (a *PgxAdapter) tenantColumnDDL() string {
+	if !a.useTenantColumn {
+		return ""
+	}
+	return ",\n\t\t" + tenantColumn + " " + tenantColumnType
+}
+
+// tenantEq returns the condition matching the tenant of the call
+func (a *PgxAdapter) tenantEq(ctx context.Context) sq.Eq {
+	return sq.Eq{tenantColumn: a.tenantOf(ctx)}
+}
+
+// selectRules returns a select of columns from the adapter's table, scoped to the tenant of the call
+func (a *PgxAdapter) selectRules(ctx context.Context, columns ...string) sq.SelectBuilder {
+	b := a.psql.Select(columns...).From(a.quotedTableName())
+	if a.useTenantColumn {
+		b = b.Where(a.tenantEq(ctx))
+	}
+	return b
+}
+
+// deleteRules returns a delete from the adapter's table, scoped to the tenant of the call
+func (a *PgxAdapter) deleteRules(ctx context.Context) sq.DeleteBuilder {
+	b := a.psql.Delete(a.quotedTableName())
+	if a.useTenantColumn {
+		b = b.Where(a.tenantEq(ctx))
+	}
+	return b
+}
+
+// updateRules returns an update of the adapter's table, scoped to the tenant of the call
+func (a *PgxAdapter) updateRules(ctx context.Context) sq.UpdateBuilder {
+	b := a.psql.Update(a.quotedTableName())
+	if a.useTenantColumn {
+		b = b.Where(a.tenantEq(ctx))
+	}
+	return b
+}
+
+// ListTenants returns the tenants holding rules in the table, in order. It
+// needs WithTenantColumn; with WithRowLevelSecurity only the tenant of the
+// call is visible.
+func (a *PgxAdapter) ListTenants(ctx context.Context) ([]string, error) {
+	ctx, cancel := a.readContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return nil, err
+	}
+	if !a.useTenantColumn {
+		return nil, errors.New("tenant column is not enabled")
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sql, args, err := a.psql.
+		Select(tenantColumn).
+		Distinct().
+		From(a.quotedTableName()).
+		OrderBy(tenantColumn).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := a.dbFrom(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tenants: %w", err)
+	}
+	tenants, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants: %w", err)
+	}
+	return tenants, nil
+}
+
+// CountPolicies returns the number of rules tenant holds. It needs
+// WithTenantColumn; with WithRowLevelSecurity the rules of tenants other than
+// that of the call count as none.
+func (a *PgxAdapter) CountPolicies(ctx context.Context, tenant string) (int64, error) {
+	ctx, cancel := a.readContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return 0, err
+	}
+	if !a.useTenantColumn {
+		return 0, errors.New("tenant column is not enabled")
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sql, args, err := a.psql.
+		Select("COUNT(*)").
+		From(a.quotedTableName()).
+		Where(sq.Eq{tenantColumn: tenant}).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var count int64
+	if err := a.dbFrom(ctx).QueryRow(ctx, sql, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count policies: %w", err)
+	}
+	return count, nil
+}
+
+// CopyTenantPolicies copies every rule of fromTenant to toTenant in one
+// transaction, such as when a workspace is created from a template, and
+// returns the number of rules copied. Rules toTenant already holds are
+// skipped. Metadata is copied along. It needs WithTenantColumn and cannot be
+// used with WithRowLevelSecurity, which hides the rules of other tenants.
+func (a *PgxAdapter) CopyTenantPolicies(ctx context.Context, fromTenant, toTenant string) (int64, error) {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return 0, err
+	}
+	if !a.useTenantColumn {
+		return 0, errors.New("tenant column is not enabled")
+	}
+	if a.rowLevelSecurity {
+		return 0, errors.New("cannot copy policies between tenants with row level security")
+	}
+
+	columns := a.columns(a.tableRuleColumns())
+	if a.useEftColumn {
+		columns = append(columns, eftColumn)
+	}
+	if a.useMetadataColumn {
+		columns = append(columns, metadataColumn)
+	}
+
+	selectBuilder := a.psql.
+		Select(columns...).
+		Column(sq.Expr("?::text", toTenant)).
+		From(a.quotedTableName()).
+		Where(sq.Eq{tenantColumn: fromTenant}).
+		OrderBy("id")
+	insertColumns, selectBuilder := a.stampSelect(append(columns, tenantColumn), selectBuilder)
+
+	sql, args, err := a.psql.
+		Insert(a.quotedTableName()).
+		Columns(insertColumns...).
+		Select(selectBuilder).
+		Suffix(a.onConflictDoNothing()).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build copy query: %w", err)
+	}
+
+	tx, err := a.beginWrite(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The copies are rules of toTenant, so they are audited under it
+	copied, err := a.execAudited(context.WithValue(ctx, tenantKey{}, toTenant), tx, AuditOpAdd, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy policies: %w", mapWriteError(err))
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	a.maybeAnalyze(ctx, copied)
+
+	return copied, nil
+}
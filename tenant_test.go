@@ -0,0 +1,319 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v3/model"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithTenant(t *testing.T) {
+	tableName := "casbin_test_tenant"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	newAdapter := func(tenant string) *pgxadapter.PgxAdapter {
+		adapter, err := pgxadapter.NewAdapterWithPool(pool,
+			pgxadapter.WithTableName(tableName),
+			pgxadapter.WithTenant(tenant),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		return adapter
+	}
+	acme := newAdapter("acme")
+	beta := newAdapter("beta")
+
+	// Tenants can hold the same rule
+	for _, adapter := range []*pgxadapter.PgxAdapter{acme, beta} {
+		if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+			t.Fatalf("Failed to add policy for %s: %v", adapter.GetTenant(), err)
+		}
+	}
+	if err := beta.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if err := acme.RemoveFilteredPolicyCtx(ctx, "p", "p", 0, "alice"); err != nil {
+		t.Fatalf("Failed to remove policies: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		adapter *pgxadapter.PgxAdapter
+		want    int
+	}{
+		{name: "removal only affects its tenant", adapter: acme, want: 0},
+		{name: "other tenant keeps its rules", adapter: beta, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := model.NewModelFromString(TestModelText)
+			if err != nil {
+				t.Fatalf("Failed to create model: %v", err)
+			}
+			if err := tt.adapter.LoadPolicyCtx(ctx, m); err != nil {
+				t.Fatalf("Failed to load policy: %v", err)
+			}
+			policies, err := m.GetPolicy("p", "p")
+			if err != nil {
+				t.Fatalf("Failed to get policies: %v", err)
+			}
+			if len(policies) != tt.want {
+				t.Errorf("Expected %d rules, got %v", tt.want, policies)
+			}
+		})
+	}
+
+	var count int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+tableName+" WHERE tenant_id = 'beta'").Scan(&count); err != nil {
+		t.Fatalf("Failed to count rules: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 rules stored for beta, got %d", count)
+	}
+}
+
+type tenantCtxKey struct{}
+
+func TestWithTenantResolver(t *testing.T) {
+	tableName := "casbin_test_tenant_resolver"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithTenantResolver(func(ctx context.Context) (string, error) {
+			tenant, ok := ctx.Value(tenantCtxKey{}).(string)
+			if !ok {
+				return "", errors.New("no tenant in context")
+			}
+			return tenant, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	acmeCtx := context.WithValue(ctx, tenantCtxKey{}, "acme")
+	betaCtx := context.WithValue(ctx, tenantCtxKey{}, "beta")
+
+	if err := adapter.AddPolicyCtx(acmeCtx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if err := adapter.AddPoliciesCtx(betaCtx, "p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"eve", "data1", "read"}); err == nil {
+		t.Error("Expected an error without a tenant in the context")
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want int
+	}{
+		{name: "acme", ctx: acmeCtx, want: 1},
+		{name: "beta", ctx: betaCtx, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := model.NewModelFromString(TestModelText)
+			if err != nil {
+				t.Fatalf("Failed to create model: %v", err)
+			}
+			if err := adapter.LoadPolicyCtx(tt.ctx, m); err != nil {
+				t.Fatalf("Failed to load policy: %v", err)
+			}
+			if policies, _ := m.GetPolicy("p", "p"); len(policies) != tt.want {
+				t.Errorf("Expected %d rules, got %v", tt.want, policies)
+			}
+		})
+	}
+}
+
+func TestCopyTenantPolicies(t *testing.T) {
+	tableName := "casbin_test_tenant_copy"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	newAdapter := func(tenant string) *pgxadapter.PgxAdapter {
+		adapter, err := pgxadapter.NewAdapterWithPool(pool,
+			pgxadapter.WithTableName(tableName),
+			pgxadapter.WithTenant(tenant),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		return adapter
+	}
+	template := newAdapter("template")
+	workspace := newAdapter("workspace")
+
+	if err := template.AddPoliciesCtx(ctx, "p", "p", [][]string{{"admin", "data1", "read"}, {"admin", "data1", "write"}}); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+	if err := template.AddPolicyCtx(ctx, "g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("Failed to add grouping policy: %v", err)
+	}
+	if err := workspace.AddPolicyCtx(ctx, "p", "p", []string{"admin", "data1", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	copied, err := template.CopyTenantPolicies(ctx, "template", "workspace")
+	if err != nil {
+		t.Fatalf("Failed to copy policies: %v", err)
+	}
+	if copied != 2 {
+		t.Errorf("Expected 2 rules copied, got %d", copied)
+	}
+
+	tests := []struct {
+		name    string
+		adapter *pgxadapter.PgxAdapter
+		want    int
+	}{
+		{name: "target holds the copied rules", adapter: workspace, want: 3},
+		{name: "source is unchanged", adapter: template, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := tt.adapter.GetRawPolicies(ctx)
+			if err != nil {
+				t.Fatalf("Failed to get policies: %v", err)
+			}
+			if len(rules) != tt.want {
+				t.Errorf("Expected %d rules, got %v", tt.want, rules)
+			}
+		})
+	}
+
+	plain, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName+"_plain"))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	t.Cleanup(func() { _ = plain.DropTable(ctx) })
+	if _, err := plain.CopyTenantPolicies(ctx, "template", "workspace"); err == nil {
+		t.Error("Expected an error without the tenant column")
+	}
+}
+
+func TestListTenantsAndCountPolicies(t *testing.T) {
+	tableName := "casbin_test_tenant_list"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	seed := map[string][][]string{
+		"acme": {{"alice", "data1", "read"}, {"bob", "data2", "write"}},
+		"beta": {{"carol", "data3", "read"}},
+	}
+	var adapter *pgxadapter.PgxAdapter
+	for tenant, rules := range seed {
+		a, err := pgxadapter.NewAdapterWithPool(pool,
+			pgxadapter.WithTableName(tableName),
+			pgxadapter.WithTenant(tenant),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		if err := a.AddPoliciesCtx(ctx, "p", "p", rules); err != nil {
+			t.Fatalf("Failed to add policies: %v", err)
+		}
+		adapter = a
+	}
+
+	tenants, err := adapter.ListTenants(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list tenants: %v", err)
+	}
+	if !reflect.DeepEqual(tenants, []string{"acme", "beta"}) {
+		t.Errorf("Expected tenants [acme beta], got %v", tenants)
+	}
+
+	tests := []struct {
+		tenant string
+		want   int64
+	}{
+		{tenant: "acme", want: 2},
+		{tenant: "beta", want: 1},
+		{tenant: "gamma", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tenant, func(t *testing.T) {
+			count, err := adapter.CountPolicies(ctx, tt.tenant)
+			if err != nil {
+				t.Fatalf("Failed to count policies: %v", err)
+			}
+			if count != tt.want {
+				t.Errorf("Expected %d rules, got %d", tt.want, count)
+			}
+		})
+	}
+}
+
+func TestSavePolicyWithTenant(t *testing.T) {
+	tests := []struct {
+		name string
+		mode pgxadapter.SaveMode
+	}{
+		{name: "replace", mode: pgxadapter.SaveModeReplace},
+		{name: "upsert diff", mode: pgxadapter.SaveModeUpsertDiff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tableName := "casbin_test_tenant_save"
+			pool := setupTestPool(t, tableName)
+			ctx := context.Background()
+
+			newAdapter := func(tenant string) *pgxadapter.PgxAdapter {
+				adapter, err := pgxadapter.NewAdapterWithPool(pool,
+					pgxadapter.WithTableName(tableName),
+					pgxadapter.WithTenant(tenant),
+					pgxadapter.WithSaveMode(tt.mode),
+				)
+				if err != nil {
+					t.Fatalf("Failed to create adapter: %v", err)
+				}
+				return adapter
+			}
+			acme := newAdapter("acme")
+			beta := newAdapter("beta")
+
+			for _, adapter := range []*pgxadapter.PgxAdapter{acme, beta} {
+				if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+					t.Fatalf("Failed to add policies for %s: %v", adapter.GetTenant(), err)
+				}
+			}
+
+			m, err := model.NewModelFromString(TestModelText)
+			if err != nil {
+				t.Fatalf("Failed to create model: %v", err)
+			}
+			m.AddPolicy("p", "p", []string{"carol", "data3", "read"})
+			if err := acme.SavePolicyCtx(ctx, m); err != nil {
+				t.Fatalf("Failed to save policy: %v", err)
+			}
+
+			acmeRules, err := acme.GetRawPolicies(ctx)
+			if err != nil {
+				t.Fatalf("Failed to get policies: %v", err)
+			}
+			if want := [][]string{{"p", "carol", "data3", "read"}}; !reflect.DeepEqual(acmeRules, want) {
+				t.Errorf("Expected acme to hold %v, got %v", want, acmeRules)
+			}
+
+			count, err := beta.CountPolicies(ctx, "beta")
+			if err != nil {
+				t.Fatalf("Failed to count policies: %v", err)
+			}
+			if count != 2 {
+				t.Errorf("Expected beta to keep its 2 rules, got %d", count)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package pgxadapter
+
+import (
+	"context"
+	"time"
+)
+
+// WithQueryTimeout bounds every database operation of the adapter.
+// WithReadTimeout and WithWriteTimeout take precedence for their operations.
+func WithQueryTimeout(d time.Duration) Option {
+	return func(a *PgxAdapter) {
+		a.queryTimeout = d
+	}
+}
+
+// WithReadTimeout bounds policy loads and other read operations.
+// Loads of large tables can take a while, so this is usually the more generous timeout.
+func WithReadTimeout(d time.Duration) Option {
+	return func(a *PgxAdapter) {
+		a.readTimeout = d
+	}
+}
+
+// WithWriteTimeout bounds policy mutations, so writes fail fast instead of
+// holding locks while waiting on a busy table.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(a *PgxAdapter) {
+		a.writeTimeout = d
+	}
+}
+
+// WithStatementTimeout makes Postgres cancel every statement running longer
+// than d, through the statement_timeout setting of the connections NewAdapter
+// creates, so a hung LoadPolicy or SavePolicy fails fast even when the
+// caller's context has no deadline. A context deadline, or WithQueryTimeout
+// and friends, still end a call sooner. Adapters on a connection or pool
+// passed in keep its settings; set statement_timeout in its RuntimeParams instead.
+func WithStatementTimeout(d time.Duration) Option {
+	return func(a *PgxAdapter) {
+		a.statementTimeout = d
+	}
+}
+
+// readContext derives the context for a read operation
+func (a *PgxAdapter) readContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, a.readTimeout, a.queryTimeout)
+}
+
+// writeContext derives the context for a write operation
+func (a *PgxAdapter) writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, a.writeTimeout, a.queryTimeout)
+}
+
+// withTimeout applies the first non-zero timeout to ctx
+func withTimeout(ctx context.Context, timeouts ...time.Duration) (context.Context, context.CancelFunc) {
+	for _, d := range timeouts {
+		if d > 0 {
+			return context.WithTimeout(ctx, d)
+		}
+	}
+	return ctx, func() {}
+}
@@ -0,0 +1,162 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/casbin/casbin/v3/model"
+	"github.com/casbin/casbin/v3/persist"
+	"github.com/jackc/pgx/v5"
+)
+
+// Names of the timestamp columns enabled by WithTimestamps
+const (
+	createdAtColumn = "created_at"
+	updatedAtColumn = "updated_at"
+)
+
+// PolicyMeta is a stored rule together with the timestamps of WithTimestamps
+type PolicyMeta struct {
+	Ptype     string    `json:"ptype"`
+	Rule      []string  `json:"rule"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WithTimestamps adds created_at and updated_at columns, so operators can tell
+// when a rule was granted or last changed. The adapter stamps both with its
+// clock, see WithClock, on every insert, COPY and update. Rows inserted
+// outside the adapter get the current time through the column defaults.
+// Existing tables get the columns when the adapter creates its table, with
+// the current time for rows that already exist. Timestamps are read with
+// LoadPolicyWithMeta.
+func WithTimestamps() Option {
+	return func(a *PgxAdapter) {
+		a.timestamps = true
+	}
+}
+
+// timestampsDDL returns the column definitions of the timestamp columns, if enabled
+func (a *PgxAdapter) timestampsDDL() string {
+	if !a.timestamps {
+		return ""
+	}
+	return ",\n\t\t" + createdAtColumn + " TIMESTAMPTZ NOT NULL DEFAULT now()" +
+		",\n\t\t" + updatedAtColumn + " TIMESTAMPTZ NOT NULL DEFAULT now()"
+}
+
+// timestampColumns returns the timestamp columns written with every rule, if enabled
+func (a *PgxAdapter) timestampColumns() []string {
+	if !a.timestamps {
+		return nil
+	}
+	return []string{createdAtColumn, updatedAtColumn}
+}
+
+// timestampValues returns the values of timestampColumns, read from the adapter's clock
+func (a *PgxAdapter) timestampValues() []any {
+	if !a.timestamps {
+		return nil
+	}
+	now := a.now()
+	return []any{now, now}
+}
+
+// stampSelect adds timestampValues to the select list of an INSERT ... SELECT
+// and returns the insert columns with timestampColumns appended
+func (a *PgxAdapter) stampSelect(columns []string, b sq.SelectBuilder) ([]string, sq.SelectBuilder) {
+	for _, v := range a.timestampValues() {
+		b = b.Column(sq.Expr("?::timestamptz", v))
+	}
+	return append(columns, a.timestampColumns()...), b
+}
+
+// installTimestamps adds the timestamp columns to an existing table and drops
+// the trigger earlier versions used to maintain updated_at, which would
+// overwrite the time of the adapter's clock
+func (a *PgxAdapter) installTimestamps(ctx context.Context) error {
+	if !a.timestamps {
+		return nil
+	}
+
+	name := pgx.Identifier{a.tableName + "_touch"}.Sanitize()
+	function := a.qualifiedName(a.tableName + "_touch")
+
+	ddl := `ALTER TABLE ` + a.quotedTableName() + `
+			ADD COLUMN IF NOT EXISTS ` + createdAtColumn + ` TIMESTAMPTZ NOT NULL DEFAULT now(),
+			ADD COLUMN IF NOT EXISTS ` + updatedAtColumn + ` TIMESTAMPTZ NOT NULL DEFAULT now();
+		DROP TRIGGER IF EXISTS ` + name + ` ON ` + a.quotedTableName() + `;
+		DROP FUNCTION IF EXISTS ` + function + `()`
+
+	if _, err := a.db.Exec(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to create timestamp columns: %w", err)
+	}
+	return nil
+}
+
+// LoadPolicyWithMeta loads all policy rules into model like LoadPolicy and
+// returns every loaded rule together with its timestamps.
+// Requires WithTimestamps.
+func (a *PgxAdapter) LoadPolicyWithMeta(ctx context.Context, model model.Model) ([]PolicyMeta, error) {
+	ctx, cancel := a.readContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return nil, err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !a.timestamps {
+		return nil, fmt.Errorf("timestamps are not enabled")
+	}
+
+	a.loadMu.Lock()
+	defer a.loadMu.Unlock()
+
+	if err := a.learnEft(model); err != nil {
+		return nil, err
+	}
+
+	q, args, err := a.limitLoad(a.selectRules(ctx, append(a.selectColumns(), createdAtColumn, updatedAtColumn)...).
+		OrderBy(a.orderBy()...)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := a.dbFrom(ctx).Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policies: %w", err)
+	}
+	defer rows.Close()
+
+	var metas []PolicyMeta
+	for rows.Next() {
+		if err := a.checkLoadRows(len(metas) + 1); err != nil {
+			return nil, err
+		}
+
+		var meta PolicyMeta
+		ptype, rule, err := a.scanRuleWith(rows, &meta.CreatedAt, &meta.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		meta.Ptype, meta.Rule = ptype, rule
+
+		if err := persist.LoadPolicyArray(append([]string{ptype}, rule...), model); err != nil {
+			return nil, err
+		}
+		metas = append(metas, meta)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return metas, nil
+}
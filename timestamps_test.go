@@ -0,0 +1,218 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3/model"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithTimestamps(t *testing.T) {
+	tableName := "casbin_test_timestamps"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	// The columns are added to an existing table
+	if _, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName)); err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithTimestamps(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	before := time.Now().Add(-time.Minute)
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	load := func() pgxadapter.PolicyMeta {
+		t.Helper()
+		m, err := model.NewModelFromString(TestModelText)
+		if err != nil {
+			t.Fatalf("Failed to create model: %v", err)
+		}
+		metas, err := adapter.LoadPolicyWithMeta(ctx, m)
+		if err != nil {
+			t.Fatalf("LoadPolicyWithMeta() unexpected error: %v", err)
+		}
+		if len(metas) != 1 || metas[0].Ptype != "p" || len(metas[0].Rule) != 3 {
+			t.Fatalf("Expected one rule, got %+v", metas)
+		}
+		if ok, _ := m.HasPolicy("p", "p", metas[0].Rule); !ok {
+			t.Error("Expected the rule to be loaded into the model")
+		}
+		return metas[0]
+	}
+
+	added := load()
+	if added.CreatedAt.Before(before) || !added.UpdatedAt.Equal(added.CreatedAt) {
+		t.Errorf("Unexpected timestamps after insert: %+v", added)
+	}
+
+	if err := adapter.UpdatePolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data1", "write"}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+	updated := load()
+	if !updated.CreatedAt.Equal(added.CreatedAt) || !updated.UpdatedAt.After(added.UpdatedAt) {
+		t.Errorf("Expected only updated_at to move, got %+v then %+v", added, updated)
+	}
+
+	plain, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	m, _ := model.NewModelFromString(TestModelText)
+	if _, err := plain.LoadPolicyWithMeta(ctx, m); err == nil {
+		t.Error("Expected an error without WithTimestamps")
+	}
+}
+
+func TestWithTimestampsClock(t *testing.T) {
+	tableName := "casbin_test_timestamps_clock"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithTimestamps(),
+		pgxadapter.WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	stamps := func() map[string]pgxadapter.PolicyMeta {
+		t.Helper()
+		m, err := model.NewModelFromString(TestModelText)
+		if err != nil {
+			t.Fatalf("Failed to create model: %v", err)
+		}
+		metas, err := adapter.LoadPolicyWithMeta(ctx, m)
+		if err != nil {
+			t.Fatalf("LoadPolicyWithMeta() unexpected error: %v", err)
+		}
+		result := make(map[string]pgxadapter.PolicyMeta)
+		for _, meta := range metas {
+			result[meta.Rule[0]] = meta
+		}
+		return result
+	}
+	expect := func(sub string, created, updated time.Time) {
+		t.Helper()
+		meta, ok := stamps()[sub]
+		if !ok {
+			t.Fatalf("Expected a rule for %s", sub)
+		}
+		if !meta.CreatedAt.Equal(created) || !meta.UpdatedAt.Equal(updated) {
+			t.Errorf("Expected %s stamped %v / %v, got %v / %v", sub, created, updated, meta.CreatedAt, meta.UpdatedAt)
+		}
+	}
+
+	// SavePolicy writes with COPY
+	inserted := now
+	m, _ := model.NewModelFromString(TestModelText)
+	m.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	if err := adapter.SavePolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+	expect("alice", inserted, inserted)
+
+	now = now.Add(time.Hour)
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data2", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	expect("bob", now, now)
+
+	now = now.Add(time.Hour)
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{{"carol", "data3", "read"}}); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+	expect("carol", now, now)
+
+	now = now.Add(time.Hour)
+	if err := adapter.UpdatePolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data1", "write"}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+	expect("alice", inserted, now)
+
+	now = now.Add(time.Hour)
+	if err := adapter.UpdatePoliciesCtx(ctx, "p", "p", [][]string{{"alice", "data1", "write"}}, [][]string{{"alice", "data1", "admin"}}); err != nil {
+		t.Fatalf("Failed to update policies: %v", err)
+	}
+	expect("alice", inserted, now)
+}
+
+func TestWithClockCopiedRules(t *testing.T) {
+	tableName := "casbin_test_clock_copy"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+	t.Cleanup(func() { _, _ = pool.Exec(context.Background(), "DROP TABLE IF EXISTS "+tableName+"_source") })
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	newAdapter := func(tenant string) *pgxadapter.PgxAdapter {
+		adapter, err := pgxadapter.NewAdapterWithPool(pool,
+			pgxadapter.WithTableName(tableName),
+			pgxadapter.WithTenant(tenant),
+			pgxadapter.WithTimestamps(),
+			pgxadapter.WithClock(func() time.Time { return now }),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		return adapter
+	}
+	template := newAdapter("template")
+	workspace := newAdapter("workspace")
+
+	expect := func(adapter *pgxadapter.PgxAdapter, want time.Time) {
+		t.Helper()
+		m, _ := model.NewModelFromString(TestModelText)
+		metas, err := adapter.LoadPolicyWithMeta(ctx, m)
+		if err != nil {
+			t.Fatalf("LoadPolicyWithMeta() unexpected error: %v", err)
+		}
+		if len(metas) == 0 {
+			t.Fatal("Expected rules to be loaded")
+		}
+		for _, meta := range metas {
+			if !meta.CreatedAt.Equal(want) || !meta.UpdatedAt.Equal(want) {
+				t.Errorf("Expected %v stamped %v, got %v / %v", meta.Rule, want, meta.CreatedAt, meta.UpdatedAt)
+			}
+		}
+	}
+
+	if err := template.AddPolicyCtx(ctx, "p", "p", []string{"admin", "data1", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	// Copied rules are new rows of the target tenant
+	now = now.Add(time.Hour)
+	if _, err := template.CopyTenantPolicies(ctx, "template", "workspace"); err != nil {
+		t.Fatalf("Failed to copy policies: %v", err)
+	}
+	expect(workspace, now)
+
+	// So are migrated rules
+	now = now.Add(time.Hour)
+	if _, err := pool.Exec(ctx, `CREATE TABLE `+tableName+`_source (
+		id BIGSERIAL PRIMARY KEY, ptype TEXT,
+		v0 TEXT, v1 TEXT, v2 TEXT, v3 TEXT, v4 TEXT, v5 TEXT)`); err != nil {
+		t.Fatalf("Failed to create source table: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `INSERT INTO `+tableName+`_source (ptype, v0, v1, v2) VALUES ('p', 'bob', 'data2', 'read')`); err != nil {
+		t.Fatalf("Failed to insert source row: %v", err)
+	}
+	migrated := newAdapter("migrated")
+	if _, err := migrated.MigrateFromTable(ctx, tableName+"_source"); err != nil {
+		t.Fatalf("Failed to migrate policies: %v", err)
+	}
+	expect(migrated, now)
+}
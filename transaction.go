@@ -0,0 +1,45 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+)
+
+// Transaction runs fn with a view of the adapter bound to a new transaction
+// and commits it if fn returns nil, or rolls it back if fn fails or panics.
+// The view shares the adapter's configuration and must not be used after fn
+// returns. Called on a view, Transaction runs fn in a savepoint.
+func (a *PgxAdapter) Transaction(ctx context.Context, fn func(tx *PgxAdapter) error) error {
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := a.beginWrite(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	view := newAdapter(a.opts...)
+	view.db = tx
+	// The parent already created the table
+	view.lazyInit = false
+	// The effect positions are learned on load, which the view did not run
+	a.mu.RLock()
+	view.eftIndexes = a.eftIndexes
+	a.mu.RUnlock()
+
+	if err := fn(view); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/casbin/casbin/v3/model"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestTransaction(t *testing.T) {
+	tableName := "casbin_test_transaction"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	rules := make([][]string, 10)
+	for i := range rules {
+		rules[i] = []string{fmt.Sprintf("user%d", i), "data", "read"}
+	}
+
+	errAbort := errors.New("abort")
+	tests := []struct {
+		name      string
+		fail      error
+		panics    bool
+		wantRules int
+	}{
+		{
+			name:      "commit",
+			wantRules: 5,
+		},
+		{
+			name:      "rollback_on_error",
+			fail:      errAbort,
+			wantRules: 0,
+		},
+		{
+			name:      "rollback_on_panic",
+			panics:    true,
+			wantRules: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := pool.Exec(ctx, "DELETE FROM "+tableName); err != nil {
+				t.Fatalf("Failed to clear table: %v", err)
+			}
+
+			run := func() error {
+				return adapter.Transaction(ctx, func(tx *pgxadapter.PgxAdapter) error {
+					if err := tx.AddPoliciesCtx(ctx, "p", "p", rules); err != nil {
+						return err
+					}
+					if err := tx.RemovePoliciesCtx(ctx, "p", "p", rules[:5]); err != nil {
+						return err
+					}
+					if tt.panics {
+						panic("boom")
+					}
+					return tt.fail
+				})
+			}
+
+			var err error
+			func() {
+				defer func() {
+					if r := recover(); r != nil && !tt.panics {
+						t.Fatalf("Unexpected panic: %v", r)
+					}
+				}()
+				err = run()
+			}()
+
+			if !errors.Is(err, tt.fail) {
+				t.Errorf("Transaction() error = %v, want %v", err, tt.fail)
+			}
+
+			var count int
+			if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+tableName).Scan(&count); err != nil {
+				t.Fatalf("Failed to count rules: %v", err)
+			}
+			if count != tt.wantRules {
+				t.Errorf("Expected %d rules, got %d", tt.wantRules, count)
+			}
+		})
+	}
+}
+
+func TestTransactionRepeatsStagedOperations(t *testing.T) {
+	tableName := "casbin_test_transaction_staged"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithCopyThreshold(1),
+		pgxadapter.WithSaveMode(pgxadapter.SaveModeUpsertDiff),
+		pgxadapter.WithLoadFetchSize(10),
+		pgxadapter.WithSubjectField(0),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	// Each operation stages rules in a temporary table or loads through a
+	// cursor, and must be able to run twice in one transaction
+	err = adapter.Transaction(ctx, func(tx *pgxadapter.PgxAdapter) error {
+		for i := range 2 {
+			rule := []string{fmt.Sprintf("user%d", i), "data", "read"}
+			if err := tx.AddPoliciesCtx(ctx, "p", "p", [][]string{rule}); err != nil {
+				return fmt.Errorf("AddPolicies #%d: %w", i, err)
+			}
+			if _, err := tx.RemovePoliciesBulk(ctx, "p", [][]string{rule}); err != nil {
+				return fmt.Errorf("RemovePoliciesBulk #%d: %w", i, err)
+			}
+			if _, _, err := tx.ReplaceSubjectPolicies(ctx, "p", "alice", [][]string{{"alice", "data", "read"}}); err != nil {
+				return fmt.Errorf("ReplaceSubjectPolicies #%d: %w", i, err)
+			}
+
+			m, _ := model.NewModelFromString(TestModelText)
+			m.AddPolicy("p", "p", []string{"alice", "data", "read"})
+			if err := tx.SavePolicyCtx(ctx, m); err != nil {
+				return fmt.Errorf("SavePolicy #%d: %w", i, err)
+			}
+
+			loaded, _ := model.NewModelFromString(TestModelText)
+			if err := tx.LoadPolicyCtx(ctx, loaded); err != nil {
+				return fmt.Errorf("LoadPolicy #%d: %w", i, err)
+			}
+			if ok, _ := loaded.HasPolicy("p", "p", []string{"alice", "data", "read"}); !ok {
+				return fmt.Errorf("LoadPolicy #%d: expected the saved rule", i)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction() unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// WithChangeTrigger installs a trigger on the policy table that sends a NOTIFY
+// on the adapter's channel after every statement inserting, updating,
+// deleting or truncating rules, so edits made directly in SQL reach watchers
+// too. The payload is a WatcherMessage for a full reload without an instance
+// ID, which means the instance making a change is notified as well. With
+// WithTenantColumn the trigger runs for every row instead, notifying the
+// channel of each tenant whose rules changed; Postgres folds the identical
+// notifications of a transaction into one per tenant.
+func WithChangeTrigger() Option {
+	return func(a *PgxAdapter) {
+		a.changeTrigger = true
+	}
+}
+
+// changeTriggerName returns the name of the trigger and of its function
+func (a *PgxAdapter) changeTriggerName() string {
+	return a.tableName + "_notify"
+}
+
+// installChangeTrigger creates or replaces the trigger function and the trigger
+func (a *PgxAdapter) installChangeTrigger(ctx context.Context) error {
+	name := pgx.Identifier{a.changeTriggerName()}.Sanitize()
+	truncateName := pgx.Identifier{a.changeTriggerName() + "_truncate"}.Sanitize()
+	function := a.qualifiedName(a.changeTriggerName())
+	payload := quoteLiteral(`{"method":"` + string(MethodUpdate) + `"}`)
+
+	// Statement level, so a bulk import sends one notification instead of one per row
+	body := `PERFORM pg_notify(` + quoteLiteral(a.GetNotifyChannel()) + `, ` + payload + `);`
+	triggers := `CREATE TRIGGER ` + name + `
+			AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON ` + a.quotedTableName() + `
+			FOR EACH STATEMENT EXECUTE FUNCTION ` + function + `()`
+
+	if a.useTenantColumn {
+		// Rows name their tenant; TRUNCATE names none, so it notifies the
+		// tenants of the rows it is about to remove
+		body = `IF TG_OP = 'TRUNCATE' THEN
+				PERFORM pg_notify(` + a.tenantNotifyChannelExpr(tenantColumn) + `, ` + payload + `)
+					FROM (SELECT DISTINCT ` + tenantColumn + ` FROM ` + a.quotedTableName() + `) AS tenants;
+				RETURN NULL;
+			END IF;
+			IF TG_OP <> 'INSERT' THEN
+				PERFORM pg_notify(` + a.tenantNotifyChannelExpr("OLD."+tenantColumn) + `, ` + payload + `);
+			END IF;
+			IF TG_OP <> 'DELETE' THEN
+				PERFORM pg_notify(` + a.tenantNotifyChannelExpr("NEW."+tenantColumn) + `, ` + payload + `);
+			END IF;`
+		triggers = `CREATE TRIGGER ` + name + `
+			AFTER INSERT OR UPDATE OR DELETE ON ` + a.quotedTableName() + `
+			FOR EACH ROW EXECUTE FUNCTION ` + function + `();
+		CREATE TRIGGER ` + truncateName + `
+			BEFORE TRUNCATE ON ` + a.quotedTableName() + `
+			FOR EACH STATEMENT EXECUTE FUNCTION ` + function + `()`
+	}
+
+	ddl := `CREATE OR REPLACE FUNCTION ` + function + `() RETURNS trigger AS $$
+		BEGIN
+			` + body + `
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+		DROP TRIGGER IF EXISTS ` + name + ` ON ` + a.quotedTableName() + `;
+		DROP TRIGGER IF EXISTS ` + truncateName + ` ON ` + a.quotedTableName() + `;
+		` + triggers
+
+	if _, err := a.db.Exec(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to create change trigger: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithChangeTrigger(t *testing.T) {
+	tableName := "casbin_test_change_trigger"
+	pool := setupTestPool(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithNamespace("change_trigger"),
+		pgxadapter.WithChangeTrigger(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	w, err := pgxadapter.NewWatcher(context.Background(), adapter)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer w.Close()
+
+	called := make(chan string, 10)
+	_ = w.SetUpdateCallback(func(payload string) { called <- payload })
+
+	// Edits made directly in SQL, bypassing the adapter
+	statements := []string{
+		"INSERT INTO " + tableName + " (ptype, v0, v1, v2) VALUES ('p', 'alice', 'data1', 'read'), ('p', 'bob', 'data2', 'write')",
+		"UPDATE " + tableName + " SET v2 = 'write' WHERE v0 = 'alice'",
+		"DELETE FROM " + tableName,
+	}
+	for _, stmt := range statements {
+		if _, err := pool.Exec(context.Background(), stmt); err != nil {
+			t.Fatalf("Failed to execute %q: %v", stmt, err)
+		}
+
+		select {
+		case payload := <-called:
+			msg, err := pgxadapter.ParseWatcherMessage(payload)
+			if err != nil || msg.Method != pgxadapter.MethodUpdate {
+				t.Errorf("Unexpected payload %q", payload)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for the notification of %q", stmt)
+		}
+	}
+}
+
+func TestWithChangeTriggerTenants(t *testing.T) {
+	tableName := "casbin_test_change_trigger_tenants"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	called := make(map[string]chan string)
+	for _, tenant := range []string{"acme", "beta"} {
+		adapter, err := pgxadapter.NewAdapterWithPool(pool,
+			pgxadapter.WithTableName(tableName),
+			pgxadapter.WithTenant(tenant),
+			pgxadapter.WithChangeTrigger(),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+
+		w, err := pgxadapter.NewWatcher(ctx, adapter)
+		if err != nil {
+			t.Fatalf("Failed to create watcher: %v", err)
+		}
+		defer w.Close()
+
+		ch := make(chan string, 10)
+		_ = w.SetUpdateCallback(func(payload string) { ch <- payload })
+		called[tenant] = ch
+	}
+
+	tests := []struct {
+		stmt    string
+		tenants []string
+	}{
+		{stmt: "INSERT INTO " + tableName + " (ptype, v0, v1, v2, tenant_id) VALUES ('p', 'alice', 'data1', 'read', 'acme'), ('p', 'bob', 'data2', 'write', 'acme')", tenants: []string{"acme"}},
+		{stmt: "UPDATE " + tableName + " SET tenant_id = 'beta' WHERE v0 = 'alice'", tenants: []string{"acme", "beta"}},
+		{stmt: "TRUNCATE " + tableName, tenants: []string{"acme", "beta"}},
+	}
+	for _, tt := range tests {
+		if _, err := pool.Exec(ctx, tt.stmt); err != nil {
+			t.Fatalf("Failed to execute %q: %v", tt.stmt, err)
+		}
+
+		for _, tenant := range tt.tenants {
+			select {
+			case <-called[tenant]:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Timed out waiting for the notification of %s on %q", tenant, tt.stmt)
+			}
+		}
+		for tenant, ch := range called {
+			select {
+			case <-ch:
+				t.Errorf("Unexpected notification of %s on %q", tenant, tt.stmt)
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+	}
+}
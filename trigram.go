@@ -0,0 +1,46 @@
+package pgxadapter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// WithGinTrigramIndex adds a GIN index with gin_trgm_ops on the given column,
+// which speeds up LIKE and ILIKE filters such as prefix or substring matches
+// of resource paths, including the AnyLike terms of Filter. The pg_trgm
+// extension is created if it is missing; without the privilege to create it,
+// table creation fails until it has been installed by an administrator.
+// Array columns are not supported.
+func WithGinTrigramIndex(column string) Option {
+	return func(a *PgxAdapter) {
+		a.indexes = append(a.indexes, indexDef{columns: normalizeColumns([]string{column}), trigram: true})
+	}
+}
+
+// createTrigramIndex creates the pg_trgm extension if needed and the trigram index on col
+func (a *PgxAdapter) createTrigramIndex(ctx context.Context, col string) error {
+	// Creating an extension needs privileges an application role often lacks,
+	// so it is only attempted when the extension is missing
+	var installed bool
+	if err := a.db.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')",
+	).Scan(&installed); err != nil {
+		return fmt.Errorf("failed to query pg_trgm extension: %w", err)
+	}
+	if !installed {
+		if _, err := a.db.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS pg_trgm"); err != nil {
+			return fmt.Errorf("failed to create pg_trgm extension, install it as a superuser: %w", err)
+		}
+	}
+
+	indexName := "idx_" + a.tableName + "_" + col + "_trgm"
+	createIndexSQL := `CREATE INDEX IF NOT EXISTS ` + pgx.Identifier{indexName}.Sanitize() +
+		` ON ` + a.quotedTableName() + ` USING gin (` + pgx.Identifier{a.columnName(col)}.Sanitize() + ` gin_trgm_ops)`
+
+	if _, err := a.db.Exec(ctx, createIndexSQL); err != nil {
+		return fmt.Errorf("failed to create index %s: %w", indexName, err)
+	}
+	return nil
+}
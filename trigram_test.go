@@ -0,0 +1,60 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithGinTrigramIndex(t *testing.T) {
+	tableName := "casbin_test_trigram"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	if _, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithArrayColumn("v2"),
+		pgxadapter.WithGinTrigramIndex("v2"),
+	); err == nil || !strings.Contains(err.Error(), "array columns") {
+		t.Errorf("Expected an error for an array column, got %v", err)
+	}
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithGinTrigramIndex("V1"),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "pg_trgm") {
+			t.Skipf("pg_trgm is not available: %v", err)
+		}
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	var def string
+	if err := pool.QueryRow(ctx,
+		"SELECT indexdef FROM pg_indexes WHERE tablename = $1 AND indexname = $2",
+		tableName, "idx_"+tableName+"_v1_trgm").Scan(&def); err != nil {
+		t.Fatalf("Failed to query index: %v", err)
+	}
+	if !strings.Contains(def, "USING gin") || !strings.Contains(def, "gin_trgm_ops") {
+		t.Errorf("Expected a GIN trigram index, got %s", def)
+	}
+
+	rules := [][]string{
+		{"alice", "/api/v1/users", "read"},
+		{"bob", "/api/v2/orders", "read"},
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", rules); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+
+	var count int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+tableName+" WHERE v1 LIKE '/api/v1/%'").Scan(&count); err != nil {
+		t.Fatalf("Failed to query rules: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected one rule under /api/v1, got %d", count)
+	}
+}
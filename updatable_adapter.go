@@ -2,10 +2,11 @@ package pgxadapter
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"strings"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 )
 
 // UpdatePolicy updates a policy rule from storage
@@ -25,50 +26,62 @@ func (a *PgxAdapter) UpdateFilteredPolicies(sec string, ptype string, newRules [
 
 // UpdatePolicyCtx updates a policy rule from storage
 func (a *PgxAdapter) UpdatePolicyCtx(ctx context.Context, sec string, ptype string, oldRule, newRule []string) error {
-	// Build WHERE clause for old rule
-	updateBuilder := a.psql.Update(a.tableName).Where(sq.Eq{"ptype": ptype})
-
-	// Add conditions for each old rule value
-	for i := range 6 {
-		col := colParams[i]
-		if i < len(oldRule) && oldRule[i] != "" {
-			updateBuilder = updateBuilder.Where(sq.Eq{col: oldRule[i]})
-		} else {
-			updateBuilder = updateBuilder.Where(sq.Eq{col: nil})
-		}
-	}
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
 
-	// Build SET clause for new rule
-	setMap := make(map[string]any)
-	for i := range 6 {
-		col := colParams[i]
-		if i < len(newRule) && newRule[i] != "" {
-			setMap[col] = newRule[i]
-		} else {
-			setMap[col] = nil
-		}
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return err
 	}
-	updateBuilder = updateBuilder.SetMap(setMap)
+
+	// Build WHERE clause for old rule and SET clause for new rule
+	updateBuilder := a.updateRules(ctx).
+		Where(sq.Eq{a.column("ptype"): ptype}).
+		Where(a.ruleEq(ptype, oldRule)).
+		SetMap(a.ruleSetMap(ptype, newRule))
 
 	sqlQuery, args, err := updateBuilder.ToSql()
 	if err != nil {
 		return fmt.Errorf("failed to build update query: %w", err)
 	}
 
-	result, err := a.db.Exec(ctx, sqlQuery, args...)
-	if err != nil {
-		return fmt.Errorf("failed to update policy: %w", err)
-	}
+	return a.auditTx(ctx, func(db DB) error {
+		result, err := db.Exec(ctx, sqlQuery, args...)
+		if err != nil {
+			return fmt.Errorf("failed to update policy: %w", mapWriteError(err))
+		}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("policy not found")
-	}
+		if result.RowsAffected() == 0 {
+			return fmt.Errorf("policy not found")
+		}
 
-	return nil
+		return a.writeAudit(ctx, db, AuditOpUpdate, []auditChange{{ptype: ptype, oldRule: oldRule, newRule: newRule}})
+	})
 }
 
 // UpdatePoliciesCtx updates multiple policy rules in storage within a transaction
 func (a *PgxAdapter) UpdatePoliciesCtx(ctx context.Context, sec string, ptype string, oldRules, newRules [][]string) error {
+	return a.withRetry(ctx, func() error {
+		return a.updatePolicies(ctx, sec, ptype, oldRules, newRules)
+	})
+}
+
+// updatePolicies runs a single attempt of UpdatePoliciesCtx
+func (a *PgxAdapter) updatePolicies(ctx context.Context, sec string, ptype string, oldRules, newRules [][]string) error {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return err
+	}
+
 	if len(oldRules) != len(newRules) {
 		return fmt.Errorf("old rules and new rules must have the same length")
 	}
@@ -77,85 +90,254 @@ func (a *PgxAdapter) UpdatePoliciesCtx(ctx context.Context, sec string, ptype st
 		return nil
 	}
 
-	tx, err := a.db.Begin(ctx)
+	tx, err := a.beginWrite(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
+	updated, err := a.replaceRules(ctx, tx, ptype, oldRules, newRules)
+	if err != nil {
+		return err
+	}
 	for i := range oldRules {
-		oldRule := oldRules[i]
-		newRule := newRules[i]
-
-		// Build WHERE clause for old rule
-		updateBuilder := a.psql.Update(a.tableName).Where(sq.Eq{"ptype": ptype})
-
-		// Add conditions for each old rule value
-		for j := range 6 {
-			col := colParams[j]
-			if j < len(oldRule) && oldRule[j] != "" {
-				updateBuilder = updateBuilder.Where(sq.Eq{col: oldRule[j]})
-			} else {
-				updateBuilder = updateBuilder.Where(sq.Eq{col: nil})
-			}
+		if !updated[i] {
+			return fmt.Errorf("policy not found at index %d", i)
 		}
+	}
 
-		// Build SET clause for new rule
-		setMap := make(map[string]any)
-		for j := range 6 {
-			col := colParams[j]
-			if j < len(newRule) && newRule[j] != "" {
-				setMap[col] = newRule[j]
-			} else {
-				setMap[col] = nil
-			}
-		}
-		updateBuilder = updateBuilder.SetMap(setMap)
+	changes := make([]auditChange, len(oldRules))
+	for i := range oldRules {
+		changes[i] = auditChange{ptype: ptype, oldRule: oldRules[i], newRule: newRules[i]}
+	}
+	if err := a.writeAudit(ctx, tx, AuditOpUpdate, changes); err != nil {
+		return err
+	}
 
-		sqlQuery, args, err := updateBuilder.ToSql()
-		if err != nil {
-			return fmt.Errorf("failed to build update query: %w", err)
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UpdatePoliciesBulk replaces every old rule with the new rule at the same
+// index and returns the number of rules replaced. Old rules that do not exist
+// are skipped instead of failing the update.
+func (a *PgxAdapter) UpdatePoliciesBulk(ctx context.Context, ptype string, oldRules, newRules [][]string) (int64, error) {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return 0, err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(oldRules) != len(newRules) {
+		return 0, fmt.Errorf("old rules and new rules must have the same length")
+	}
+
+	if len(oldRules) == 0 {
+		return 0, nil
+	}
+
+	tx, err := a.beginWrite(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	updated, err := a.replaceRules(ctx, tx, ptype, oldRules, newRules)
+	if err != nil {
+		return 0, err
+	}
+
+	var changes []auditChange
+	for i := range oldRules {
+		if updated[i] {
+			changes = append(changes, auditChange{ptype: ptype, oldRule: oldRules[i], newRule: newRules[i]})
 		}
+	}
+	if err := a.writeAudit(ctx, tx, AuditOpUpdate, changes); err != nil {
+		return 0, err
+	}
 
-		result, err := tx.Exec(ctx, sqlQuery, args...)
-		if err != nil {
-			return fmt.Errorf("failed to update policy: %w", err)
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return int64(len(changes)), nil
+}
+
+// replaceUpdates names the rule pairs of replaceRules in its statement
+const replaceUpdates = "casbin_updates"
+
+// replaceRules replaces every old rule of ptype with the new rule at the same
+// index in a single UPDATE joined against the unnested pairs, and reports
+// which old rules were found. Rules are matched like the unique index
+// compares them, treating NULL and empty values alike.
+func (a *PgxAdapter) replaceRules(ctx context.Context, db DB, ptype string, oldRules, newRules [][]string) ([]bool, error) {
+	// The UPDATE changes each row once, so a repeated old rule would be reported as not found
+	if i, ok := repeatedRule(oldRules); ok {
+		return nil, fmt.Errorf("old rule at index %d repeats an earlier old rule", i)
+	}
+
+	columns := a.valueColumnTotal()
+	if a.useEftColumn {
+		columns++
+	}
+
+	// One array per column of the old rules, then one per column of the new rules
+	arrays := make([][]*string, 2*columns)
+	for i := range arrays {
+		arrays[i] = make([]*string, len(oldRules))
+	}
+	for j := range oldRules {
+		for k, rule := range [][]string{oldRules[j], newRules[j]} {
+			rule, eft := a.splitEft(ptype, rule)
+			for i := range a.valueColumnTotal() {
+				if i < len(rule) && rule[i] != "" {
+					arrays[k*columns+i][j] = &rule[i]
+				}
+			}
+			if eft, ok := eft.(string); ok {
+				arrays[k*columns+columns-1][j] = &eft
+			}
 		}
+	}
 
-		if result.RowsAffected() == 0 {
-			return fmt.Errorf("policy not found at index %d", i)
+	names := make([]string, 0, 2*columns+1)
+	unnest := make([]string, 2*columns)
+	args := make([]any, 2*columns)
+	for i := range arrays {
+		names = append(names, fmt.Sprintf("c%d", i))
+		unnest[i] = "?::text[]"
+		args[i] = arrays[i]
+	}
+	names = append(names, "idx")
+
+	// The table side compares the unique index expressions of the value and
+	// effect columns, the pair side the same expressions over the old rule
+	tableExprs := a.uniqueIndexExprs()[1 : columns+1]
+	pairExprs := make([]string, columns)
+	update := a.updateRules(ctx).
+		Prefix("WITH "+replaceUpdates+" ("+strings.Join(names, ", ")+") AS (SELECT * FROM unnest("+
+			strings.Join(unnest, ", ")+") WITH ORDINALITY)", args...).
+		From(replaceUpdates)
+	for i := range columns {
+		oldValue := replaceUpdates + "." + names[i]
+		newValue := replaceUpdates + "." + names[columns+i]
+
+		switch col := valueColumn(i); {
+		case i == a.valueColumnTotal():
+			pairExprs[i] = "COALESCE(" + oldValue + ",'')"
+			update = update.Set(eftColumn, sq.Expr(newValue))
+		case a.isArrayColumn(col):
+			delimiter := quoteLiteral(a.arrayDelimiter)
+			pairExprs[i] = "COALESCE(string_to_array(" + oldValue + ", " + delimiter + "),'{}')"
+			update = update.Set(a.column(col), sq.Expr("string_to_array("+newValue+", "+delimiter+")"))
+		case a.emptyValue(col) != nil:
+			pairExprs[i] = "COALESCE(" + oldValue + ",'')"
+			update = update.Set(a.column(col), sq.Expr("COALESCE("+newValue+",'')"))
+		default:
+			pairExprs[i] = "COALESCE(" + oldValue + ",'')"
+			update = update.Set(a.column(col), sq.Expr(newValue))
 		}
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	if a.timestamps {
+		update = update.Set(updatedAtColumn, a.now())
 	}
 
-	return nil
+	sql, args, err := update.
+		Where(sq.Eq{a.column("ptype"): ptype}).
+		Where("(" + strings.Join(tableExprs, ", ") + ") = (" + strings.Join(pairExprs, ", ") + ")").
+		Suffix("RETURNING " + replaceUpdates + ".idx").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update policy: %w", mapWriteError(err))
+	}
+	indexes, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("failed to update policy: %w", mapWriteError(err))
+	}
+
+	updated := make([]bool, len(oldRules))
+	for _, idx := range indexes {
+		updated[idx-1] = true
+	}
+	return updated, nil
+}
+
+// repeatedRule returns the index of the first rule repeating an earlier one.
+// Trailing empty values are ignored, as the unique index treats them as NULL.
+func repeatedRule(rules [][]string) (int, bool) {
+	seen := make(map[string]struct{}, len(rules))
+	for i, rule := range rules {
+		for len(rule) > 0 && rule[len(rule)-1] == "" {
+			rule = rule[:len(rule)-1]
+		}
+		key := strings.Join(rule, "\x00")
+		if _, ok := seen[key]; ok {
+			return i, true
+		}
+		seen[key] = struct{}{}
+	}
+	return 0, false
 }
 
 // UpdateFilteredPoliciesCtx deletes old rules matching the filter and adds new rules
 func (a *PgxAdapter) UpdateFilteredPoliciesCtx(ctx context.Context, sec string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
-	if fieldIndex < 0 || fieldIndex > 5 {
+	var removed [][]string
+	err := a.withRetry(ctx, func() error {
+		var err error
+		removed, err = a.updateFilteredPolicies(ctx, sec, ptype, newRules, fieldIndex, fieldValues...)
+		return err
+	})
+	return removed, err
+}
+
+// updateFilteredPolicies runs a single attempt of UpdateFilteredPoliciesCtx
+func (a *PgxAdapter) updateFilteredPolicies(ctx context.Context, sec string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return nil, err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if fieldIndex < 0 || fieldIndex >= a.valueColumnTotal() {
 		return nil, fmt.Errorf("invalid field index: %d", fieldIndex)
 	}
 
-	tx, err := a.db.Begin(ctx)
+	tx, err := a.beginWrite(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
 	// Build query to find matching old policies
-	selectBuilder := a.psql.Select(selectColumns...).From(a.tableName).Where(sq.Eq{"ptype": ptype})
+	selectBuilder := a.selectRules(ctx, a.selectColumns()...).Where(sq.Eq{a.column("ptype"): ptype})
 
 	// Add filter conditions
 	for i := range fieldValues {
-		if i+fieldIndex > 5 {
+		if i+fieldIndex >= a.valueColumnTotal() {
 			break
 		}
-		col := colParams[i+fieldIndex]
-		selectBuilder = selectBuilder.Where(sq.Eq{col: fieldValues[i]})
+		col := a.fieldColumn(ptype, i+fieldIndex)
+		selectBuilder = selectBuilder.Where(a.columnEq(col, fieldValues[i]))
 	}
 
 	sqlQuery, args, err := selectBuilder.ToSql()
@@ -170,34 +352,15 @@ func (a *PgxAdapter) UpdateFilteredPoliciesCtx(ctx context.Context, sec string,
 
 	var oldPolicies [][]string
 	for rows.Next() {
-		var ptypeVal string
-		var v0, v1, v2, v3, v4, v5 sql.NullString
-
-		if err := rows.Scan(&ptypeVal, &v0, &v1, &v2, &v3, &v4, &v5); err != nil {
+		_, policy, err := a.scanRule(rows)
+		if err != nil {
 			rows.Close()
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+			return nil, err
 		}
 
-		policy := []string{}
-		if v0.Valid {
-			policy = append(policy, v0.String)
+		if policy == nil {
+			policy = []string{}
 		}
-		if v1.Valid {
-			policy = append(policy, v1.String)
-		}
-		if v2.Valid {
-			policy = append(policy, v2.String)
-		}
-		if v3.Valid {
-			policy = append(policy, v3.String)
-		}
-		if v4.Valid {
-			policy = append(policy, v4.String)
-		}
-		if v5.Valid {
-			policy = append(policy, v5.String)
-		}
-
 		oldPolicies = append(oldPolicies, policy)
 	}
 	rows.Close()
@@ -207,13 +370,13 @@ func (a *PgxAdapter) UpdateFilteredPoliciesCtx(ctx context.Context, sec string,
 	}
 
 	// Delete old policies matching the filter
-	deleteBuilder := a.psql.Delete(a.tableName).Where(sq.Eq{"ptype": ptype})
+	deleteBuilder := a.deleteRules(ctx).Where(sq.Eq{a.column("ptype"): ptype})
 	for i := range fieldValues {
-		if i+fieldIndex > 5 {
+		if i+fieldIndex >= a.valueColumnTotal() {
 			break
 		}
-		col := colParams[i+fieldIndex]
-		deleteBuilder = deleteBuilder.Where(sq.Eq{col: fieldValues[i]})
+		col := a.fieldColumn(ptype, i+fieldIndex)
+		deleteBuilder = deleteBuilder.Where(a.columnEq(col, fieldValues[i]))
 	}
 
 	sqlQuery, args, err = deleteBuilder.ToSql()
@@ -228,19 +391,10 @@ func (a *PgxAdapter) UpdateFilteredPoliciesCtx(ctx context.Context, sec string,
 
 	// Insert new policies
 	if len(newRules) > 0 {
-		insertBuilder := a.psql.Insert(a.tableName).Columns(insertColumns...)
+		insertBuilder := a.psql.Insert(a.quotedTableName()).Columns(a.insertColumns()...)
 
 		for _, rule := range newRules {
-			vals := make([]any, 7)
-			vals[0] = ptype
-			for i := range 6 {
-				if i < len(rule) && rule[i] != "" {
-					vals[i+1] = rule[i]
-				} else {
-					vals[i+1] = nil
-				}
-			}
-			insertBuilder = insertBuilder.Values(vals...)
+			insertBuilder = insertBuilder.Values(a.insertValues(ctx, ptype, rule)...)
 		}
 
 		sqlQuery, args, err = insertBuilder.ToSql()
@@ -250,10 +404,26 @@ func (a *PgxAdapter) UpdateFilteredPoliciesCtx(ctx context.Context, sec string,
 
 		_, err = tx.Exec(ctx, sqlQuery, args...)
 		if err != nil {
-			return nil, fmt.Errorf("failed to insert new policies: %w", err)
+			return nil, fmt.Errorf("failed to insert new policies: %w", mapWriteError(err))
 		}
 	}
 
+	removed := make([]auditChange, len(oldPolicies))
+	for i, rule := range oldPolicies {
+		removed[i] = auditChange{ptype: ptype, oldRule: rule}
+	}
+	if err := a.writeAudit(ctx, tx, AuditOpRemove, removed); err != nil {
+		return nil, err
+	}
+
+	added := make([]auditChange, len(newRules))
+	for i, rule := range newRules {
+		added[i] = auditChange{ptype: ptype, newRule: rule}
+	}
+	if err := a.writeAudit(ctx, tx, AuditOpAdd, added); err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strings"
 	"testing"
 
 	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
@@ -168,6 +169,39 @@ func TestUpdatePolicies(t *testing.T) {
 			wantErr: true,
 			errMsg:  "policy not found",
 		},
+		{
+			name: "update_into_existing_rule",
+			setupPolicies: [][]string{
+				{"p", "alice", "data1", "read"},
+				{"p", "bob", "data2", "write"},
+			},
+			oldRules: [][]string{
+				{"alice", "data1", "read"},
+				{"bob", "data2", "write"},
+			},
+			newRules: [][]string{
+				{"alice", "data1", "write"},
+				{"alice", "data1", "write"},
+			},
+			wantErr: true,
+			errMsg:  "failed to update policy",
+		},
+		{
+			name: "update_with_repeated_old_rule",
+			setupPolicies: [][]string{
+				{"p", "alice", "data1", "read"},
+			},
+			oldRules: [][]string{
+				{"alice", "data1", "read"},
+				{"alice", "data1", "read", ""},
+			},
+			newRules: [][]string{
+				{"alice", "data1", "write"},
+				{"alice", "data1", "admin"},
+			},
+			wantErr: true,
+			errMsg:  "repeats an earlier old rule",
+		},
 	}
 
 	for _, tt := range tests {
@@ -205,6 +239,8 @@ func TestUpdatePolicies(t *testing.T) {
 			if tt.wantErr {
 				if err == nil {
 					t.Errorf("UpdatePolicies() expected error but got none")
+				} else if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("UpdatePolicies() error = %v, want it to contain %q", err, tt.errMsg)
 				}
 
 				// Verify transaction rollback - count should be unchanged
@@ -227,6 +263,54 @@ func TestUpdatePolicies(t *testing.T) {
 	}
 }
 
+func TestUpdatePoliciesBulk(t *testing.T) {
+	ctx := context.Background()
+	tableName := "casbin_test_update_policies_bulk"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	const total = 20000
+	oldRules := make([][]string, 0, total+1)
+	newRules := make([][]string, 0, total+1)
+	for i := range total {
+		oldRules = append(oldRules, []string{fmt.Sprintf("user%d", i), "data1", "read"})
+		newRules = append(newRules, []string{fmt.Sprintf("user%d", i), "data2", "write"})
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", oldRules); err != nil {
+		t.Fatalf("Failed to insert test policies: %v", err)
+	}
+
+	// Rules that do not exist are skipped
+	oldRules = append(oldRules, []string{"nobody", "data1", "read"})
+	newRules = append(newRules, []string{"nobody", "data2", "write"})
+
+	updated, err := adapter.UpdatePoliciesBulk(ctx, "p", oldRules, newRules)
+	if err != nil {
+		t.Fatalf("UpdatePoliciesBulk() unexpected error: %v", err)
+	}
+	if updated != total {
+		t.Errorf("UpdatePoliciesBulk() = %d, want %d", updated, total)
+	}
+
+	var count int
+	if err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM "+tableName+" WHERE v1 = 'data2' AND v2 = 'write'").Scan(&count); err != nil {
+		t.Fatalf("Failed to count updated policies: %v", err)
+	}
+	if count != total {
+		t.Errorf("Table has %d updated policies, want %d", count, total)
+	}
+
+	// The rule strictly updated by UpdatePolicies must exist
+	err = adapter.UpdatePolicies("p", "p", [][]string{{"nobody", "data2", "write"}}, [][]string{{"nobody", "data3", "read"}})
+	if err == nil {
+		t.Error("UpdatePolicies() expected error for a missing rule")
+	}
+}
+
 func TestUpdateFilteredPolicies(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -0,0 +1,49 @@
+package pgxadapter
+
+import (
+	"context"
+	"errors"
+)
+
+// WithUUIDPrimaryKey creates the id column as UUID DEFAULT gen_random_uuid()
+// instead of SERIAL, so rows inserted on different primaries, such as in
+// logical replication or multi-master setups, do not collide. It requires
+// PostgreSQL 13 or later and only affects newly created tables. Random ids
+// carry no insertion order, so rules load in an arbitrary order unless
+// WithCanonicalOrder is set. Ids are returned by AddPolicyReturningUUID.
+func WithUUIDPrimaryKey() Option {
+	return func(a *PgxAdapter) {
+		a.uuidPrimaryKey = true
+	}
+}
+
+// idColumnDDL returns the definition of the id column
+func (a *PgxAdapter) idColumnDDL() string {
+	ddl := "id SERIAL"
+	if a.uuidPrimaryKey {
+		ddl = "id UUID DEFAULT gen_random_uuid()"
+	} else if a.identityPrimaryKey {
+		ddl = "id BIGINT GENERATED ALWAYS AS IDENTITY"
+	}
+
+	if a.partitionByPtype {
+		// The primary key must include the partition key, see partitionDDL
+		return ddl
+	}
+	return ddl + " PRIMARY KEY"
+}
+
+// AddPolicyReturningUUID adds a policy rule to the storage and returns the id
+// of the inserted row, for tables created with WithUUIDPrimaryKey
+func (a *PgxAdapter) AddPolicyReturningUUID(ctx context.Context, sec string, ptype string, rule []string) (string, error) {
+	if !a.uuidPrimaryKey {
+		return "", errors.New("ids are not UUIDs, use AddPolicyReturningID")
+	}
+
+	var id string
+	if err := a.addPolicyReturning(ctx, ptype, rule, &id); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
@@ -0,0 +1,67 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"testing"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithUUIDPrimaryKey(t *testing.T) {
+	tableName := "casbin_test_uuid"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithUUIDPrimaryKey(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	var typ string
+	if err := pool.QueryRow(ctx,
+		"SELECT format_type(atttypid, atttypmod) FROM pg_attribute WHERE attrelid = $1::regclass AND attname = 'id'",
+		tableName).Scan(&typ); err != nil {
+		t.Fatalf("Failed to query id type: %v", err)
+	}
+	if typ != "uuid" {
+		t.Errorf("Expected a uuid id column, got %q", typ)
+	}
+
+	id, err := adapter.AddPolicyReturningUUID(ctx, "p", "p", []string{"alice", "data1", "read"})
+	if err != nil {
+		t.Fatalf("AddPolicyReturningUUID() unexpected error: %v", err)
+	}
+	if len(id) != 36 {
+		t.Errorf("Expected a UUID, got %q", id)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("AddPolicy() unexpected error: %v", err)
+	}
+
+	// Duplicates can only exist without the unique index
+	if _, err := pool.Exec(ctx, "DROP INDEX idx_"+tableName); err != nil {
+		t.Fatalf("Failed to drop index: %v", err)
+	}
+	if _, err := pool.Exec(ctx, "INSERT INTO "+tableName+" (ptype, v0, v1, v2) VALUES ('p', 'alice', 'data1', 'read')"); err != nil {
+		t.Fatalf("Failed to insert duplicate: %v", err)
+	}
+	removed, err := adapter.DeduplicatePolicies(ctx)
+	if err != nil {
+		t.Fatalf("DeduplicatePolicies() unexpected error: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("DeduplicatePolicies() removed %d rows, want 1", removed)
+	}
+
+	plain, err := pgxadapter.NewAdapterWithPool(pool, pgxadapter.WithTableName(tableName+"_serial"))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	t.Cleanup(func() { _ = plain.DropTable(ctx) })
+	if _, err := plain.AddPolicyReturningUUID(ctx, "p", "p", []string{"alice", "data1", "read"}); err == nil {
+		t.Error("Expected an error for a table with SERIAL ids")
+	}
+}
@@ -0,0 +1,117 @@
+package pgxadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// WithPolicyVersion keeps a version counter in a table named after the policy
+// table with a "_version" suffix. A trigger bumps it after every statement
+// that changes rules, including SavePolicy and edits made directly in SQL,
+// so comparing GetPolicyVersion with LoadedPolicyVersion tells whether an
+// in-memory model is stale. Writers briefly serialize on the counter row.
+func WithPolicyVersion() Option {
+	return func(a *PgxAdapter) {
+		a.policyVersion = true
+	}
+}
+
+// GetPolicyVersion returns the current policy version, see WithPolicyVersion
+func (a *PgxAdapter) GetPolicyVersion(ctx context.Context) (int64, error) {
+	ctx, cancel := a.readContext(ctx)
+	defer cancel()
+
+	if err := a.ensureInit(ctx); err != nil {
+		return 0, err
+	}
+	if !a.policyVersion {
+		return 0, errors.New("policy version is not enabled, see WithPolicyVersion")
+	}
+
+	return a.readVersion(ctx)
+}
+
+// LoadedPolicyVersion returns the policy version read by the last LoadPolicy,
+// or 0 if WithPolicyVersion is not set. The version is read before the rules,
+// so a change made during the load makes the model look stale, never current.
+func (a *PgxAdapter) LoadedPolicyVersion() int64 {
+	return a.loadedVersion.Load()
+}
+
+// versionTable returns the sanitized name of the version table
+func (a *PgxAdapter) versionTable() string {
+	return a.qualifiedName(a.tableName + "_version")
+}
+
+// createVersionTable creates the version table and its single row
+func (a *PgxAdapter) createVersionTable(ctx context.Context) error {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	ddl := `CREATE TABLE IF NOT EXISTS ` + a.versionTable() + ` (
+		id INT PRIMARY KEY CHECK (id = 1),
+		version BIGINT NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	)`
+	if _, err := a.dbFrom(ctx).Exec(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to create version table: %w", err)
+	}
+	if _, err := a.dbFrom(ctx).Exec(ctx,
+		"INSERT INTO "+a.versionTable()+" (id, version, updated_at) VALUES (1, 0, now()) ON CONFLICT DO NOTHING",
+	); err != nil {
+		return fmt.Errorf("failed to create version row: %w", err)
+	}
+	return nil
+}
+
+// installVersionTrigger creates or replaces the trigger bumping the version
+func (a *PgxAdapter) installVersionTrigger(ctx context.Context) error {
+	name := pgx.Identifier{a.tableName + "_version_bump"}.Sanitize()
+	function := a.qualifiedName(a.tableName + "_version_bump")
+
+	ddl := `CREATE OR REPLACE FUNCTION ` + function + `() RETURNS trigger AS $$
+		BEGIN
+			UPDATE ` + a.versionTable() + ` SET version = version + 1, updated_at = now() WHERE id = 1;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+		DROP TRIGGER IF EXISTS ` + name + ` ON ` + a.quotedTableName() + `;
+		CREATE TRIGGER ` + name + `
+			AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON ` + a.quotedTableName() + `
+			FOR EACH STATEMENT EXECUTE FUNCTION ` + function + `()`
+
+	if _, err := a.dbFrom(ctx).Exec(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to create version trigger: %w", err)
+	}
+	return nil
+}
+
+// readVersion reads the version counter
+func (a *PgxAdapter) readVersion(ctx context.Context) (int64, error) {
+	ctx, cancel := a.readContext(ctx)
+	defer cancel()
+
+	var version int64
+	if err := a.dbFrom(ctx).QueryRow(ctx, "SELECT version FROM "+a.versionTable()+" WHERE id = 1").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read version: %w", err)
+	}
+	return version, nil
+}
+
+// bumpVersion increments the version counter and returns the new version
+func (a *PgxAdapter) bumpVersion(ctx context.Context) (int64, error) {
+	ctx, cancel := a.writeContext(ctx)
+	defer cancel()
+
+	var version int64
+	err := a.dbFrom(ctx).QueryRow(ctx,
+		"UPDATE "+a.versionTable()+" SET version = version + 1, updated_at = now() WHERE id = 1 RETURNING version",
+	).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update version: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,85 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v3/model"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWithPolicyVersion(t *testing.T) {
+	tableName := "casbin_test_policy_version"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, _ = pool.Exec(ctx, "DROP TABLE IF EXISTS "+tableName+"_version")
+	})
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithPolicyVersion(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	version := func() int64 {
+		t.Helper()
+		v, err := adapter.GetPolicyVersion(ctx)
+		if err != nil {
+			t.Fatalf("GetPolicyVersion() unexpected error: %v", err)
+		}
+		return v
+	}
+
+	m, _ := model.NewModelFromString(TestModelText)
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if adapter.LoadedPolicyVersion() != version() {
+		t.Errorf("Expected the loaded model to be current")
+	}
+
+	before := version()
+	m.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	if err := adapter.SavePolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+	afterSave := version()
+	if afterSave <= before {
+		t.Errorf("Expected SavePolicy to bump the version, got %d after %d", afterSave, before)
+	}
+
+	// Edits made directly in SQL bump it too
+	if _, err := pool.Exec(ctx, "INSERT INTO "+tableName+" (ptype, v0, v1, v2) VALUES ('p', 'bob', 'data2', 'write')"); err != nil {
+		t.Fatalf("Failed to insert rule: %v", err)
+	}
+	if version() <= afterSave {
+		t.Errorf("Expected a direct edit to bump the version")
+	}
+	if adapter.LoadedPolicyVersion() >= version() {
+		t.Errorf("Expected the loaded model to be stale")
+	}
+
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if adapter.LoadedPolicyVersion() != version() {
+		t.Errorf("Expected the reloaded model to be current")
+	}
+}
+
+func TestGetPolicyVersionDisabled(t *testing.T) {
+	tableName := "casbin_test_policy_version_disabled"
+	conn := setupTestDB(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithConn(conn, pgxadapter.WithTableName(tableName))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	if _, err := adapter.GetPolicyVersion(context.Background()); err == nil {
+		t.Error("Expected an error without WithPolicyVersion")
+	}
+}
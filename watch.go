@@ -0,0 +1,131 @@
+package pgxadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// WithNotifyDebounce coalesces notifications received by Watch within d into a
+// single onChange call, so bulk changes do not make every instance reload at
+// once. The call happens at most d after the first notification of a burst,
+// so a steady stream of notifications cannot postpone it indefinitely.
+func WithNotifyDebounce(d time.Duration) Option {
+	return func(a *PgxAdapter) {
+		a.notifyDebounce = d
+	}
+}
+
+// Watch listens on the adapter's NOTIFY channel, that of the tenant of ctx with
+// WithTenantColumn, and calls onChange for every notification, or once per
+// burst with WithNotifyDebounce. It blocks until ctx
+// is done or the listening connection fails. Watch uses its own connection:
+// one acquired from the pool, or a new one with the config of the adapter's connection.
+func (a *PgxAdapter) Watch(ctx context.Context, onChange func()) error {
+	if err := a.ensureInit(ctx); err != nil {
+		return err
+	}
+	ctx, err := a.resolveTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	notify := onChange
+	if a.notifyDebounce > 0 {
+		d := &debouncer{window: a.notifyDebounce, fn: onChange}
+		defer d.stop()
+		notify = d.trigger
+	}
+
+	return a.listen(ctx, a.channelOf(ctx), nil, func(string) { notify() })
+}
+
+// listen LISTENs on channel and calls onNotify with the payload of every
+// notification until ctx is done. onListen, when set, is called once LISTEN is active.
+func (a *PgxAdapter) listen(ctx context.Context, channel string, onListen func(), onNotify func(payload string)) error {
+	conn, err := a.listenConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	if onListen != nil {
+		onListen()
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+		onNotify(n.Payload)
+	}
+}
+
+// listenConn returns a connection dedicated to LISTEN, owned by the caller
+func (a *PgxAdapter) listenConn(ctx context.Context) (*pgx.Conn, error) {
+	if a.pool != nil {
+		pooled, err := a.pool.Acquire(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire connection: %w", err)
+		}
+		// Take the connection out of the pool so its LISTEN state never leaks back
+		return pooled.Hijack(), nil
+	}
+
+	if a.conn != nil {
+		conn, err := pgx.ConnectConfig(ctx, a.conn.Config())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create connection: %w", err)
+		}
+		return conn, nil
+	}
+
+	return nil, errors.New("watch requires an adapter created with a connection or pool")
+}
+
+// debouncer calls fn once per window after the first trigger of a burst
+type debouncer struct {
+	window time.Duration
+	fn     func()
+
+	mu    sync.Mutex
+	timer *time.Timer
+}
+
+// trigger schedules fn unless a call is already pending
+func (d *debouncer) trigger() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil {
+		return
+	}
+	d.timer = time.AfterFunc(d.window, func() {
+		d.mu.Lock()
+		d.timer = nil
+		d.mu.Unlock()
+		d.fn()
+	})
+}
+
+// stop cancels a pending call
+func (d *debouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+}
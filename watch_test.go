@@ -0,0 +1,57 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWatchNotifyDebounce(t *testing.T) {
+	tableName := "casbin_test_watch_debounce"
+	pool := setupTestPool(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithNamespace("watch_debounce"),
+		pgxadapter.WithNotifyDebounce(300*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls atomic.Int32
+	done := make(chan error, 1)
+	go func() {
+		done <- adapter.Watch(ctx, func() { calls.Add(1) })
+	}()
+
+	// Give the watcher time to issue LISTEN before notifying
+	time.Sleep(200 * time.Millisecond)
+
+	const notifications = 20
+	for i := 0; i < notifications; i++ {
+		if _, err := pool.Exec(context.Background(), "SELECT pg_notify($1, '')", adapter.GetNotifyChannel()); err != nil {
+			t.Fatalf("Failed to notify: %v", err)
+		}
+	}
+
+	time.Sleep(time.Second)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	got := calls.Load()
+	if got < 1 {
+		t.Errorf("Expected at least one callback after the last notification")
+	}
+	if got >= notifications {
+		t.Errorf("Expected notifications to coalesce, got %d callbacks for %d notifications", got, notifications)
+	}
+}
@@ -0,0 +1,454 @@
+package pgxadapter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mathrand "math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+	"github.com/casbin/casbin/v3/persist"
+)
+
+var (
+	_ persist.Watcher          = (*Watcher)(nil)
+	_ persist.WatcherEx        = (*Watcher)(nil)
+	_ persist.UpdatableWatcher = (*Watcher)(nil)
+)
+
+// Default delays between reconnect attempts, see WithReconnectBackoff
+const (
+	defaultMinBackoff = 100 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// maxNotifyPayload is the largest payload Postgres accepts for NOTIFY, minus one
+// for the terminating NUL byte
+const maxNotifyPayload = 7999
+
+// UpdateMethod identifies the change a watcher message describes
+type UpdateMethod string
+
+// Update methods carried by watcher messages
+const (
+	MethodUpdate                  UpdateMethod = "Update"
+	MethodUpdateForAddPolicy      UpdateMethod = "UpdateForAddPolicy"
+	MethodUpdateForRemovePolicy   UpdateMethod = "UpdateForRemovePolicy"
+	MethodUpdateForRemoveFiltered UpdateMethod = "UpdateForRemoveFilteredPolicy"
+	MethodUpdateForSavePolicy     UpdateMethod = "UpdateForSavePolicy"
+	MethodUpdateForAddPolicies    UpdateMethod = "UpdateForAddPolicies"
+	MethodUpdateForRemovePolicies UpdateMethod = "UpdateForRemovePolicies"
+	MethodUpdateForUpdatePolicy   UpdateMethod = "UpdateForUpdatePolicy"
+	MethodUpdateForUpdatePolicies UpdateMethod = "UpdateForUpdatePolicies"
+)
+
+// WatcherMessage is the JSON NOTIFY payload published by Watcher. Messages
+// for Update and UpdateForSavePolicy carry no rules and call for a full reload,
+// as do messages whose rules would not fit in a NOTIFY payload.
+type WatcherMessage struct {
+	Method      UpdateMethod `json:"method"`
+	InstanceID  string       `json:"instance_id,omitempty"`
+	Sec         string       `json:"sec,omitempty"`
+	Ptype       string       `json:"ptype,omitempty"`
+	Rules       [][]string   `json:"rules,omitempty"`
+	NewRules    [][]string   `json:"new_rules,omitempty"`
+	FieldIndex  int          `json:"field_index,omitempty"`
+	FieldValues []string     `json:"field_values,omitempty"`
+}
+
+// ParseWatcherMessage decodes a payload passed to the update callback
+func ParseWatcherMessage(payload string) (WatcherMessage, error) {
+	var msg WatcherMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		return WatcherMessage{}, fmt.Errorf("failed to parse watcher message: %w", err)
+	}
+	return msg, nil
+}
+
+// Watcher is a persist.Watcher that keeps enforcers sharing a policy table in
+// sync through Postgres LISTEN/NOTIFY on the adapter's notify channel. Set it on
+// the enforcer with SetWatcher; the enforcer then calls Update after AddPolicy,
+// RemovePolicy, SavePolicy and friends, and every other instance's callback fires.
+type Watcher struct {
+	adapter *PgxAdapter
+	channel string
+
+	instanceID string
+	selfNotify bool
+
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	onReconnect func()
+
+	// debounce window and the payloads received within it, see WithDebounce
+	debounce  time.Duration
+	debouncer *debouncer
+	pending   []string
+
+	mu       sync.Mutex
+	callback func(string)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// WatcherOption configures a Watcher
+type WatcherOption func(*Watcher)
+
+// WithInstanceID sets the ID stamped on the watcher's messages, which it uses
+// to recognise its own notifications. Defaults to a random ID.
+func WithInstanceID(id string) WatcherOption {
+	return func(w *Watcher) {
+		w.instanceID = id
+	}
+}
+
+// WithSelfNotify delivers the watcher's own notifications to its callback too.
+// By default they are dropped, since the local enforcer already holds the change.
+func WithSelfNotify(enabled bool) WatcherOption {
+	return func(w *Watcher) {
+		w.selfNotify = enabled
+	}
+}
+
+// WithReconnectBackoff sets the delays between attempts to restore a dropped
+// LISTEN connection. The delay starts at minDelay and doubles up to maxDelay,
+// with jitter so instances do not reconnect in lockstep after a failover.
+// Defaults to 100ms and 30s.
+func WithReconnectBackoff(minDelay, maxDelay time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.minBackoff = minDelay
+		w.maxBackoff = maxDelay
+	}
+}
+
+// WithOnReconnect sets a function called each time the watcher listens again
+// after its connection dropped. Notifications sent in between are lost, so
+// the function typically reloads the whole policy.
+func WithOnReconnect(fn func()) WatcherOption {
+	return func(w *Watcher) {
+		w.onReconnect = fn
+	}
+}
+
+// WithDebounce coalesces the notifications received within window into one
+// callback, so importing thousands of rules does not cause a reload storm.
+// A burst of a single notification passes its payload through unchanged;
+// a larger burst is delivered as a WatcherMessage for a full reload.
+func WithDebounce(window time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.debounce = window
+	}
+}
+
+// NewWatcher starts listening on the adapter's notify channel. It returns once
+// LISTEN is active, so no Update issued after it returns is missed. If the
+// connection drops later, the watcher reconnects until it is closed. With
+// WithTenantColumn the watcher listens and publishes on the channel of the
+// tenant of ctx.
+func NewWatcher(ctx context.Context, adapter *PgxAdapter, opts ...WatcherOption) (*Watcher, error) {
+	if err := adapter.ensureInit(ctx); err != nil {
+		return nil, err
+	}
+	ctx, err := adapter.resolveTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	listenCtx, cancel := context.WithCancel(context.Background())
+	w := &Watcher{
+		adapter: adapter,
+		channel: adapter.channelOf(ctx),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.instanceID == "" {
+		w.instanceID = newInstanceID()
+	}
+	if w.minBackoff <= 0 {
+		w.minBackoff = defaultMinBackoff
+	}
+	if w.maxBackoff < w.minBackoff {
+		w.maxBackoff = max(defaultMaxBackoff, w.minBackoff)
+	}
+	if w.debounce > 0 {
+		w.debouncer = &debouncer{window: w.debounce, fn: w.flush}
+	}
+
+	ready := make(chan struct{})
+	errc := make(chan error, 1)
+	go w.run(listenCtx, ready, errc)
+
+	select {
+	case <-ready:
+		return w, nil
+	case err := <-errc:
+		cancel()
+		return nil, err
+	case <-ctx.Done():
+		cancel()
+		<-w.done
+		return nil, ctx.Err()
+	}
+}
+
+// run listens until ctx is done, reconnecting with backoff when the
+// connection drops. The outcome of the first attempt is reported through
+// ready or errc.
+func (w *Watcher) run(ctx context.Context, ready chan<- struct{}, errc chan<- error) {
+	defer close(w.done)
+
+	connected := false
+	attempt := 0
+	for {
+		err := w.adapter.listen(ctx, w.channel, func() {
+			attempt = 0
+			if !connected {
+				connected = true
+				close(ready)
+			} else if w.onReconnect != nil {
+				w.onReconnect()
+			}
+		}, w.dispatch)
+		if ctx.Err() != nil {
+			return
+		}
+		if !connected {
+			errc <- err
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.backoff(attempt)):
+		}
+		attempt++
+	}
+}
+
+// backoff returns the delay before reconnect attempt n
+func (w *Watcher) backoff(n int) time.Duration {
+	return backoffDelay(n, w.minBackoff, w.maxBackoff)
+}
+
+// backoffDelay returns the delay before reconnect attempt n, between half and
+// all of the delay growing exponentially from minDelay up to maxDelay
+func backoffDelay(n int, minDelay, maxDelay time.Duration) time.Duration {
+	d := maxDelay
+	if n < 32 {
+		d = min(minDelay<<n, maxDelay)
+	}
+	if d <= 0 {
+		d = maxDelay
+	}
+	return d/2 + mathrand.N(d/2+1)
+}
+
+// SetUpdateCallback sets the function called with the payload of every change notification
+func (w *Watcher) SetUpdateCallback(fn func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.callback = fn
+	return nil
+}
+
+// Update notifies every watcher on the channel that the policy has changed
+func (w *Watcher) Update() error {
+	return w.publish(context.Background(), WatcherMessage{Method: MethodUpdate})
+}
+
+// UpdateCtx is Update sending the notification on the transaction carried by
+// ctx, see WithTx, so it is only delivered once that transaction commits
+func (w *Watcher) UpdateCtx(ctx context.Context) error {
+	return w.publish(ctx, WatcherMessage{Method: MethodUpdate})
+}
+
+// UpdateForAddPolicy publishes a rule added by Enforcer.AddPolicy
+func (w *Watcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return w.publish(context.Background(), WatcherMessage{Method: MethodUpdateForAddPolicy, Sec: sec, Ptype: ptype, Rules: [][]string{params}})
+}
+
+// UpdateForRemovePolicy publishes a rule removed by Enforcer.RemovePolicy
+func (w *Watcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return w.publish(context.Background(), WatcherMessage{Method: MethodUpdateForRemovePolicy, Sec: sec, Ptype: ptype, Rules: [][]string{params}})
+}
+
+// UpdateForRemoveFilteredPolicy publishes the filter of Enforcer.RemoveFilteredPolicy
+func (w *Watcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return w.publish(context.Background(), WatcherMessage{Method: MethodUpdateForRemoveFiltered, Sec: sec, Ptype: ptype, FieldIndex: fieldIndex, FieldValues: fieldValues})
+}
+
+// UpdateForSavePolicy publishes a full save; subscribers reload the policy
+func (w *Watcher) UpdateForSavePolicy(model.Model) error {
+	return w.publish(context.Background(), WatcherMessage{Method: MethodUpdateForSavePolicy})
+}
+
+// UpdateForAddPolicies publishes rules added by Enforcer.AddPolicies
+func (w *Watcher) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(context.Background(), WatcherMessage{Method: MethodUpdateForAddPolicies, Sec: sec, Ptype: ptype, Rules: rules})
+}
+
+// UpdateForRemovePolicies publishes rules removed by Enforcer.RemovePolicies
+func (w *Watcher) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(context.Background(), WatcherMessage{Method: MethodUpdateForRemovePolicies, Sec: sec, Ptype: ptype, Rules: rules})
+}
+
+// UpdateForUpdatePolicy publishes a rule replaced by Enforcer.UpdatePolicy
+func (w *Watcher) UpdateForUpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	return w.publish(context.Background(), WatcherMessage{Method: MethodUpdateForUpdatePolicy, Sec: sec, Ptype: ptype, Rules: [][]string{oldRule}, NewRules: [][]string{newRule}})
+}
+
+// UpdateForUpdatePolicies publishes rules replaced by Enforcer.UpdatePolicies
+func (w *Watcher) UpdateForUpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	return w.publish(context.Background(), WatcherMessage{Method: MethodUpdateForUpdatePolicies, Sec: sec, Ptype: ptype, Rules: oldRules, NewRules: newRules})
+}
+
+// Close stops listening; the callback is not called any more
+func (w *Watcher) Close() {
+	w.cancel()
+	<-w.done
+
+	if w.debouncer != nil {
+		w.debouncer.stop()
+	}
+}
+
+// publish sends msg on the adapter's notify channel, degrading it to a plain
+// Update when its rules do not fit in a NOTIFY payload
+func (w *Watcher) publish(ctx context.Context, msg WatcherMessage) error {
+	msg.InstanceID = w.instanceID
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode watcher message: %w", err)
+	}
+	if len(payload) > maxNotifyPayload {
+		payload, _ = json.Marshal(WatcherMessage{Method: MethodUpdate, InstanceID: w.instanceID})
+	}
+
+	ctx, cancel := w.adapter.writeContext(ctx)
+	defer cancel()
+
+	if _, err := w.adapter.dbFrom(ctx).Exec(ctx, "SELECT pg_notify($1, $2)", w.channel, string(payload)); err != nil {
+		return fmt.Errorf("failed to notify: %w", err)
+	}
+	return nil
+}
+
+// InstanceID returns the ID stamped on the watcher's messages
+func (w *Watcher) InstanceID() string {
+	return w.instanceID
+}
+
+// dispatch passes a notification payload to the callback, unless the watcher
+// sent it itself and self notifications are off
+func (w *Watcher) dispatch(payload string) {
+	if !w.selfNotify {
+		if msg, err := ParseWatcherMessage(payload); err == nil && msg.InstanceID == w.instanceID {
+			return
+		}
+	}
+
+	if w.debouncer != nil {
+		w.mu.Lock()
+		w.pending = append(w.pending, payload)
+		w.mu.Unlock()
+		w.debouncer.trigger()
+		return
+	}
+
+	w.mu.Lock()
+	fn := w.callback
+	w.mu.Unlock()
+
+	if fn != nil {
+		fn(payload)
+	}
+}
+
+// flush delivers the payloads collected during a debounce window
+func (w *Watcher) flush() {
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = nil
+	fn := w.callback
+	w.mu.Unlock()
+
+	if fn == nil || len(pending) == 0 {
+		return
+	}
+	if len(pending) == 1 {
+		fn(pending[0])
+		return
+	}
+
+	payload, _ := json.Marshal(WatcherMessage{Method: MethodUpdate})
+	fn(string(payload))
+}
+
+// newInstanceID returns a random watcher instance ID
+func newInstanceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// DefaultUpdateCallback returns an update callback that applies the changes
+// carried by a WatcherMessage to e without writing them back to the adapter,
+// and reloads the whole policy when the message carries no rules.
+func DefaultUpdateCallback(e casbin.IEnforcer) func(string) {
+	return func(payload string) {
+		msg, err := ParseWatcherMessage(payload)
+		if err != nil {
+			_ = e.LoadPolicy()
+			return
+		}
+		if err := applyWatcherMessage(e, msg); err != nil {
+			_ = e.LoadPolicy()
+		}
+	}
+}
+
+// applyWatcherMessage replays msg on e through its Self* methods
+func applyWatcherMessage(e casbin.IEnforcer, msg WatcherMessage) error {
+	switch msg.Method {
+	case MethodUpdateForAddPolicy, MethodUpdateForRemovePolicy:
+		if len(msg.Rules) != 1 {
+			return fmt.Errorf("malformed %s message", msg.Method)
+		}
+	case MethodUpdateForUpdatePolicy:
+		if len(msg.Rules) != 1 || len(msg.NewRules) != 1 {
+			return fmt.Errorf("malformed %s message", msg.Method)
+		}
+	}
+
+	var err error
+	switch msg.Method {
+	case MethodUpdateForAddPolicy:
+		_, err = e.SelfAddPolicy(msg.Sec, msg.Ptype, msg.Rules[0])
+	case MethodUpdateForRemovePolicy:
+		_, err = e.SelfRemovePolicy(msg.Sec, msg.Ptype, msg.Rules[0])
+	case MethodUpdateForRemoveFiltered:
+		_, err = e.SelfRemoveFilteredPolicy(msg.Sec, msg.Ptype, msg.FieldIndex, msg.FieldValues...)
+	case MethodUpdateForAddPolicies:
+		_, err = e.SelfAddPolicies(msg.Sec, msg.Ptype, msg.Rules)
+	case MethodUpdateForRemovePolicies:
+		_, err = e.SelfRemovePolicies(msg.Sec, msg.Ptype, msg.Rules)
+	case MethodUpdateForUpdatePolicy:
+		_, err = e.SelfUpdatePolicy(msg.Sec, msg.Ptype, msg.Rules[0], msg.NewRules[0])
+	case MethodUpdateForUpdatePolicies:
+		_, err = e.SelfUpdatePolicies(msg.Sec, msg.Ptype, msg.Rules, msg.NewRules)
+	default:
+		err = e.LoadPolicy()
+	}
+	return err
+}
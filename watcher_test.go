@@ -0,0 +1,401 @@
+package pgxadapter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+
+	pgxadapter "github.com/noho-digital/casbin-pgx-adapter"
+)
+
+func TestWatcherSyncsEnforcers(t *testing.T) {
+	tableName := "casbin_test_watcher"
+	pool := setupTestPool(t, tableName)
+
+	newEnforcer := func() (*casbin.Enforcer, *pgxadapter.Watcher) {
+		adapter, err := pgxadapter.NewAdapterWithPool(pool,
+			pgxadapter.WithTableName(tableName),
+			pgxadapter.WithNamespace("watcher"),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		m, err := model.NewModelFromString(TestModelText)
+		if err != nil {
+			t.Fatalf("Failed to create model: %v", err)
+		}
+		e, err := casbin.NewEnforcer(m, adapter)
+		if err != nil {
+			t.Fatalf("Failed to create enforcer: %v", err)
+		}
+		w, err := pgxadapter.NewWatcher(context.Background(), adapter)
+		if err != nil {
+			t.Fatalf("Failed to create watcher: %v", err)
+		}
+		t.Cleanup(w.Close)
+		if err := e.SetWatcher(w); err != nil {
+			t.Fatalf("Failed to set watcher: %v", err)
+		}
+		return e, w
+	}
+
+	writer, _ := newEnforcer()
+	reader, readerWatcher := newEnforcer()
+
+	reloaded := make(chan string, 10)
+	if err := readerWatcher.SetUpdateCallback(func(payload string) {
+		if err := reader.LoadPolicy(); err != nil {
+			t.Errorf("Failed to reload policy: %v", err)
+		}
+		reloaded <- payload
+	}); err != nil {
+		t.Fatalf("Failed to set callback: %v", err)
+	}
+
+	if _, err := writer.AddPolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the watcher callback")
+	}
+
+	if ok, _ := reader.Enforce("alice", "data1", "read"); !ok {
+		t.Error("Expected the other enforcer to see the new policy")
+	}
+}
+
+func TestWatcherClose(t *testing.T) {
+	tableName := "casbin_test_watcher_close"
+	pool := setupTestPool(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithNamespace("watcher_close"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	w, err := pgxadapter.NewWatcher(context.Background(), adapter)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+
+	called := make(chan string, 1)
+	_ = w.SetUpdateCallback(func(payload string) { called <- payload })
+	w.Close()
+
+	if err := w.Update(); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	select {
+	case <-called:
+		t.Error("Expected no callback after Close")
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestWatcherExIncrementalUpdates(t *testing.T) {
+	tableName := "casbin_test_watcher_ex"
+	pool := setupTestPool(t, tableName)
+
+	newEnforcer := func() (*casbin.Enforcer, *pgxadapter.Watcher) {
+		adapter, err := pgxadapter.NewAdapterWithPool(pool,
+			pgxadapter.WithTableName(tableName),
+			pgxadapter.WithNamespace("watcher_ex"),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		m, err := model.NewModelFromString(TestModelText)
+		if err != nil {
+			t.Fatalf("Failed to create model: %v", err)
+		}
+		e, err := casbin.NewEnforcer(m, adapter)
+		if err != nil {
+			t.Fatalf("Failed to create enforcer: %v", err)
+		}
+		w, err := pgxadapter.NewWatcher(context.Background(), adapter)
+		if err != nil {
+			t.Fatalf("Failed to create watcher: %v", err)
+		}
+		t.Cleanup(w.Close)
+		if err := e.SetWatcher(w); err != nil {
+			t.Fatalf("Failed to set watcher: %v", err)
+		}
+		return e, w
+	}
+
+	writer, _ := newEnforcer()
+	reader, readerWatcher := newEnforcer()
+
+	messages := make(chan pgxadapter.WatcherMessage, 10)
+	apply := pgxadapter.DefaultUpdateCallback(reader)
+	if err := readerWatcher.SetUpdateCallback(func(payload string) {
+		msg, err := pgxadapter.ParseWatcherMessage(payload)
+		if err != nil {
+			t.Errorf("Failed to parse payload %q: %v", payload, err)
+		}
+		apply(payload)
+		messages <- msg
+	}); err != nil {
+		t.Fatalf("Failed to set callback: %v", err)
+	}
+
+	next := func() pgxadapter.WatcherMessage {
+		select {
+		case msg := <-messages:
+			return msg
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the watcher callback")
+			return pgxadapter.WatcherMessage{}
+		}
+	}
+
+	if _, err := writer.AddPolicies([][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+	msg := next()
+	if msg.Method != pgxadapter.MethodUpdateForAddPolicies || msg.Ptype != "p" || len(msg.Rules) != 2 {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+	if ok, _ := reader.Enforce("bob", "data2", "write"); !ok {
+		t.Error("Expected the added rules to be applied incrementally")
+	}
+
+	if _, err := writer.RemovePolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("Failed to remove policy: %v", err)
+	}
+	msg = next()
+	if msg.Method != pgxadapter.MethodUpdateForRemovePolicy || len(msg.Rules) != 1 || msg.Rules[0][0] != "alice" {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+	if ok, _ := reader.Enforce("alice", "data1", "read"); ok {
+		t.Error("Expected the removed rule to be applied incrementally")
+	}
+}
+
+func TestWatcherSelfNotify(t *testing.T) {
+	tableName := "casbin_test_watcher_self"
+	pool := setupTestPool(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithNamespace("watcher_self"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		opts       []pgxadapter.WatcherOption
+		expectCall bool
+	}{
+		{
+			name:       "own updates suppressed by default",
+			expectCall: false,
+		},
+		{
+			name:       "own updates delivered with self notify",
+			opts:       []pgxadapter.WatcherOption{pgxadapter.WithSelfNotify(true)},
+			expectCall: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := pgxadapter.NewWatcher(context.Background(), adapter, tt.opts...)
+			if err != nil {
+				t.Fatalf("Failed to create watcher: %v", err)
+			}
+			defer w.Close()
+
+			called := make(chan string, 1)
+			_ = w.SetUpdateCallback(func(payload string) { called <- payload })
+
+			if err := w.Update(); err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+
+			select {
+			case payload := <-called:
+				if !tt.expectCall {
+					t.Errorf("Unexpected callback with %q", payload)
+				}
+				msg, err := pgxadapter.ParseWatcherMessage(payload)
+				if err != nil || msg.InstanceID != w.InstanceID() {
+					t.Errorf("Expected payload stamped with %q, got %q", w.InstanceID(), payload)
+				}
+			case <-time.After(500 * time.Millisecond):
+				if tt.expectCall {
+					t.Error("Timed out waiting for the watcher callback")
+				}
+			}
+		})
+	}
+}
+
+func TestWatcherReconnect(t *testing.T) {
+	tableName := "casbin_test_watcher_reconnect"
+	pool := setupTestPool(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithNamespace("watcher_reconnect"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	reconnected := make(chan struct{}, 1)
+	w, err := pgxadapter.NewWatcher(context.Background(), adapter,
+		pgxadapter.WithReconnectBackoff(10*time.Millisecond, 100*time.Millisecond),
+		pgxadapter.WithOnReconnect(func() { reconnected <- struct{}{} }),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer w.Close()
+
+	called := make(chan string, 1)
+	_ = w.SetUpdateCallback(func(payload string) { called <- payload })
+
+	// Simulate a failover by killing the listening backend
+	if _, err := pool.Exec(context.Background(),
+		"SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE query LIKE 'LISTEN%' AND query LIKE '%' || $1 || '%'",
+		adapter.GetNotifyChannel(),
+	); err != nil {
+		t.Fatalf("Failed to terminate listener: %v", err)
+	}
+
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the watcher to reconnect")
+	}
+
+	other, err := pgxadapter.NewWatcher(context.Background(), adapter)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer other.Close()
+
+	if err := other.Update(); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected notifications to be delivered after reconnecting")
+	}
+}
+
+func TestWatcherDebounce(t *testing.T) {
+	tableName := "casbin_test_watcher_debounce"
+	pool := setupTestPool(t, tableName)
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithNamespace("watcher_debounce"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	w, err := pgxadapter.NewWatcher(context.Background(), adapter, pgxadapter.WithDebounce(300*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer w.Close()
+
+	publisher, err := pgxadapter.NewWatcher(context.Background(), adapter)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer publisher.Close()
+
+	called := make(chan string, 100)
+	_ = w.SetUpdateCallback(func(payload string) { called <- payload })
+
+	const notifications = 50
+	for i := 0; i < notifications; i++ {
+		if err := publisher.UpdateForAddPolicy("p", "p", "alice", "data1", "read"); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+
+	time.Sleep(time.Second)
+
+	var payloads []string
+	for len(called) > 0 {
+		payloads = append(payloads, <-called)
+	}
+	if len(payloads) == 0 || len(payloads) >= notifications {
+		t.Fatalf("Expected notifications to coalesce, got %d callbacks for %d notifications", len(payloads), notifications)
+	}
+	for _, payload := range payloads {
+		msg, err := pgxadapter.ParseWatcherMessage(payload)
+		if err != nil {
+			t.Fatalf("Failed to parse payload %q: %v", payload, err)
+		}
+		if msg.Method != pgxadapter.MethodUpdate && msg.Method != pgxadapter.MethodUpdateForAddPolicy {
+			t.Errorf("Unexpected method %q", msg.Method)
+		}
+	}
+}
+
+func TestWatcherUpdateCtxInTx(t *testing.T) {
+	tableName := "casbin_test_watcher_tx"
+	pool := setupTestPool(t, tableName)
+	ctx := context.Background()
+
+	adapter, err := pgxadapter.NewAdapterWithPool(pool,
+		pgxadapter.WithTableName(tableName),
+		pgxadapter.WithNamespace("watcher_tx"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	w, err := pgxadapter.NewWatcher(ctx, adapter, pgxadapter.WithSelfNotify(true))
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer w.Close()
+
+	called := make(chan string, 1)
+	_ = w.SetUpdateCallback(func(payload string) { called <- payload })
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := w.UpdateCtx(pgxadapter.WithTx(ctx, tx)); err != nil {
+		t.Fatalf("UpdateCtx failed: %v", err)
+	}
+
+	// The notification waits for the commit
+	select {
+	case payload := <-called:
+		t.Fatalf("Unexpected callback before commit with %q", payload)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	select {
+	case <-called:
+	case <-time.After(500 * time.Millisecond):
+		t.Error("Timed out waiting for the watcher callback after commit")
+	}
+}